@@ -24,6 +24,14 @@ func (a actorChecks) Match(it vocab.Item) bool {
 	return All(a...).Match(act.Actor)
 }
 
+func (a actorChecks) Combinator() (Field, Operator) {
+	return FieldActor, OperatorAll
+}
+
+func (a actorChecks) Children() []Check {
+	return a
+}
+
 func (a actorChecks) GoString() string {
 	if len(a) == 0 {
 		return ""
@@ -62,6 +70,14 @@ func (t targetChecks) Match(it vocab.Item) bool {
 	return All(t...).Match(act.Target)
 }
 
+func (t targetChecks) Combinator() (Field, Operator) {
+	return FieldTarget, OperatorAll
+}
+
+func (t targetChecks) Children() []Check {
+	return t
+}
+
 func (t targetChecks) GoString() string {
 	if len(t) == 0 {
 		return ""
@@ -97,6 +113,14 @@ func (o objectChecks) Match(it vocab.Item) bool {
 	return All(o...).Match(act.Object)
 }
 
+func (o objectChecks) Combinator() (Field, Operator) {
+	return FieldObject, OperatorAll
+}
+
+func (o objectChecks) Children() []Check {
+	return o
+}
+
 func (o objectChecks) GoString() string {
 	if len(o) == 0 {
 		return ""
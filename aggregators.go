@@ -15,6 +15,14 @@ func (n notCrit) Match(it vocab.Item) bool {
 	return !f.Match(it)
 }
 
+func (n notCrit) Combinator() (Field, Operator) {
+	return FieldNone, OperatorNot
+}
+
+func (n notCrit) Children() []Check {
+	return n
+}
+
 // Not negates the result of a Check function.
 // It is equivalent to a unary NOT operator.
 func Not(fn Check) Check {
@@ -35,6 +43,14 @@ func (a checkAny) Match(it vocab.Item) bool {
 	return false
 }
 
+func (a checkAny) Combinator() (Field, Operator) {
+	return FieldNone, OperatorAny
+}
+
+func (a checkAny) Children() []Check {
+	return a
+}
+
 // Any aggregates a list of individual Check functions into a single Check
 // which resolves to false if all the individual members resolve as false,
 // and true if any of them resolves as true.
@@ -63,6 +79,14 @@ func (a checkAll) Match(it vocab.Item) bool {
 	return true
 }
 
+func (a checkAll) Combinator() (Field, Operator) {
+	return FieldNone, OperatorAll
+}
+
+func (a checkAll) Children() []Check {
+	return a
+}
+
 // All aggregates a list of individual Check functions into a single Check
 // which resolves true if all individual members resolve as true, and false otherwise.
 // It is equivalent to a sequence of AND operators.
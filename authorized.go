@@ -36,6 +36,11 @@ func (a authorized) Match(it vocab.Item) bool {
 	return Any(ff...).Match(it)
 }
 
+// Leaf returns the FieldNone property, as the check applies on multiple properties of the item.
+func (a authorized) Leaf() (Field, Operator, Value) {
+	return FieldNone, OperatorAuthorized, vocab.IRI(a)
+}
+
 // Authorized creates a filter that checks the [vocab.IRI] against the recipients list of the item it gets applied on.
 // The ActivityStreams Public Namespace IRI gets special treatment, because servers use it to signify that the audience of
 // an object is public.
@@ -77,3 +82,7 @@ func (p public) Match(it vocab.Item) bool {
 	}
 	return accumRecipients(it).Contains(vocab.PublicNS)
 }
+
+func (p public) Leaf() (Field, Operator, Value) {
+	return FieldRecipients, OperatorEquals, vocab.PublicNS
+}
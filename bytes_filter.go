@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"maps"
-	"reflect"
 	"slices"
 	"strconv"
+	"strings"
 
+	"github.com/go-ap/filters/index"
+	"golang.org/x/text/language"
 	"quamina.net/go/quamina/v2"
 )
 
@@ -35,17 +37,45 @@ func (qe qExists) MarshalJSON() ([]byte, error) {
 	return []byte(`{"exists":` + strconv.FormatBool(bool(qe)) + `}`), nil
 }
 
+// qShellstyle represents a pattern where "*" matches any sequence of characters, eg: {"shellstyle":"https://*.example.com/*"}.
+type qShellstyle string
+
+func (qs qShellstyle) MarshalJSON() ([]byte, error) {
+	v, err := json.Marshal(string(qs))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(`{"shellstyle":` + string(v) + `}`), nil
+}
+
 type qAnythingBut string
 
 func (qab qAnythingBut) MarshalJSON() ([]byte, error) {
 	return []byte(`{"anything-but":"` + string(qab) + `"}`), nil
 }
 
-var (
-	nlvEqCheck    = reflect.ValueOf(naturalLanguageValuesEquals)
-	nlvLikeCheck  = reflect.ValueOf(naturalLanguageValuesLike)
-	nlvEmptyCheck = reflect.ValueOf(naturalLanguageEmpty)
-)
+// qNumeric represents a numeric comparison pattern, eg: {"numeric":[">",10]} or {"numeric":[">=",10,"<=",100]}.
+// NOTE(marius): if the quamina version doesn't support numeric comparisons, the pattern gets rejected,
+// and the RawMatcher doesn't match any document.
+type qNumeric []any
+
+func (qn qNumeric) MarshalJSON() ([]byte, error) {
+	ss := bytes.Buffer{}
+	ss.WriteString(`{"numeric":[`)
+	for i, v := range qn {
+		switch vv := v.(type) {
+		case string:
+			appendS(&ss, vv)
+		case float64:
+			ss.WriteString(strconv.FormatFloat(vv, 'f', -1, 64))
+		}
+		if i < len(qn)-1 {
+			ss.WriteRune(',')
+		}
+	}
+	ss.WriteString(`]}`)
+	return ss.Bytes(), nil
+}
 
 type qLeaf json.Marshaler
 
@@ -162,28 +192,25 @@ func getLeafValue(ff Check) (string, json.Marshaler) {
 		}
 		return keyType, r
 	case naturalLanguageValCheck:
-		var name string
-		switch c.typ {
-		case byName:
-			name = keyName
-		case byPreferredUsername:
-			name = keyPreferredUsername
-		case bySummary:
-			name = keySummary
-		case byContent:
-			name = keyContent
-		}
-		rCheckFn := reflect.ValueOf(c.checkFn)
-		switch rCheckFn.Pointer() {
-		case nlvEqCheck.Pointer():
-			return name, qLeafArray{qString(c.checkValue)}
-		case nlvEmptyCheck.Pointer():
-			return name, qLeafArray{qExists(false)}
-		case nlvLikeCheck.Pointer():
+		field, _, _ := c.Leaf()
+		name := string(field)
+		var val json.Marshaler
+		switch c.op {
+		case nlvEquals:
+			val = qLeafArray{qString(c.checkValue)}
+		case nlvEmpty:
+			val = qLeafArray{qExists(false)}
+		case nlvLike:
 			fallthrough
 		default:
-			return name, qLeafArray{qPrefix(c.checkValue)}
+			val = qLeafArray{qPrefix(c.checkValue)}
+		}
+		if c.lang != language.Und {
+			// NOTE(marius): the values in a specific language are found in the corresponding map property,
+			// eg: {"contentMap":{"fr":"..."}}
+			return name + "Map", qFullPattern{c.lang.String(): val}
 		}
+		return name, val
 	case objectChecks:
 		return keyObject, buildFullPattern(Checks(c))
 	case actorChecks:
@@ -192,12 +219,87 @@ func getLeafValue(ff Check) (string, json.Marshaler) {
 		return keyTarget, buildFullPattern(Checks(c))
 	case tagChecks:
 		return keyTag, buildFullPattern(Checks(c))
+	case mentionCheck:
+		// NOTE(marius): the hashtag and emoji names are compared case-insensitively, which we can't express
+		// in the patterns, so we translate only the mentions.
+		return keyTag, qFullPattern{"type": qLeafArray{qString(index.MentionType)}, "href": qLeafArray{qString(c)}}
+	case patternCheck:
+		// NOTE(marius): quamina doesn't support the RE2 regular expressions, so we can translate only the glob patterns.
+		if !c.glob || c.re == nil {
+			return "", nil
+		}
+		key := string(c.field)
+		if c.field == FieldIRI {
+			key = keyID
+		}
+		return key, qLeafArray{qShellstyle(c.expr)}
+	case numericCheck:
+		if !validPropertyPath(c.path) {
+			return "", nil
+		}
+		return numericPattern(c)
+	case propertyChecks:
+		if !validPropertyPath(c.path) {
+			return "", nil
+		}
+		return propertyPattern(c)
 	case checkAll:
 		return "-", buildFullPattern(Checks(c))
 	}
 	return "", nil
 }
 
+// numericPattern builds a nested numeric comparison pattern for the dotted path of the numeric check.
+func numericPattern(c numericCheck) (string, json.Marshaler) {
+	var val qNumeric
+	switch c.op {
+	case OperatorGt:
+		val = qNumeric{opGt, c.value}
+	case OperatorGte:
+		val = qNumeric{opGte, c.value}
+	case OperatorLt:
+		val = qNumeric{opLt, c.value}
+	case OperatorLte:
+		val = qNumeric{opLte, c.value}
+	case OperatorBetween:
+		val = qNumeric{opGte, c.value, opLte, c.max}
+	default:
+		return "", nil
+	}
+	path := strings.Split(c.path, ".")
+	var pat json.Marshaler = qLeafArray{val}
+	for i := len(path) - 1; i > 0; i-- {
+		pat = qFullPattern{path[i]: pat}
+	}
+	return path[0], pat
+}
+
+// propertyPattern builds a nested pattern for the dotted path of the Property check.
+// The checks on the ID or IRI of the property value get applied on the innermost property directly,
+// as in the raw documents the values are usually just strings.
+func propertyPattern(c propertyChecks) (string, json.Marshaler) {
+	var val json.Marshaler
+	if len(c.fns) == 1 {
+		if k, v := getLeafValue(c.fns[0]); k == keyID {
+			val = v
+		}
+	}
+	if val == nil {
+		val = buildFullPattern(c.fns)
+	}
+	path := strings.Split(c.path, ".")
+	for i := len(path) - 1; i > 0; i-- {
+		val = qFullPattern{path[i]: val}
+	}
+	return path[0], val
+}
+
+// patternMatcher is the part of the [quamina.Quamina] API used by the RawMatcher.
+type patternMatcher interface {
+	AddPattern(x quamina.X, pattern string) error
+	MatchesForEvent(event []byte) ([]quamina.X, error)
+}
+
 func RawMatcher(filters Checks) func([]byte) bool {
 	alwaysT := func(_ []byte) bool {
 		return true
@@ -211,6 +313,16 @@ func RawMatcher(filters Checks) func([]byte) bool {
 		// NOTE(marius): failed to initialize quamina, fallback to other filtering methods
 		return alwaysT
 	}
+	return rawMatcher(q, filters)
+}
+
+func rawMatcher(q patternMatcher, filters Checks) func([]byte) bool {
+	alwaysT := func(_ []byte) bool {
+		return true
+	}
+	alwaysF := func(_ []byte) bool {
+		return false
+	}
 
 	// NOTE(marius): we build two patters, and if none is valid we skip matching.
 	noValidPattern := true
@@ -219,9 +331,12 @@ func RawMatcher(filters Checks) func([]byte) bool {
 	// is in a denormalized/not-flattened form.
 	pattern1, _ := buildFullPattern(filters).MarshalJSON()
 	if len(pattern1) > 2 {
-		if err = q.AddPattern("full", string(pattern1)); err == nil {
-			noValidPattern = false
+		if err := q.AddPattern("full", string(pattern1)); err != nil {
+			// NOTE(marius): the pattern contains conditions quamina can't match, so we can't tell which documents
+			// match the filters, and we fail closed, instead of letting all of them through.
+			return alwaysF
 		}
+		noValidPattern = false
 	}
 	// NOTE(marius): the second pattern assumes the JSON is normalized,
 	// therefore we convert all filters beyond depth 1 to just an "exist" check.
@@ -229,9 +344,10 @@ func RawMatcher(filters Checks) func([]byte) bool {
 	// and the ones for Objects with Tag.
 	pattern2, _ := buildFullPattern(TopLevelChecks(filters...)).MarshalJSON()
 	if len(pattern2) > 2 && !bytes.Equal(pattern1, pattern2) {
-		if err = q.AddPattern("top-level", string(pattern2)); err == nil {
-			noValidPattern = false
+		if err := q.AddPattern("top-level", string(pattern2)); err != nil {
+			return alwaysF
 		}
+		noValidPattern = false
 	}
 
 	if noValidPattern {
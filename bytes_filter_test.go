@@ -2,7 +2,15 @@ package filters
 
 import (
 	"bytes"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
 	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+	"golang.org/x/text/language"
+	"quamina.net/go/quamina/v2"
 )
 
 func Test_quaminaPattern(t *testing.T) {
@@ -220,6 +228,61 @@ func Test_quaminaPattern(t *testing.T) {
 			},
 			want: []byte(`{"actor":[{"exists":true}],"object":[{"exists":true}],"type":["Create","Update"]}`),
 		},
+		{
+			name:   "property",
+			checks: Checks{Property("mediaType", SameIRI("image/png"))},
+			want:   []byte(`{"mediaType":["image/png"]}`),
+		},
+		{
+			name:   "nested property",
+			checks: Checks{Property("icon.url", IRILike("https://example.com"))},
+			want:   []byte(`{"icon":{"url":[{"prefix":"https://example.com"}]}}`),
+		},
+		{
+			name:   "property with name",
+			checks: Checks{Property("icon", NameIs("avatar"))},
+			want:   []byte(`{"icon":{"name":["avatar"]}}`),
+		},
+		{
+			name:   "property with invalid path",
+			checks: Checks{Property(`icon"`, NameIs("avatar"))},
+			want:   nil,
+		},
+		{
+			name:   "totalItems greater than",
+			checks: Checks{Gt("totalItems", 10)},
+			want:   []byte(`{"totalItems":[{"numeric":[">",10]}]}`),
+		},
+		{
+			name:   "replies.totalItems between",
+			checks: Checks{Between("replies.totalItems", 1, 2.5)},
+			want:   []byte(`{"replies":{"totalItems":[{"numeric":[">=",1,"<=",2.5]}]}}`),
+		},
+		{
+			name:   "content in language",
+			checks: Checks{ContentIs("chat", language.French)},
+			want:   []byte(`{"contentMap":{"fr":["chat"]}}`),
+		},
+		{
+			name:   "id glob",
+			checks: Checks{MustIDGlob("https://*.example.com/*")},
+			want:   []byte(`{"id":[{"shellstyle":"https://*.example.com/*"}]}`),
+		},
+		{
+			name:   "id regular expression is skipped",
+			checks: Checks{MustIDMatches(`^https://`)},
+			want:   nil,
+		},
+		{
+			name:   "mention",
+			checks: Checks{Mentions("https://example.com/~jdoe")},
+			want:   []byte(`{"tag":{"href":["https://example.com/~jdoe"],"type":["Mention"]}}`),
+		},
+		{
+			name:   "hashtag is skipped",
+			checks: Checks{HasHashtag("golang")},
+			want:   nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -361,3 +424,51 @@ func TestMatchRaw(t *testing.T) {
 		})
 	}
 }
+
+// numericRejecter is a patternMatcher which, like the quamina versions without numeric comparisons,
+// rejects the numeric patterns, and matches any document for the others.
+type numericRejecter map[quamina.X]string
+
+func (n numericRejecter) AddPattern(x quamina.X, pattern string) error {
+	if strings.Contains(pattern, `"numeric"`) {
+		return errors.New("unsupported numeric pattern")
+	}
+	n[x] = pattern
+	return nil
+}
+
+func (n numericRejecter) MatchesForEvent(_ []byte) ([]quamina.X, error) {
+	return slices.Collect(maps.Keys(n)), nil
+}
+
+func Test_rawMatcher(t *testing.T) {
+	raw := []byte(`{"id":"https://example.com/1","type":"Note","replies":{"totalItems":3}}`)
+	tests := []struct {
+		name    string
+		filters Checks
+		want    bool
+	}{
+		{
+			name:    "supported pattern",
+			filters: Checks{HasType(vocab.NoteType)},
+			want:    true,
+		},
+		{
+			name:    "untranslatable checks",
+			filters: Checks{WithMaxCount(1)},
+			want:    true,
+		},
+		{
+			name:    "rejected pattern fails closed",
+			filters: Checks{HasType(vocab.NoteType), Gt("replies.totalItems", 1)},
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rawMatcher(numericRejecter{}, tt.filters)(raw); got != tt.want {
+				t.Errorf("rawMatcher() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package filters
 
 import (
+	"reflect"
+	"runtime"
 	"testing"
 
 	vocab "github.com/go-ap/activitypub"
@@ -134,6 +136,20 @@ func TestChecks_runOnItems(t *testing.T) {
 	}
 }
 
+func sameFns(f1, f2 any) bool {
+	p1 := reflect.ValueOf(f1).Pointer()
+	p2 := reflect.ValueOf(f2).Pointer()
+	if p1 == p2 {
+		return true
+	}
+	if p1 == 0 || p2 == 0 {
+		return false
+	}
+	s1, l1 := runtime.FuncForPC(p1).FileLine(p1)
+	s2, l2 := runtime.FuncForPC(p2).FileLine(p2)
+	return s1 == s2 && l1 == l2
+}
+
 func Test_checkFn(t *testing.T) {
 	t.Skipf("can't compare functions")
 	tests := []struct {
@@ -0,0 +1,86 @@
+package filters
+
+import (
+	"net/url"
+	"strings"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/filters/index"
+)
+
+// Collation configures the differences between the values that the Like and Is checks ignore.
+// See [index.Collation] for the available options.
+type Collation = index.Collation
+
+const (
+	CaseFold   = index.CaseFold
+	AccentFold = index.AccentFold
+	WidthFold  = index.WidthFold
+	// ExactCollation compares the values as they are, after normalizing them to the Unicode NFC form.
+	ExactCollation Collation = 0
+	// DefaultCollation is the collation of the index tokens, it ignores the differences in case, diacritics
+	// and character width, so "cafe" matches "Café". The checks use it only when configured with WithCollation.
+	DefaultCollation = index.DefaultCollation
+)
+
+// WithCollation returns a copy of the c Check where the natural language values checks,
+// and the Like checks on IRI properties, like IDLike or URLLike, use the coll Collation, eg:
+//
+//	WithCollation(CaseFold, ContentLike("cafe"))
+//
+// matches "Cafe", but not "Café".
+// By default, the Is checks of the natural language values ignore only the differences in case, and the Like ones,
+// together with the IRI checks, are compared exactly. The checks are resolved exactly from the indexes
+// only when they use the DefaultCollation, which the index tokens are folded with, eg:
+//
+//	WithCollation(DefaultCollation, NameLike("cafe"))
+//
+// The SQL conditions fold the values only for the checks configured with WithCollation, so by default
+// they compare the columns as they are, and they can use the existing indexes of the database.
+func WithCollation(coll Collation, c Check) Check {
+	return Rewrite(c, func(c Check) (Check, bool) {
+		switch cc := c.(type) {
+		case naturalLanguageValCheck:
+			cc.collation = coll
+			cc.collated = true
+			return cc, false
+		case collatedLike:
+			cc.collation = coll
+			return cc, false
+		case collatable:
+			return collatedLike{like: cc, collation: coll}, false
+		}
+		return c, true
+	})
+}
+
+// collatable is implemented by the Like checks on IRI properties, which can be wrapped by a collatedLike.
+type collatable interface {
+	Leaf
+	matchCollated(vocab.Item, Collation) bool
+}
+
+// collatedLike applies a Like check on an IRI property using a Collation different from the exact one.
+type collatedLike struct {
+	like      collatable
+	collation Collation
+}
+
+func (c collatedLike) Match(it vocab.Item) bool {
+	return c.like.matchCollated(it, c.collation)
+}
+
+func (c collatedLike) Leaf() (Field, Operator, Value) {
+	return c.like.Leaf()
+}
+
+// Collation returns the collation used for comparing the values.
+func (c collatedLike) Collation() Collation {
+	return c.collation
+}
+
+// collatedContains checks if s contains the URL escaped frag, using the coll Collation.
+func collatedContains(s, frag string, coll Collation) bool {
+	frag, _ = url.QueryUnescape(frag)
+	return strings.Contains(index.Fold(s, coll), index.Fold(frag, coll))
+}
@@ -0,0 +1,159 @@
+package filters
+
+import (
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+)
+
+func TestWithCollation(t *testing.T) {
+	ob := &vocab.Object{
+		ID:      "https://example.com/Café",
+		Name:    lnl("und", "Café Crème"),
+		Content: lnl("und", "<p>Ｃａｆｅ</p>"),
+		URL:     vocab.IRI("https://example.com/Notes/1"),
+	}
+	tests := []struct {
+		name  string
+		check Check
+		want  bool
+	}{
+		{
+			name:  "name like is exact by default",
+			check: NameLike("cafe creme"),
+			want:  false,
+		},
+		{
+			name:  "name is ignores the case by default",
+			check: NameIs("CAFÉ CRÈME"),
+			want:  true,
+		},
+		{
+			name:  "name is doesn't fold the accents by default",
+			check: NameIs("CAFE CREME"),
+			want:  false,
+		},
+		{
+			name:  "content like is exact by default",
+			check: ContentLike("cafe"),
+			want:  false,
+		},
+		{
+			name:  "name like with default collation",
+			check: WithCollation(DefaultCollation, NameLike("cafe creme")),
+			want:  true,
+		},
+		{
+			name:  "name is with default collation",
+			check: WithCollation(DefaultCollation, NameIs("CAFE CREME")),
+			want:  true,
+		},
+		{
+			name:  "content like with default collation",
+			check: WithCollation(DefaultCollation, ContentLike("cafe")),
+			want:  true,
+		},
+		{
+			name:  "name like with case folding only",
+			check: WithCollation(CaseFold, NameLike("cafe")),
+			want:  false,
+		},
+		{
+			name:  "name like with case folding and accents",
+			check: WithCollation(CaseFold, NameLike("café")),
+			want:  true,
+		},
+		{
+			name:  "name like with exact collation",
+			check: WithCollation(ExactCollation, NameLike("café")),
+			want:  false,
+		},
+		{
+			name:  "content like without width folding",
+			check: WithCollation(CaseFold|AccentFold, ContentLike("cafe")),
+			want:  false,
+		},
+		{
+			name:  "id like is exact by default",
+			check: IDLike("cafe"),
+			want:  false,
+		},
+		{
+			name:  "id like with default collation",
+			check: WithCollation(DefaultCollation, IDLike("cafe")),
+			want:  true,
+		},
+		{
+			name:  "id like with URL escaped value",
+			check: WithCollation(DefaultCollation, IDLike("caf%C3%A9")),
+			want:  true,
+		},
+		{
+			name:  "iri like with case folding",
+			check: WithCollation(CaseFold, IRILike("CAFÉ")),
+			want:  true,
+		},
+		{
+			name:  "url like with case folding",
+			check: WithCollation(CaseFold, URLLike("notes")),
+			want:  true,
+		},
+		{
+			name:  "collation in aggregate",
+			check: WithCollation(CaseFold, Any(URLLike("NOTES"), NameLike("nothing"))),
+			want:  true,
+		},
+		{
+			name:  "collation overrides the previous one",
+			check: WithCollation(ExactCollation, WithCollation(CaseFold, URLLike("notes"))),
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check.Match(ob); got != tt.want {
+				t.Errorf("Match() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollated_Collation(t *testing.T) {
+	tests := []struct {
+		name  string
+		check Check
+		want  Collation
+	}{
+		{
+			name:  "natural language values like default",
+			check: ContentLike("test"),
+			want:  ExactCollation,
+		},
+		{
+			name:  "natural language values is default",
+			check: ContentIs("test"),
+			want:  CaseFold,
+		},
+		{
+			name:  "natural language values",
+			check: WithCollation(CaseFold, NameIs("test")),
+			want:  CaseFold,
+		},
+		{
+			name:  "iri like",
+			check: WithCollation(AccentFold, IRILike("test")),
+			want:  AccentFold,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := tt.check.(Collated)
+			if !ok {
+				t.Fatalf("%T does not implement Collated", tt.check)
+			}
+			if got := c.Collation(); got != tt.want {
+				t.Errorf("Collation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
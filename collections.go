@@ -34,6 +34,10 @@ func (cnt *counter) Match(it vocab.Item) bool {
 	return true
 }
 
+func (cnt *counter) Leaf() (Field, Operator, Value) {
+	return FieldNone, OperatorMaxItems, cnt.max
+}
+
 func (cnt *counter) GoString() string {
 	return "maxItems=" + strconv.Itoa(cnt.max)
 }
@@ -77,6 +81,14 @@ func (isAfter *afterCrit) Match(it vocab.Item) bool {
 	return isAfter.check
 }
 
+func (isAfter *afterCrit) Combinator() (Field, Operator) {
+	return FieldNone, OperatorAfter
+}
+
+func (isAfter *afterCrit) Children() []Check {
+	return isAfter.fns
+}
+
 type afterCrit struct {
 	check bool
 	fns   []Check
@@ -105,3 +117,11 @@ func (isBefore *beforeCrit) Match(it vocab.Item) bool {
 	}
 	return isBefore.check
 }
+
+func (isBefore *beforeCrit) Combinator() (Field, Operator) {
+	return FieldNone, OperatorBefore
+}
+
+func (isBefore *beforeCrit) Children() []Check {
+	return isBefore.fns
+}
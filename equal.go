@@ -0,0 +1,210 @@
+package filters
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spaolacci/murmur3"
+)
+
+// Equal checks if the two received Check trees are structurally equivalent.
+// The comparison is done on their canonical form, so the order of operands
+// for the Any and All aggregators, or the order of the types in a HasType check, do not matter.
+func Equal(a, b Check) bool {
+	return canonicalString(Canonical(a)) == canonicalString(Canonical(b))
+}
+
+// Hash returns a hash of the canonical form of the Check.
+// It is stable between runs, and two checks for which Equal returns true have the same hash,
+// so it can be used as a key for caching results.
+func Hash(c Check) uint64 {
+	return murmur3.Sum64([]byte(canonicalString(Canonical(c))))
+}
+
+// Canonical returns the canonical form of the received Check.
+// The canonical form:
+//   - removes nil operands,
+//   - flattens nested Any and All aggregators of the same kind,
+//   - removes duplicate operands and sorts them, leaving pagination checks at the end,
+//   - removes double negations,
+//   - unwraps Any and All aggregators with a single operand.
+//
+// The pagination checks are stateful, the ones in the canonical form are new instances with their state reset.
+func Canonical(c Check) Check {
+	switch cc := c.(type) {
+	case nil:
+		return nil
+	case checkAll:
+		fns := canonicalOperands(cc, func(c Check) ([]Check, bool) {
+			all, ok := c.(checkAll)
+			return all, ok
+		})
+		if len(fns) == 1 {
+			return fns[0]
+		}
+		return checkAll(fns)
+	case checkAny:
+		fns := canonicalOperands(cc, func(c Check) ([]Check, bool) {
+			anys, ok := c.(checkAny)
+			return anys, ok
+		})
+		if len(fns) == 1 {
+			return fns[0]
+		}
+		return checkAny(fns)
+	case notCrit:
+		if len(cc) == 0 || cc[0] == nil {
+			return cc
+		}
+		inner := Canonical(cc[0])
+		if n, ok := inner.(notCrit); ok && len(n) > 0 && n[0] != nil {
+			return n[0]
+		}
+		return notCrit{inner}
+	case actorChecks:
+		return actorChecks(canonicalOperands(cc, nil))
+	case objectChecks:
+		return objectChecks(canonicalOperands(cc, nil))
+	case targetChecks:
+		return targetChecks(canonicalOperands(cc, nil))
+	case tagChecks:
+		return tagChecks(canonicalOperands(cc, nil))
+	case propertyChecks:
+		return propertyChecks{path: cc.path, fns: canonicalOperands(cc.fns, nil)}
+	case withTypes:
+		types := slices.Clone(cc)
+		slices.Sort(types)
+		return withTypes(slices.Compact(types))
+	case *afterCrit:
+		return &afterCrit{fns: canonicalOperands(cc.fns, nil)}
+	case *beforeCrit:
+		return &beforeCrit{check: true, fns: canonicalOperands(cc.fns, nil)}
+	case *counter:
+		return &counter{max: cc.max}
+	}
+	return c
+}
+
+func canonicalOperands(fns []Check, flattenFn func(Check) ([]Check, bool)) []Check {
+	result := make([]Check, 0, len(fns))
+	keys := make(map[string]struct{}, len(fns))
+	add := func(c Check) {
+		k := canonicalString(c)
+		if _, ok := keys[k]; ok {
+			return
+		}
+		keys[k] = struct{}{}
+		result = append(result, c)
+	}
+	for _, fn := range fns {
+		fn = Canonical(fn)
+		if fn == nil {
+			continue
+		}
+		if flattenFn != nil {
+			if operands, ok := flattenFn(fn); ok {
+				// NOTE(marius): the operands of a canonical check are already canonical.
+				for _, op := range operands {
+					add(op)
+				}
+				continue
+			}
+		}
+		add(fn)
+	}
+	slices.SortStableFunc(result, compareCanonical)
+	return result
+}
+
+// compareCanonical orders the checks by their canonical string representation,
+// with the exception of the pagination checks, which keep their relative order at the end of the list.
+func compareCanonical(a, b Check) int {
+	af, bf := isFilterFn(a), isFilterFn(b)
+	switch {
+	case af && bf:
+		return strings.Compare(canonicalString(a), canonicalString(b))
+	case af:
+		return -1
+	case bf:
+		return 1
+	}
+	return 0
+}
+
+func canonicalString(c Check) string {
+	s := strings.Builder{}
+	writeCanonical(&s, c)
+	return s.String()
+}
+
+func writeCanonicalList(s *strings.Builder, name string, fns []Check) {
+	s.WriteString(name)
+	s.WriteRune('(')
+	for i, fn := range fns {
+		writeCanonical(s, fn)
+		if i < len(fns)-1 {
+			s.WriteRune(',')
+		}
+	}
+	s.WriteRune(')')
+}
+
+func writeCanonical(s *strings.Builder, c Check) {
+	switch cc := c.(type) {
+	case nil:
+		s.WriteString("nil")
+	case checkAll:
+		writeCanonicalList(s, "all", cc)
+	case checkAny:
+		writeCanonicalList(s, "any", cc)
+	case notCrit:
+		writeCanonicalList(s, "not", cc)
+	case actorChecks:
+		writeCanonicalList(s, keyActor, cc)
+	case objectChecks:
+		writeCanonicalList(s, keyObject, cc)
+	case targetChecks:
+		writeCanonicalList(s, keyTarget, cc)
+	case tagChecks:
+		writeCanonicalList(s, keyTag, cc)
+	case propertyChecks:
+		writeCanonicalList(s, keyProperty+"."+cc.path, cc.fns)
+	case *afterCrit:
+		writeCanonicalList(s, keyAfter, cc.fns)
+	case *beforeCrit:
+		writeCanonicalList(s, keyBefore, cc.fns)
+	case *counter:
+		s.WriteString(keyMaxItems + "(" + strconv.Itoa(cc.max) + ")")
+	case withTypes:
+		s.WriteString(keyType + "(")
+		for i, typ := range cc {
+			s.WriteString(strconv.Quote(string(typ)))
+			if i < len(cc)-1 {
+				s.WriteRune(',')
+			}
+		}
+		s.WriteRune(')')
+	case naturalLanguageValCheck:
+		_, _ = fmt.Fprintf(s, "%T(%d,%d,%q,%s,%d,%d,%t)", cc, cc.typ, cc.op, cc.checkValue, cc.lang, cc.text, cc.collation, cc.collated)
+	case patternCheck:
+		_, _ = fmt.Fprintf(s, "%T(%s,%t,%q,%d)", cc, cc.field, cc.glob, cc.expr, cc.text)
+	case collatedLike:
+		_, _ = fmt.Fprintf(s, "%T(%d,", cc, cc.collation)
+		writeCanonical(s, cc.like)
+		s.WriteRune(')')
+	case languageCheck:
+		_, _ = fmt.Fprintf(s, "%T(%s)", cc, cc.lang)
+	default:
+		// NOTE(marius): the rest of the checks in this package are simple types based on strings,
+		// or empty structs, so we can represent them by their type name and value.
+		s.WriteString(fmt.Sprintf("%T", c))
+		if v := reflect.ValueOf(c); v.Kind() == reflect.String {
+			s.WriteString("(" + strconv.Quote(v.String()) + ")")
+		} else {
+			_, _ = fmt.Fprintf(s, "(%+v)", c)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package filters
+
+import (
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/text/language"
+)
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Check
+		b    Check
+		want bool
+	}{
+		{
+			name: "nils",
+			want: true,
+		},
+		{
+			name: "nil and not nil",
+			a:    SameID("https://example.com"),
+			want: false,
+		},
+		{
+			name: "same id",
+			a:    SameID("https://example.com"),
+			b:    SameID("https://example.com"),
+			want: true,
+		},
+		{
+			name: "different id",
+			a:    SameID("https://example.com"),
+			b:    SameID("https://example.com/1"),
+			want: false,
+		},
+		{
+			name: "same value, different property",
+			a:    SameID("https://example.com"),
+			b:    SameIRI("https://example.com"),
+			want: false,
+		},
+		{
+			name: "same name",
+			a:    NameIs("john"),
+			b:    NameIs("john"),
+			want: true,
+		},
+		{
+			name: "name is vs name like",
+			a:    NameIs("john"),
+			b:    NameLike("john"),
+			want: false,
+		},
+		{
+			name: "name vs content",
+			a:    NameIs("john"),
+			b:    ContentIs("john"),
+			want: false,
+		},
+		{
+			name: "empty checks",
+			a:    NameEmpty,
+			b:    nameCheck("", nlvEmpty),
+			want: true,
+		},
+		{
+			name: "types in different order",
+			a:    HasType(vocab.NoteType, vocab.ArticleType),
+			b:    HasType(vocab.ArticleType, vocab.NoteType, vocab.NoteType),
+			want: true,
+		},
+		{
+			name: "all in different order",
+			a:    All(SameID("https://example.com"), NameIs("john")),
+			b:    All(NameIs("john"), SameID("https://example.com")),
+			want: true,
+		},
+		{
+			name: "nested all gets flattened",
+			a:    All(SameID("https://example.com"), All(NameIs("john"), HasType(vocab.NoteType))),
+			b:    All(HasType(vocab.NoteType), NameIs("john"), SameID("https://example.com")),
+			want: true,
+		},
+		{
+			name: "any vs all",
+			a:    Any(SameID("https://example.com"), NameIs("john")),
+			b:    All(SameID("https://example.com"), NameIs("john")),
+			want: false,
+		},
+		{
+			name: "single operand all",
+			a:    checkAll{SameID("https://example.com")},
+			b:    SameID("https://example.com"),
+			want: true,
+		},
+		{
+			name: "double negation",
+			a:    Not(Not(SameID("https://example.com"))),
+			b:    SameID("https://example.com"),
+			want: true,
+		},
+		{
+			name: "negation",
+			a:    Not(SameID("https://example.com")),
+			b:    SameID("https://example.com"),
+			want: false,
+		},
+		{
+			name: "object checks",
+			a:    Object(SameID("https://example.com"), HasType(vocab.NoteType)),
+			b:    Object(HasType(vocab.NoteType), SameID("https://example.com")),
+			want: true,
+		},
+		{
+			name: "object vs actor checks",
+			a:    Object(SameID("https://example.com")),
+			b:    Actor(SameID("https://example.com")),
+			want: false,
+		},
+		{
+			name: "max count",
+			a:    WithMaxCount(10),
+			b:    WithMaxCount(10),
+			want: true,
+		},
+		{
+			name: "different max count",
+			a:    WithMaxCount(10),
+			b:    WithMaxCount(11),
+			want: false,
+		},
+		{
+			name: "after",
+			a:    After(SameID("https://example.com")),
+			b:    After(SameID("https://example.com")),
+			want: true,
+		},
+		{
+			name: "after vs before",
+			a:    After(SameID("https://example.com")),
+			b:    Before(SameID("https://example.com")),
+			want: false,
+		},
+		{
+			name: "authorized",
+			a:    Authorized("https://example.com/~jdoe"),
+			b:    Authorized("https://example.com/~jdoe"),
+			want: true,
+		},
+		{
+			name: "authorized public",
+			a:    Authorized(vocab.PublicNS),
+			b:    IsPublic(),
+			want: true,
+		},
+		{
+			name: "same name in different languages",
+			a:    NameIs("john", language.English),
+			b:    NameIs("john", language.French),
+			want: false,
+		},
+		{
+			name: "name in language vs any language",
+			a:    NameIs("john", language.English),
+			b:    NameIs("john"),
+			want: false,
+		},
+		{
+			name: "content with different text options",
+			a:    ContentLike("foo"),
+			b:    WithTextOptions(TextRaw, ContentLike("foo")),
+			want: false,
+		},
+		{
+			name: "name with different collations",
+			a:    NameLike("foo"),
+			b:    WithCollation(CaseFold, NameLike("foo")),
+			want: false,
+		},
+		{
+			name: "name with the implicit and explicit default collation",
+			a:    NameLike("foo"),
+			b:    WithCollation(DefaultCollation, NameLike("foo")),
+			want: false,
+		},
+		{
+			name: "iri like with same collation",
+			a:    WithCollation(CaseFold, IRILike("foo")),
+			b:    WithCollation(CaseFold, IRILike("foo")),
+			want: true,
+		},
+		{
+			name: "iri like with and without collation",
+			a:    IRILike("foo"),
+			b:    WithCollation(CaseFold, IRILike("foo")),
+			want: false,
+		},
+		{
+			name: "same regular expressions",
+			a:    MustIDMatches(`^https://`),
+			b:    MustIDMatches(`^https://`),
+			want: true,
+		},
+		{
+			name: "regular expression and glob",
+			a:    MustIDMatches(`https*`),
+			b:    MustIDGlob(`https*`),
+			want: false,
+		},
+		{
+			name: "hashtags differing in case and prefix",
+			a:    HasHashtag("#GoLang"),
+			b:    HasHashtag("golang"),
+			want: true,
+		},
+		{
+			name: "hashtag and emoji with the same name",
+			a:    HasHashtag("blob"),
+			b:    HasEmoji("blob"),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Equal(tt.a, tt.b); got != tt.want {
+				t.Errorf("Equal(%s, %s) = %t, want %t", canonicalString(tt.a), canonicalString(tt.b), got, tt.want)
+			}
+			if got := Hash(tt.a) == Hash(tt.b); got != tt.want {
+				t.Errorf("Hash(%s) == Hash(%s) = %t, want %t", canonicalString(tt.a), canonicalString(tt.b), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  Check
+		want Check
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name: "leaf",
+			arg:  SameID("https://example.com"),
+			want: SameID("https://example.com"),
+		},
+		{
+			name: "removes nil operands",
+			arg:  checkAll{nil, SameID("https://example.com"), nil},
+			want: SameID("https://example.com"),
+		},
+		{
+			name: "sorts and removes duplicates",
+			arg:  Any(SameID("https://example.com/2"), SameID("https://example.com/1"), SameID("https://example.com/2")),
+			want: checkAny{SameID("https://example.com/1"), SameID("https://example.com/2")},
+		},
+		{
+			name: "pagination checks are last",
+			arg:  checkAll{WithMaxCount(2), SameID("https://example.com/2")},
+			want: checkAll{SameID("https://example.com/2"), WithMaxCount(2)},
+		},
+		{
+			name: "types",
+			arg:  HasType(vocab.NoteType, vocab.ArticleType, vocab.NoteType),
+			want: HasType(vocab.ArticleType, vocab.NoteType),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Canonical(tt.arg); canonicalString(got) != canonicalString(tt.want) {
+				t.Errorf("Canonical() = %s", cmp.Diff(canonicalString(tt.want), canonicalString(got)))
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	checks := []Check{
+		nil,
+		SameID("https://example.com"),
+		SameIRI("https://example.com"),
+		NameIs("https://example.com"),
+		NameLike("https://example.com"),
+		ContentIs("https://example.com"),
+		Not(SameID("https://example.com")),
+		Object(SameID("https://example.com")),
+		Actor(SameID("https://example.com")),
+		HasType(vocab.NoteType),
+		WithMaxCount(2),
+	}
+	hashes := make(map[uint64]Check)
+	for _, c := range checks {
+		h := Hash(c)
+		if h != Hash(c) {
+			t.Errorf("Hash(%s) is not stable", canonicalString(c))
+		}
+		if prev, ok := hashes[h]; ok {
+			t.Errorf("Hash(%s) = Hash(%s)", canonicalString(c), canonicalString(prev))
+		}
+		hashes[h] = c
+	}
+}
@@ -4,55 +4,36 @@ func FilterChecks(fns ...Check) Checks {
 	if len(fns) == 0 {
 		return nil
 	}
-	c := make([]Check, 0)
-	for _, fn := range fns {
-		if isFilterFn(fn) {
-			switch ff := fn.(type) {
-			case checkAny:
-				c = append(c, Any(FilterChecks(Checks(ff)...)...))
-			case checkAll:
-				c = append(c, All(FilterChecks(Checks(ff)...)...))
-			default:
-				c = append(c, fn)
-			}
+	return rewriteChecks(fns, func(c Check) (Check, bool) {
+		if !isFilterFn(c) {
+			return nil, false
 		}
-	}
-	return c
+		return c, isAggregate(c)
+	})
 }
 
 func SameIDChecks(fns ...Check) Checks {
-	c := make([]Check, 0)
-	for _, fn := range fns {
-		switch check := fn.(type) {
+	return rewriteChecks(fns, func(c Check) (Check, bool) {
+		switch c.(type) {
 		case idEquals:
-			return append(c, check)
-		case checkAny:
-			c = append(c, Any(SameIDChecks(check...)...))
-		case checkAll:
-			c = append(c, All(SameIDChecks(check...)...))
+			return c, false
+		case checkAny, checkAll:
+			return c, true
 		}
-	}
-	return c
+		return nil, false
+	})
 }
 
 func IDChecks(fns ...Check) Checks {
-	c := make([]Check, 0)
-	for _, fn := range fns {
-		switch check := fn.(type) {
-		case idEquals:
-			c = append(c, check)
-		case idLike:
-			c = append(c, check)
-		case idNil:
-			c = append(c, check)
-		case checkAny:
-			c = append(c, Any(IDChecks(check...)...))
-		case checkAll:
-			c = append(c, All(IDChecks(check...)...))
-		default:
+	return rewriteChecks(fns, func(c Check) (Check, bool) {
+		switch c.(type) {
+		case idEquals, idLike, idNil:
+			return c, false
+		case checkAny, checkAll:
+			return c, true
 		}
-	}
-	return c
+		return nil, false
+	})
 }
 
 // ItemChecks
@@ -62,22 +43,13 @@ func ItemChecks(fns ...Check) Checks {
 	if len(fns) == 0 {
 		return fns
 	}
-	c := make([]Check, 0)
-	for _, fn := range fns {
-		switch check := fn.(type) {
-		case objectChecks:
-		case actorChecks:
-		case targetChecks:
-		case tagChecks:
-		case checkAny:
-			c = append(c, Any(ItemChecks(check...)...))
-		case checkAll:
-			c = append(c, All(ItemChecks(check...)...))
-		default:
-			c = append(c, check)
+	return rewriteChecks(fns, func(c Check) (Check, bool) {
+		switch c.(type) {
+		case objectChecks, actorChecks, targetChecks, tagChecks:
+			return nil, false
 		}
-	}
-	return c
+		return c, isAggregate(c)
+	})
 }
 
 func PaginationChecks(fns ...Check) Checks {
@@ -91,39 +63,27 @@ func CursorChecks(fns ...Check) Checks {
 	return filterCheckFns(isCursorFn, fns...)
 }
 
+// filterCheckFns returns the checks for which checkFn returns true.
+// It recurses into the Any and All aggregators, which are kept if any of their operands match.
 func filterCheckFns(checkFn func(Check) bool, fns ...Check) Checks {
-	c := make([]Check, 0)
-
-	aggCheck := func(c []Check, fil []Check) {
-		for _, ff := range fil {
-			if !checkFn(ff) {
-				continue
-			}
-			c = append(c, ff)
+	return rewriteChecks(fns, func(c Check) (Check, bool) {
+		if checkFn(c) {
+			return c, false
 		}
-	}
-
-	for _, fn := range fns {
-		if checkFn(fn) {
-			c = append(c, fn)
-		} else {
-			switch fil := fn.(type) {
-			case checkAny:
-				leftover := make([]Check, 0, len(fil))
-				aggCheck(leftover, fil)
-				if len(leftover) > 0 {
-					c = append(c, checkAny(leftover))
-				}
-			case checkAll:
-				leftover := make([]Check, 0, len(fil))
-				aggCheck(leftover, fil)
-				if len(leftover) > 0 {
-					c = append(c, checkAll(leftover))
-				}
-			}
+		if isAggregate(c) {
+			return c, true
 		}
+		return nil, false
+	})
+}
+
+// isAggregate returns true for the Any and All checks.
+func isAggregate(c Check) bool {
+	switch c.(type) {
+	case checkAny, checkAll:
+		return true
 	}
-	return c
+	return false
 }
 
 func MaxCountCheck(fns ...Check) Check {
@@ -184,74 +144,72 @@ func ObjectChecks(fns ...Check) Checks {
 	if len(fns) == 0 {
 		return fns
 	}
-	c := make([]Check, 0)
-	for _, fn := range fns {
-		switch fns := fn.(type) {
-		case objectChecks:
-			c = append(c, fns...)
-		}
-	}
-	return c
+	return topLevelOperands(fns, func(c Check) bool {
+		_, ok := c.(objectChecks)
+		return ok
+	})
 }
 
 func ActorChecks(fns ...Check) Checks {
 	if len(fns) == 0 {
 		return fns
 	}
-	c := make([]Check, 0)
-	for _, fn := range fns {
-		switch fns := fn.(type) {
-		case actorChecks:
-			c = append(c, fns...)
-		}
-	}
-	return c
+	return topLevelOperands(fns, func(c Check) bool {
+		_, ok := c.(actorChecks)
+		return ok
+	})
 }
 
 func TargetChecks(fns ...Check) Checks {
 	if len(fns) == 0 {
 		return fns
 	}
-	c := make([]Check, 0)
-	for _, fn := range fns {
-		switch fns := fn.(type) {
-		case targetChecks:
-			c = append(c, fns...)
-		}
-	}
-	return c
+	return topLevelOperands(fns, func(c Check) bool {
+		_, ok := c.(targetChecks)
+		return ok
+	})
 }
 
 func ActivityChecks(fns ...Check) Checks {
 	if len(fns) == 0 {
 		return fns
 	}
-	c := make([]Check, 0)
-	for _, fn := range fns {
-		switch fns := fn.(type) {
-		case targetChecks:
-			c = append(c, fns...)
-		case objectChecks:
-			c = append(c, fns...)
-		case actorChecks:
-			c = append(c, fns...)
+	return topLevelOperands(fns, func(c Check) bool {
+		switch c.(type) {
+		case targetChecks, objectChecks, actorChecks:
+			return true
 		}
-	}
-	return c
+		return false
+	})
 }
 
 func IntransitiveActivityChecks(fns ...Check) Checks {
 	if len(fns) == 0 {
 		return fns
 	}
-	c := make([]Check, 0, 2)
-	for _, fn := range fns {
-		switch f := fn.(type) {
-		case targetChecks:
-			c = append(c, f...)
-		case actorChecks:
-			c = append(c, f...)
+	return topLevelOperands(fns, func(c Check) bool {
+		switch c.(type) {
+		case targetChecks, actorChecks:
+			return true
 		}
+		return false
+	})
+}
+
+// topLevelOperands returns the operands of the checks in fns for which the match function returns true.
+// It doesn't recurse into the operands.
+func topLevelOperands(fns []Check, match func(Check) bool) Checks {
+	c := make([]Check, 0)
+	for _, fn := range fns {
+		isTop := true
+		Walk(fn, func(cc Check) bool {
+			if isTop {
+				isTop = false
+				return match(cc)
+			}
+			c = append(c, cc)
+			return false
+		})
 	}
 	return c
 }
@@ -290,21 +248,22 @@ func TagChecks(fns ...Check) Checks {
 func TopLevelChecks(fns ...Check) Checks {
 	c := make([]Check, 0)
 	for _, fn := range fns {
-		switch cc := fn.(type) {
-		case checkAll:
-			c = append(c, TopLevelChecks(cc...)...)
-		case checkAny:
-			c = append(c, TopLevelChecks(cc...)...)
-		case targetChecks:
-			c = append(c, Target(NotNilItem))
-		case objectChecks:
-			c = append(c, Object(NotNilItem))
-		case actorChecks:
-			c = append(c, Actor(NotNilItem))
-		case tagChecks:
-		default:
-			c = append(c, cc)
-		}
+		Walk(fn, func(cc Check) bool {
+			switch cc.(type) {
+			case checkAll, checkAny:
+				return true
+			case targetChecks:
+				c = append(c, Target(NotNilItem))
+			case objectChecks:
+				c = append(c, Object(NotNilItem))
+			case actorChecks:
+				c = append(c, Actor(NotNilItem))
+			case tagChecks:
+			default:
+				c = append(c, cc)
+			}
+			return false
+		})
 	}
 	return c
 }
@@ -4,9 +4,10 @@ go 1.25.0
 
 require (
 	github.com/RoaringBitmap/roaring v1.9.4
+	github.com/blevesearch/snowballstem v0.9.0
+	github.com/clipperhouse/uax29/v2 v2.3.0
 	github.com/go-ap/activitypub v0.0.0-20260819152015-c3df165dcded
 	github.com/google/go-cmp v0.7.0
-	github.com/jdkato/prose v1.2.1
 	github.com/leporo/sqlf v1.4.0
 	github.com/spaolacci/murmur3 v1.1.0
 	golang.org/x/text v0.41.0
@@ -22,6 +23,7 @@ require (
 	github.com/charmbracelet/x/ansi v0.9.2 // indirect
 	github.com/charmbracelet/x/cellbuf v0.0.13 // indirect
 	github.com/charmbracelet/x/term v0.2.1 // indirect
+	github.com/clipperhouse/stringish v0.1.1 // indirect
 	github.com/go-ap/errors v0.0.0-20260701132509-92e5e4fd6394 // indirect
 	github.com/go-ap/jsonld v0.0.0-20260607140920-737b40e0ca38 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
@@ -39,7 +41,6 @@ require (
 	golang.org/x/sys v0.47.0 // indirect
 	golang.org/x/term v0.32.0 // indirect
 	golang.org/x/tools v0.48.0 // indirect
-	gopkg.in/neurosnap/sentences.v1 v1.0.7 // indirect
 )
 
 tool (
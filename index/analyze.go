@@ -0,0 +1,125 @@
+package index
+
+import (
+	"github.com/blevesearch/snowballstem"
+	"github.com/blevesearch/snowballstem/danish"
+	"github.com/blevesearch/snowballstem/dutch"
+	"github.com/blevesearch/snowballstem/english"
+	"github.com/blevesearch/snowballstem/finnish"
+	"github.com/blevesearch/snowballstem/french"
+	"github.com/blevesearch/snowballstem/german"
+	"github.com/blevesearch/snowballstem/hungarian"
+	"github.com/blevesearch/snowballstem/italian"
+	"github.com/blevesearch/snowballstem/norwegian"
+	"github.com/blevesearch/snowballstem/portuguese"
+	"github.com/blevesearch/snowballstem/romanian"
+	"github.com/blevesearch/snowballstem/russian"
+	"github.com/blevesearch/snowballstem/spanish"
+	"github.com/blevesearch/snowballstem/swedish"
+	"github.com/blevesearch/snowballstem/turkish"
+	"golang.org/x/text/language"
+)
+
+// term is an analyzed token of a text, together with its position.
+// The positions of the removed stopwords are preserved, so the phrase queries don't match across them.
+type term struct {
+	value string
+	pos   uint32
+}
+
+// analyzeText splits the plain text into the terms used by the full-text index.
+// The text is split at the Unicode word boundaries, the segments without letters or digits are dropped,
+// the stopwords of the lang language are removed, and the remaining words are stemmed using the Snowball
+// stemmer of the language, then folded using the DefaultCollation.
+//
+// The [language.Und] texts are analyzed as English.
+func analyzeText(text string, lang language.Tag) []term {
+	stem := stemmers[analysisLanguage(lang)]
+
+	terms := make([]term, 0)
+	pos := uint32(0)
+	for _, w := range textToWords(text) {
+		pos++
+		if IsStopword(Fold(w, DefaultCollation), lang) {
+			continue
+		}
+		// NOTE(marius): the stemmers rely on the diacritics of the words, so the accents are folded
+		// only after stemming.
+		w = Fold(w, CaseFold|WidthFold)
+		if stem != nil {
+			env := snowballstem.NewEnv(w)
+			stem(env)
+			w = env.Current()
+		}
+		terms = append(terms, term{value: Fold(w, DefaultCollation), pos: pos})
+	}
+	return terms
+}
+
+// analysisLanguage returns the ISO 639 base code of the language the lang texts are analyzed as.
+// The [language.Und] texts are analyzed as English.
+func analysisLanguage(lang language.Tag) string {
+	if lang == language.Und {
+		lang = language.English
+	}
+	base, _ := lang.Base()
+	return base.String()
+}
+
+// stemmers contains the Snowball stemming functions of the languages that have one, by their ISO 639 base code.
+// The words in other languages are indexed as they are.
+var stemmers = map[string]func(*snowballstem.Env) bool{
+	"da": danish.Stem,
+	"de": german.Stem,
+	"en": english.Stem,
+	"es": spanish.Stem,
+	"fi": finnish.Stem,
+	"fr": french.Stem,
+	"hu": hungarian.Stem,
+	"it": italian.Stem,
+	"nb": norwegian.Stem,
+	"nl": dutch.Stem,
+	"no": norwegian.Stem,
+	"pt": portuguese.Stem,
+	"ro": romanian.Stem,
+	"ru": russian.Stem,
+	"sv": swedish.Stem,
+	"tr": turkish.Stem,
+}
+
+func wordSet(words ...string) map[string]struct{} {
+	m := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		m[w] = struct{}{}
+	}
+	return m
+}
+
+// stopwords contains the most common words of some languages, by their ISO 639 base code,
+// which are not indexed as they don't help with ranking the results.
+// The words are folded with the DefaultCollation.
+var stopwords = map[string]map[string]struct{}{
+	"en": wordSet(
+		"a", "about", "an", "and", "are", "as", "at", "be", "but", "by", "for", "from", "has", "have",
+		"he", "her", "his", "i", "if", "in", "into", "is", "it", "its", "me", "my", "no", "not", "of",
+		"on", "or", "our", "she", "so", "than", "that", "the", "their", "them", "then", "there", "these",
+		"they", "this", "to", "was", "we", "were", "what", "when", "which", "who", "will", "with", "you", "your",
+	),
+	"fr": wordSet(
+		"a", "au", "aux", "avec", "ce", "ces", "dans", "de", "des", "du", "elle", "en", "et", "eux", "il",
+		"je", "la", "le", "les", "leur", "lui", "ma", "mais", "me", "mes", "moi", "mon", "ne", "nos", "notre",
+		"nous", "on", "ou", "par", "pas", "pour", "qu", "que", "qui", "sa", "se", "ses", "son", "sur", "ta",
+		"te", "tes", "toi", "ton", "tu", "un", "une", "vos", "votre", "vous",
+	),
+	"de": wordSet(
+		"aber", "als", "am", "an", "auch", "auf", "aus", "bei", "bin", "bist", "das", "dass", "dem", "den",
+		"der", "des", "die", "du", "ein", "eine", "einem", "einen", "einer", "er", "es", "fur", "hat", "ich",
+		"ihr", "im", "in", "ist", "mit", "nicht", "noch", "oder", "sie", "sind", "so", "und", "uns", "von",
+		"war", "wie", "wir", "zu", "zum", "zur",
+	),
+	"es": wordSet(
+		"a", "al", "como", "con", "de", "del", "el", "ella", "en", "es", "esta", "este", "la", "las", "le",
+		"les", "lo", "los", "me", "mi", "no", "nos", "o", "para", "pero", "por", "que", "se", "si", "sin",
+		"su", "sus", "te", "tu", "un", "una", "uno", "y", "ya", "yo",
+	),
+}
@@ -0,0 +1,84 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/text/language"
+)
+
+func Test_analyzeText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		lang language.Tag
+		want []term
+	}{
+		{
+			name: "empty",
+			want: []term{},
+		},
+		{
+			name: "stopwords are removed, positions are kept",
+			text: "The cats of the house",
+			lang: language.English,
+			want: []term{{value: "cat", pos: 2}, {value: "hous", pos: 5}},
+		},
+		{
+			name: "undetermined is analyzed as English",
+			text: "Running Cats!",
+			lang: language.Und,
+			want: []term{{value: "run", pos: 1}, {value: "cat", pos: 2}},
+		},
+		{
+			name: "folded",
+			text: "Crème BRÛLÉE",
+			lang: language.English,
+			want: []term{{value: "creme", pos: 1}, {value: "brulee", pos: 2}},
+		},
+		{
+			name: "english stemming",
+			text: "generously connected",
+			lang: language.English,
+			want: []term{{value: "generous", pos: 1}, {value: "connect", pos: 2}},
+		},
+		{
+			name: "french stopwords and stemming",
+			text: "les chats de la maison",
+			lang: language.French,
+			want: []term{{value: "chat", pos: 2}, {value: "maison", pos: 5}},
+		},
+		{
+			name: "french, folded after stemming",
+			text: "Les maisons étaient grandes",
+			lang: language.French,
+			want: []term{{value: "maison", pos: 2}, {value: "etaient", pos: 3}, {value: "grand", pos: 4}},
+		},
+		{
+			name: "german",
+			text: "Die Häuser",
+			lang: language.German,
+			want: []term{{value: "haus", pos: 2}},
+		},
+		{
+			name: "language without stemmer",
+			text: "Οι γάτες",
+			lang: language.Greek,
+			want: []term{{value: "οι", pos: 1}, {value: "γατεσ", pos: 2}},
+		},
+		{
+			name: "punctuation is dropped",
+			text: "hello , world .",
+			lang: language.English,
+			want: []term{{value: "hello", pos: 1}, {value: "world", pos: 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := analyzeText(tt.text, tt.lang)
+			if !cmp.Equal(got, tt.want, cmp.AllowUnexported(term{})) {
+				t.Errorf("analyzeText() = %s", cmp.Diff(tt.want, got, cmp.AllowUnexported(term{})))
+			}
+		})
+	}
+}
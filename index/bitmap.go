@@ -2,7 +2,10 @@ package index
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/gob"
+	"fmt"
+	"slices"
 	"sync"
 
 	"github.com/RoaringBitmap/roaring/roaring64"
@@ -19,6 +22,11 @@ type (
 		Add(vocab.LinkOrIRI) uint64
 	}
 
+	// Removable is implemented by the Indexable types that support removing the documents they contain.
+	Removable interface {
+		Remove(ref uint64)
+	}
+
 	bitmaps[T Tokenizable] interface {
 		get(key T) *roaring64.Bitmap
 		not(key T) *roaring64.Bitmap
@@ -41,9 +49,26 @@ func murmurHash(it vocab.LinkOrIRI) uint64 {
 
 var HashFn HashFnType = murmurHash
 
+// LookupFn returns the reference of an IRI which is already indexed, or 0 if it's missing.
+// It is used instead of HashFn for the searches and the removals, when the references are allocated
+// by HashFn, like the ones of SequentialRefs. When it's nil, HashFn is used.
+var LookupFn HashFnType
+
+// LookupRef returns the reference of li for searching or removing it, using LookupFn if it's set, or HashFn.
+func LookupRef(li vocab.LinkOrIRI) uint64 {
+	if LookupFn != nil {
+		return LookupFn(li)
+	}
+	return HashFn(li)
+}
+
 type tokenMap[T Tokenizable] struct {
-	w               sync.RWMutex
-	m               map[T]*roaring64.Bitmap
+	w sync.RWMutex
+	m map[T]*roaring64.Bitmap
+	// docs is the forward map containing the tokens of every document, which is used for removing them.
+	docs map[uint64][]T
+	// shared contains the tokens whose bitmaps are shared with the snapshots of the index.
+	shared          cow[T]
 	refsExtractFn   ExtractFnType[uint64]
 	tokensExtractFn ExtractFnType[T]
 }
@@ -55,10 +80,22 @@ func (i *tokenMap[T]) MarshalBinary() ([]byte, error) {
 }
 
 func (i *tokenMap[T]) UnmarshalBinary(data []byte) error {
-	if i.m == nil {
-		i.m = make(map[T]*roaring64.Bitmap)
+	i.m = make(map[T]*roaring64.Bitmap)
+	i.shared = cow[T]{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&i.m); err != nil {
+		return err
+	}
+	// NOTE(marius): the forward map contains the same information as the token bitmaps,
+	// so instead of persisting it, we rebuild it.
+	i.docs = make(map[uint64][]T)
+	for tok, bmp := range i.m {
+		it := bmp.Iterator()
+		for it.HasNext() {
+			ref := it.Next()
+			i.docs[ref] = append(i.docs[ref], tok)
+		}
 	}
-	return gob.NewDecoder(bytes.NewReader(data)).Decode(&i.m)
+	return nil
 }
 
 func (i *tokenMap[T]) Add(li vocab.LinkOrIRI) uint64 {
@@ -80,16 +117,99 @@ func (i *tokenMap[T]) Add(li vocab.LinkOrIRI) uint64 {
 
 	i.w.Lock()
 	defer i.w.Unlock()
+	if i.docs == nil {
+		i.docs = make(map[uint64][]T)
+	}
+	for _, tok := range tokens {
+		i.mutable(tok).AddMany(refs)
+	}
+	for _, ref := range refs {
+		i.docs[ref] = mergeTokens(i.docs[ref], tokens)
+	}
+	return refs[0]
+}
+
+func (i *tokenMap[T]) encodeDocument(ref uint64) ([]byte, error) {
+	i.w.RLock()
+	defer i.w.RUnlock()
+	tokens := i.docs[ref]
+	b := binary.AppendUvarint(nil, uint64(len(tokens)))
+	for _, tok := range tokens {
+		b = appendBytes(b, encodeKey(tok))
+	}
+	return b, nil
+}
+
+func (i *tokenMap[T]) decodeDocument(ref uint64, data []byte) error {
+	r := reader{b: data}
+	count := r.uvarint()
+	tokens := make([]T, 0, min(count, uint64(len(r.b))))
+	for j := uint64(0); j < count && r.err == nil; j++ {
+		tok, ok := decodeKey[T](r.chunk())
+		if !ok && r.err == nil {
+			r.err = fmt.Errorf("%w: invalid token", ErrInvalidFormat)
+		}
+		tokens = append(tokens, tok)
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	i.w.Lock()
+	defer i.w.Unlock()
+	if i.docs == nil {
+		i.docs = make(map[uint64][]T)
+	}
 	for _, tok := range tokens {
+		i.mutable(tok).Add(ref)
+	}
+	i.docs[ref] = mergeTokens(i.docs[ref], tokens)
+	return nil
+}
+
+// mergeTokens returns the doc tokens of a document together with the tokens, sorted and without duplicates.
+func mergeTokens[T Tokenizable](doc, tokens []T) []T {
+	doc = append(doc, tokens...)
+	slices.Sort(doc)
+	return slices.Compact(doc)
+}
+
+// mutable returns the bitmap of the tok token for modifying it, creating it if it doesn't exist,
+// or copying it if it's shared with a snapshot.
+func (i *tokenMap[T]) mutable(tok T) *roaring64.Bitmap {
+	b, ok := i.m[tok]
+	switch {
+	case !ok:
+		b = roaring64.New()
+		i.m[tok] = b
+		i.shared.own(tok)
+	case i.shared.own(tok):
+		b = b.Clone()
+		i.m[tok] = b
+	}
+	return b
+}
+
+// Remove removes the ref document from the bitmaps of all its tokens.
+func (i *tokenMap[T]) Remove(ref uint64) {
+	i.w.Lock()
+	defer i.w.Unlock()
+
+	for _, tok := range i.docs[ref] {
 		if _, ok := i.m[tok]; !ok {
-			i.m[tok] = roaring64.New()
+			continue
+		}
+		b := i.mutable(tok)
+		b.Remove(ref)
+		if b.IsEmpty() {
+			delete(i.m, tok)
 		}
-		i.m[tok].AddMany(refs)
 	}
-	return refs[0]
+	delete(i.docs, ref)
 }
 
-// get returns the bitmap values corresponding to the key.
+// get returns a copy of the bitmap values corresponding to the key, so the callers can modify it
+// while other documents are being added.
 func (i *tokenMap[T]) get(key T) *roaring64.Bitmap {
 	i.w.RLock()
 	defer i.w.RUnlock()
@@ -97,7 +217,7 @@ func (i *tokenMap[T]) get(key T) *roaring64.Bitmap {
 	if !ok {
 		return roaring64.New()
 	}
-	return b
+	return b.Clone()
 }
 
 // not returns the OR-ed bitmap values for all token maps not corresponding
@@ -117,9 +237,12 @@ func (i *tokenMap[T]) not(key T) *roaring64.Bitmap {
 
 // NewIndex intializes a new tokenMap index where the function to extract the references that get
 // indexed is passed directly.
+// The forward map of the index is keyed by the extracted references, so when they are not the references
+// of the documents, like HashFn returns, the documents can't be removed, or written in deltas and segments.
 func NewIndex[T Tokenizable](refsExtractFn ExtractFnType[uint64], tokExtractFn ExtractFnType[T]) Indexable {
 	return &tokenMap[T]{
 		m:               make(map[T]*roaring64.Bitmap),
+		docs:            make(map[uint64][]T),
 		refsExtractFn:   refsExtractFn,
 		tokensExtractFn: tokExtractFn,
 	}
@@ -130,6 +253,7 @@ func NewIndex[T Tokenizable](refsExtractFn ExtractFnType[uint64], tokExtractFn E
 func NewTokenIndex[T Tokenizable](extractFn ExtractFnType[T]) Indexable {
 	return &tokenMap[T]{
 		m:               make(map[T]*roaring64.Bitmap),
+		docs:            make(map[uint64][]T),
 		refsExtractFn:   iriRefFn,
 		tokensExtractFn: extractFn,
 	}
@@ -142,7 +266,7 @@ func GetBitmaps[T Tokenizable](in Indexable, tokens ...T) []*roaring64.Bitmap {
 		return nil
 	}
 	if f, ok := in.(*full); ok {
-		b := (*roaring64.Bitmap)(f).Clone()
+		b := f.indexed()
 		refs := make([]uint64, len(tokens))
 		for i, tok := range tokens {
 			if ref, _ := any(tok).(uint64); ref > 0 {
@@ -168,3 +292,32 @@ func GetBitmaps[T Tokenizable](in Indexable, tokens ...T) []*roaring64.Bitmap {
 	}
 	return nil
 }
+
+// indexed is implemented by the indexes that can return all the documents they contain tokens for.
+type indexed interface {
+	indexed() *roaring64.Bitmap
+}
+
+// indexed returns the ORed bitmaps of all the tokens.
+func (i *tokenMap[T]) indexed() *roaring64.Bitmap {
+	i.w.RLock()
+	defer i.w.RUnlock()
+	b := roaring64.New()
+	for _, v := range i.m {
+		b.Or(v)
+	}
+	return b
+}
+
+// Indexed returns the bitmap of the documents that have at least one token in the in index.
+// For the ByID index these are all the indexed documents, and for the other ones, the documents
+// where the corresponding property is present, so its complement against the ByID index gives
+// the documents where the property is absent.
+//
+// It returns nil if in doesn't support listing its documents.
+func Indexed(in Indexable) *roaring64.Bitmap {
+	if idx, ok := in.(indexed); ok {
+		return idx.indexed()
+	}
+	return nil
+}
@@ -396,3 +396,124 @@ func Test_IRI_index_UnmarshalBinary(t *testing.T) {
 		})
 	}
 }
+
+func TestIndexed(t *testing.T) {
+	items := []vocab.LinkOrIRI{
+		&vocab.Object{ID: "https://example.com/1", AttributedTo: vocab.IRI("https://example.com/~jdoe")},
+		&vocab.Object{ID: "https://example.com/2"},
+		&vocab.Object{ID: "https://example.com/3", InReplyTo: vocab.IRI("https://example.com/1")},
+	}
+	in := Partial(ByID, ByAttributedTo, ByInReplyTo, ByIDTrigram, ByPublished)
+	in.Add(items...)
+
+	tests := []struct {
+		name string
+		in   Indexable
+		want *roaring64.Bitmap
+	}{
+		{
+			name: "nil",
+			in:   nil,
+			want: nil,
+		},
+		{
+			name: "all",
+			in:   in.Indexes[ByID],
+			want: roaring64.BitmapOf(HashFn(items[0]), HashFn(items[1]), HashFn(items[2])),
+		},
+		{
+			name: "attributedTo",
+			in:   in.Indexes[ByAttributedTo],
+			want: roaring64.BitmapOf(HashFn(items[0])),
+		},
+		{
+			name: "inReplyTo",
+			in:   in.Indexes[ByInReplyTo],
+			want: roaring64.BitmapOf(HashFn(items[2])),
+		},
+		{
+			name: "trigrams",
+			in:   in.Indexes[ByIDTrigram],
+			want: roaring64.BitmapOf(HashFn(items[0]), HashFn(items[1]), HashFn(items[2])),
+		},
+		{
+			name: "published is empty",
+			in:   in.Indexes[ByPublished],
+			want: roaring64.New(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Indexed(tt.in)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("Indexed() = %v, want nil", got.ToArray())
+				}
+				return
+			}
+			if got == nil || !got.Equals(tt.want) {
+				t.Errorf("Indexed() = %v, want %v", got, tt.want.ToArray())
+			}
+		})
+	}
+}
+
+func Test_tokenMap_Remove(t *testing.T) {
+	items := []vocab.LinkOrIRI{
+		&vocab.Object{ID: "https://example.com/1", Type: vocab.NoteType},
+		&vocab.Object{ID: "https://example.com/2", Type: vocab.NoteType},
+		&vocab.Object{ID: "https://example.com/3", Type: vocab.ArticleType},
+	}
+	added := NewTokenIndex(ExtractType).(*tokenMap[string])
+	for _, it := range items {
+		added.Add(it)
+	}
+	data, err := added.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	decoded := &tokenMap[string]{}
+	if err = decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+
+	for name, in := range map[string]*tokenMap[string]{"added": added, "decoded": decoded} {
+		t.Run(name, func(t *testing.T) {
+			in.Remove(HashFn(items[0]))
+			in.Remove(HashFn(items[2]))
+
+			if got := in.get(string(vocab.NoteType)); !got.Equals(roaring64.BitmapOf(HashFn(items[1]))) {
+				t.Errorf("Remove() Note bitmap = %v, want %v", got.ToArray(), HashFn(items[1]))
+			}
+			if _, ok := in.m[string(vocab.ArticleType)]; ok {
+				t.Errorf("Remove() kept the empty Article bitmap")
+			}
+			if _, ok := in.docs[HashFn(items[0])]; ok {
+				t.Errorf("Remove() kept the tokens of the removed document")
+			}
+		})
+	}
+}
+
+func TestGetBitmaps_copies(t *testing.T) {
+	ob := &vocab.Object{ID: "https://example.com/1", Type: vocab.NoteType}
+	tokens := NewTokenIndex(ExtractType)
+	tokens.Add(ob)
+	ids := All()
+	ids.Add(ob)
+
+	for name, got := range map[string]*roaring64.Bitmap{
+		"tokens": GetBitmaps(tokens, string(vocab.NoteType))[0],
+		"full":   GetBitmaps[uint64](ids)[0],
+	} {
+		t.Run(name, func(t *testing.T) {
+			got.Clear()
+			if !GetBitmaps(tokens, string(vocab.NoteType))[0].Contains(HashFn(ob)) {
+				t.Errorf("GetBitmaps() returned the token bitmap instead of a copy")
+			}
+			if !Indexed(ids).Contains(HashFn(ob)) {
+				t.Errorf("GetBitmaps() returned the index bitmap instead of a copy")
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package index
+
+import (
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// Collation configures which differences between two strings are ignored when comparing them.
+// The options are flags that can be combined, eg: CaseFold|AccentFold.
+type Collation uint8
+
+const (
+	// CaseFold ignores the differences in case, eg: "Café" matches "CAFÉ".
+	CaseFold Collation = 1 << iota
+	// AccentFold ignores the diacritics, eg: "Café" matches "Cafe".
+	AccentFold
+	// WidthFold ignores the differences between the full and half width forms
+	// of the East Asian characters, eg: "ＡＢＣ" matches "ABC".
+	WidthFold
+)
+
+// DefaultCollation is the collation used by the index tokenizers, and by the natural language values checks
+// configured to use it, which are the only ones resolved exactly from the indexes.
+//
+// NOTE(marius): the indexes persisted before the tokens were folded contain the values as they are, and the folded
+// query tokens don't find them, so they need to be rebuilt. The binary format written by [Index.WriteTo]
+// has always contained folded tokens, while the older gob encoded indexes are rejected by it as invalid.
+const DefaultCollation = CaseFold | AccentFold | WidthFold
+
+// Fold returns s transformed according to the c Collation, so two strings that differ only in the ways
+// ignored by c result in the same value.
+// The result is always in the Unicode NFC normalization form, even if c is 0.
+func Fold(s string, c Collation) string {
+	t := make([]transform.Transformer, 0, 5)
+	if c&WidthFold == WidthFold {
+		t = append(t, width.Fold)
+	}
+	if c&AccentFold == AccentFold {
+		t = append(t, norm.NFD, runes.Remove(runes.In(unicode.Mn)))
+	}
+	t = append(t, norm.NFC)
+	if c&CaseFold == CaseFold {
+		t = append(t, cases.Fold())
+	}
+	result, _, err := transform.String(transform.Chain(t...), s)
+	if err != nil {
+		return norm.NFC.String(s)
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package index
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		c    Collation
+		want string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "exact is normalized to NFC",
+			s:    "Café",
+			want: "Café",
+		},
+		{
+			name: "case fold",
+			s:    "Café",
+			c:    CaseFold,
+			want: "café",
+		},
+		{
+			name: "case fold special casing",
+			s:    "Straße",
+			c:    CaseFold,
+			want: "strasse",
+		},
+		{
+			name: "accent fold",
+			s:    "Café crème",
+			c:    AccentFold,
+			want: "Cafe creme",
+		},
+		{
+			name: "accent fold decomposed",
+			s:    "Café",
+			c:    AccentFold,
+			want: "Cafe",
+		},
+		{
+			name: "width fold",
+			s:    "ＡＢＣ１２３",
+			c:    WidthFold,
+			want: "ABC123",
+		},
+		{
+			name: "default collation",
+			s:    "ＣＡＦÉ Crème",
+			c:    DefaultCollation,
+			want: "cafe creme",
+		},
+		{
+			name: "non latin scripts are kept",
+			s:    "Привет 世界",
+			c:    CaseFold | AccentFold,
+			want: "привет 世界",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fold(tt.s, tt.c); got != tt.want {
+				t.Errorf("Fold(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
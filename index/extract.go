@@ -2,10 +2,10 @@ package index
 
 import (
 	"bytes"
+	"slices"
 	"time"
 
 	vocab "github.com/go-ap/activitypub"
-	"github.com/jdkato/prose/tokenize"
 )
 
 // ExtractType returns the "type" of the [vocab.LinkOrIRI].
@@ -60,19 +60,27 @@ func ExtractPreferredUsername(li vocab.LinkOrIRI) []string {
 	return result
 }
 
-// ExtractSummary returns the tokens in the "summary" property of the [vocab.Item]
+// ExtractSummary returns the tokens in the "summary" property of the [vocab.Item], using the DefaultTokenizer.
 func ExtractSummary(li vocab.LinkOrIRI) []string {
-	it, ok := li.(vocab.Item)
-	if !ok {
-		return nil
-	}
+	return ExtractSummaryWith(DefaultTokenizer)(li)
+}
 
-	result := make([]string, 0)
-	_ = vocab.OnObject(it, func(ob *vocab.Object) error {
-		result = tokenizeNatLangVal(ob.Summary)
-		return nil
-	})
-	return result
+// ExtractSummaryWith returns a function that extracts the tokens in the "summary" property of the [vocab.Item],
+// using the tok Tokenizer.
+func ExtractSummaryWith(tok Tokenizer) ExtractFnType[string] {
+	return func(li vocab.LinkOrIRI) []string {
+		it, ok := li.(vocab.Item)
+		if !ok {
+			return nil
+		}
+
+		result := make([]string, 0)
+		_ = vocab.OnObject(it, func(ob *vocab.Object) error {
+			result = tokenizeNatLangVal(ob.Summary, tok)
+			return nil
+		})
+		return result
+	}
 }
 
 func contentHasDataURI(nlv vocab.NaturalLanguageValues) bool {
@@ -84,23 +92,33 @@ func contentHasDataURI(nlv vocab.NaturalLanguageValues) bool {
 	return false
 }
 
-// ExtractContent returns the tokens in the "content" property of the [vocab.Item]
+// ExtractContent returns the tokens in the "content" property of the [vocab.Item], using the DefaultTokenizer.
 func ExtractContent(li vocab.LinkOrIRI) []string {
-	it, ok := li.(vocab.Item)
-	if !ok {
-		return nil
-	}
+	return ExtractContentWith(DefaultTokenizer)(li)
+}
 
-	result := make([]string, 0)
-	_ = vocab.OnObject(it, func(ob *vocab.Object) error {
-		result = tokenizeNatLangVal(ob.Content)
-		return nil
-	})
-	return result
+// ExtractContentWith returns a function that extracts the tokens in the "content" property of the [vocab.Item],
+// using the tok Tokenizer.
+func ExtractContentWith(tok Tokenizer) ExtractFnType[string] {
+	return func(li vocab.LinkOrIRI) []string {
+		it, ok := li.(vocab.Item)
+		if !ok {
+			return nil
+		}
+
+		result := make([]string, 0)
+		_ = vocab.OnObject(it, func(ob *vocab.Object) error {
+			result = tokenizeNatLangVal(ob.Content, tok)
+			return nil
+		})
+		return result
+	}
 }
 
 // ExtractNatLangVal extracts a single token from the value of the [vocab.NaturalLanguageValues] value.
 // This is meant for the properties that contain single words like "preferredUsername" or "name".
+// The tokens are folded using the DefaultCollation, see its documentation for the indexes persisted
+// before the folding.
 func ExtractNatLangVal(nlv vocab.NaturalLanguageValues) []string {
 	if nlv == nil {
 		return nil
@@ -108,79 +126,50 @@ func ExtractNatLangVal(nlv vocab.NaturalLanguageValues) []string {
 
 	result := make([]string, 0)
 	for _, cc := range nlv {
-		result = append(result, cc.String())
+		result = append(result, Fold(cc.String(), DefaultCollation))
 	}
 	return result
 }
 
-var (
-	sentTokenizer = tokenize.NewPunktSentenceTokenizer()
-	wordTokenizer = tokenize.NewTreebankWordTokenizer()
-)
-
-func textToWords(text string) []string {
-	words := make([]string, 0)
-	for _, s := range sentTokenizer.Tokenize(text) {
-		words = append(words, wordTokenizer.Tokenize(s)...)
-	}
-
-	return words
-}
-
-// tokenizeNatLangVal extracts multiple tokens from the value of the [vocab.NaturalLanguageValues] value.
-// This is meant for the properties that can contain long texts like "summary" or "content".
-// TODO(marius): these usually are HTML, so we should extract the plain text before.
-//
-//	See something like https://pkg.go.dev/github.com/huantt/plaintext-extractor
-func tokenizeNatLangVal(nlv vocab.NaturalLanguageValues) []string {
-	if nlv == nil || contentHasDataURI(nlv) {
-		return nil
-	}
-
-	tt := t{}
+// ExtractHashtags returns the names of the Hashtag items of the "tag" property of the [vocab.Item],
+// normalized using NormalizeHashtag.
+func ExtractHashtags(li vocab.LinkOrIRI) []string {
 	result := make([]string, 0)
-	for _, cc := range nlv {
-		txt := cc.String()
-		for _, tok := range textToWords(txt) {
-			if tt.IsTagOrPunctuation(tok) {
-				continue
+	for _, tag := range TagsOfType(li, HashtagType) {
+		for _, n := range TagName(tag) {
+			if h := NormalizeHashtag(n.String()); h != "" {
+				result = append(result, h)
 			}
-			result = append(result, tok)
 		}
 	}
 	return result
 }
 
-type t struct {
-	st  bool
-	end bool
-}
-
-func (tt *t) IsTagOrPunctuation(s string) bool {
-	if tt.st {
-		if s == ">" {
-			tt.end = true
-			tt.st = false
+// ExtractMentions returns the [vocab.IRI] tokens corresponding to the Mention items of the "tag" property
+// of the [vocab.Item]: the href of the [vocab.Link] mentions, and the ID of the other ones.
+func ExtractMentions(li vocab.LinkOrIRI) []uint64 {
+	iris := make([]vocab.IRI, 0)
+	for _, tag := range TagsOfType(li, MentionType) {
+		if href := TagHref(tag); href != "" {
+			iris = append(iris, href)
 		}
-		return true
-	}
-	if len(s) <= 3 {
-		return true
 	}
-	return false
+	return iriToRefs(iris...)
 }
 
 // ExtractRecipients returns the [vocab.IRI] tokens corresponding to the various addressing properties of
 // the received [vocab.Item].
+// The items that don't implement [vocab.HasRecipients], like the IRIs and the Links, are considered public,
+// so they are indexed under the [vocab.PublicNS] IRI, the same as the recipients checks of the filters consider them.
 // NOTE(marius): Currently it includes *all* the addressing fields, not removing the "blind" ones (Bto and BCC)
 func ExtractRecipients(li vocab.LinkOrIRI) []uint64 {
 	it, ok := li.(vocab.Item)
-	if !ok {
+	if !ok || vocab.IsNil(it) {
 		return nil
 	}
 	r, ok := it.(vocab.HasRecipients)
 	if !ok {
-		return nil
+		return iriToRefs(vocab.PublicNS)
 	}
 	recipients := r.Recipients()
 	if len(recipients) == 0 {
@@ -247,6 +236,76 @@ func ExtractObject(li vocab.LinkOrIRI) []uint64 {
 	return iriToRefs(iris...)
 }
 
+// ExtractTarget returns the [vocab.IRI] tokens corresponding to the "target" property of
+// the received [vocab.Activity]
+func ExtractTarget(li vocab.LinkOrIRI) []uint64 {
+	it, ok := li.(vocab.Item)
+	if !ok {
+		return nil
+	}
+
+	iris := make(vocab.IRIs, 0)
+	_ = vocab.OnIntransitiveActivity(it, func(act *vocab.IntransitiveActivity) error {
+		iris = append(iris, derefObject(act.Target)...)
+		return nil
+	})
+	return nonEmptyRefs(iris...)
+}
+
+// ExtractTag returns the [vocab.IRI] tokens corresponding to the items of the "tag" property of
+// the received [vocab.Item]
+func ExtractTag(li vocab.LinkOrIRI) []uint64 {
+	return extractObjectProperty(li, func(ob *vocab.Object) vocab.Item { return ob.Tag })
+}
+
+// ExtractContext returns the [vocab.IRI] tokens corresponding to the "context" property of
+// the received [vocab.Item]
+func ExtractContext(li vocab.LinkOrIRI) []uint64 {
+	return extractObjectProperty(li, func(ob *vocab.Object) vocab.Item { return ob.Context })
+}
+
+// ExtractAudience returns the [vocab.IRI] tokens corresponding to the "audience" property of
+// the received [vocab.Item]
+func ExtractAudience(li vocab.LinkOrIRI) []uint64 {
+	return extractObjectProperty(li, func(ob *vocab.Object) vocab.Item { return ob.Audience })
+}
+
+// ExtractURL returns the [vocab.IRI] tokens corresponding to the "url" property of the received [vocab.Item],
+// or to the "href" property of the received [vocab.Link].
+func ExtractURL(li vocab.LinkOrIRI) []uint64 {
+	var href vocab.IRI
+	if err := vocab.OnLink(li, func(l *vocab.Link) error {
+		href = l.Href
+		return nil
+	}); err == nil {
+		return nonEmptyRefs(href)
+	}
+	return extractObjectProperty(li, func(ob *vocab.Object) vocab.Item { return ob.URL })
+}
+
+func extractObjectProperty(li vocab.LinkOrIRI, propFn func(*vocab.Object) vocab.Item) []uint64 {
+	it, ok := li.(vocab.Item)
+	if !ok {
+		return nil
+	}
+
+	iris := make(vocab.IRIs, 0)
+	_ = vocab.OnObject(it, func(ob *vocab.Object) error {
+		iris = append(iris, derefObject(propFn(ob))...)
+		return nil
+	})
+	return nonEmptyRefs(iris...)
+}
+
+// nonEmptyRefs returns the tokens of the iris that are not empty, or nil if there are none.
+func nonEmptyRefs(iris ...vocab.IRI) []uint64 {
+	iris = slices.DeleteFunc(iris, func(iri vocab.IRI) bool { return iri == "" })
+	if len(iris) == 0 {
+		return nil
+	}
+	return iriToRefs(iris...)
+}
+
 // ExtractID returns the [vocab.IRI] token corresponding to the "ID" property of
 // the received [vocab.Item]
 func ExtractID(li vocab.LinkOrIRI) []uint64 {
@@ -341,6 +400,43 @@ func ExtractUpdated(li vocab.LinkOrIRI) []uint64 {
 	return []uint64{uint64(upd.Round(time.Hour).UnixMicro())}
 }
 
+// ExtractPublishedTime returns the time of the Published property of the received [vocab.Object], in microseconds.
+// Unlike ExtractPublished, the time is not rounded, so it can be used for ordering the documents.
+// See timeOrder for how the times before 1970 are encoded.
+func ExtractPublishedTime(li vocab.LinkOrIRI) []uint64 {
+	return extractTime(li, func(ob *vocab.Object) time.Time { return ob.Published })
+}
+
+// ExtractUpdatedTime returns the time of the Updated property of the received [vocab.Object], in microseconds.
+// Unlike ExtractUpdated, the time is not rounded, so it can be used for ordering the documents.
+// See timeOrder for how the times before 1970 are encoded.
+func ExtractUpdatedTime(li vocab.LinkOrIRI) []uint64 {
+	return extractTime(li, func(ob *vocab.Object) time.Time { return ob.Updated })
+}
+
+func extractTime(li vocab.LinkOrIRI, timeFn func(*vocab.Object) time.Time) []uint64 {
+	it, ok := li.(vocab.Item)
+	if !ok {
+		return nil
+	}
+
+	var t time.Time
+	_ = vocab.OnObject(it, func(ob *vocab.Object) error {
+		t = timeFn(ob)
+		return nil
+	})
+	if t.IsZero() {
+		return nil
+	}
+	return []uint64{timeOrder(t)}
+}
+
+// timeOrder returns the microseconds of t since the Unix epoch, with the sign bit flipped, so the unsigned values
+// have the same order as the times, including the ones before 1970, which are negative.
+func timeOrder(t time.Time) uint64 {
+	return uint64(t.UnixMicro()) ^ (1 << 63)
+}
+
 // ExtractCollectionItems returns the [vocab.IRI] tokens corresponding to the items in the collection
 // of the received [vocab.Item]
 func ExtractCollectionItems(li vocab.LinkOrIRI) []uint64 {
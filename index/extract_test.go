@@ -140,7 +140,7 @@ func Test_tokenizeNatLangVal(t *testing.T) {
 		{
 			name: "multi word",
 			args: nlv(kv(vocab.NilLangRef, vocab.Content("lorem ipsum dolor sic amet"))),
-			want: []string{"lorem", "ipsum", "dolor", "amet"},
+			want: []string{"lorem", "ipsum", "dolor", "sic", "amet"},
 		},
 		{
 			name: "en-fr",
@@ -156,12 +156,32 @@ func Test_tokenizeNatLangVal(t *testing.T) {
 				kv(vocab.English, vocab.Content("lorem ipsum")),
 				kv(vocab.French, vocab.Content("teste de teste")),
 			),
-			want: []string{"lorem", "ipsum", "teste", "teste"},
+			want: []string{"lorem", "ipsum", "teste", "de", "teste"},
+		},
+		{
+			name: "html",
+			args: nlv(kv(vocab.NilLangRef, vocab.Content(`<p>lorem <strong>ipsum</strong></p><script>alert("dolor")</script>`))),
+			want: []string{"lorem", "ipsum"},
+		},
+		{
+			name: "short words",
+			args: nlv(kv(vocab.NilLangRef, vocab.Content("Go to war, for art!"))),
+			want: []string{"go", "to", "war", "for", "art"},
+		},
+		{
+			name: "unicode word boundaries",
+			args: nlv(kv(vocab.NilLangRef, vocab.Content("l'été: 日本語 — e-mail"))),
+			want: []string{"l'ete", "日", "本", "語", "e", "mail"},
+		},
+		{
+			name: "tags split across elements",
+			args: nlv(kv(vocab.NilLangRef, vocab.Content(`<a href="https://example.com" class="mention">@<span>jdoe</span></a> <br/>hi`))),
+			want: []string{"jdoe", "hi"},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := tokenizeNatLangVal(tt.args)
+			got := tokenizeNatLangVal(tt.args, DefaultTokenizer)
 			sortStrings(got)
 			sortStrings(tt.want)
 			if !cmp.Equal(got, tt.want) {
@@ -229,22 +249,22 @@ func Test_ExtractName(t *testing.T) {
 		{
 			name: "*Object name",
 			arg:  &vocab.Object{Name: nlv(kv(vocab.NilLangRef, vocab.Content("John Doe")))},
-			want: []string{"John Doe"},
+			want: []string{"john doe"},
 		},
 		{
 			name: "Object name",
 			arg:  vocab.Object{Name: nlv(kv(vocab.NilLangRef, vocab.Content("John Doe")))},
-			want: []string{"John Doe"},
+			want: []string{"john doe"},
 		},
 		{
 			name: "*Link name",
 			arg:  &vocab.Link{Name: nlv(kv(vocab.NilLangRef, vocab.Content("The empty page")))},
-			want: []string{"The empty page"},
+			want: []string{"the empty page"},
 		},
 		{
 			name: "Link name",
 			arg:  vocab.Link{Name: nlv(kv(vocab.NilLangRef, vocab.Content("The empty page")))},
-			want: []string{"The empty page"},
+			want: []string{"the empty page"},
 		},
 	}
 	for _, tt := range tests {
@@ -268,12 +288,12 @@ func Test_ExtractPreferredUsername(t *testing.T) {
 		{
 			name: "*Actor name",
 			arg:  &vocab.Actor{PreferredUsername: nlv(kv(vocab.NilLangRef, vocab.Content("John Doe")))},
-			want: []string{"John Doe"},
+			want: []string{"john doe"},
 		},
 		{
 			name: "Actor name",
 			arg:  vocab.Actor{PreferredUsername: nlv(kv(vocab.NilLangRef, vocab.Content("John Doe")))},
-			want: []string{"John Doe"},
+			want: []string{"john doe"},
 		},
 	}
 	for _, tt := range tests {
@@ -298,12 +318,12 @@ func Test_ExtractSummary(t *testing.T) {
 		{
 			name: "*Object summary",
 			arg:  &vocab.Object{Summary: nlv(kv(vocab.NilLangRef, vocab.Content("Lorem ipsum dolor sic amet")))},
-			want: []string{"Lorem", "ipsum", "dolor", "amet"},
+			want: []string{"lorem", "ipsum", "dolor", "sic", "amet"},
 		},
 		{
 			name: "Object summary",
 			arg:  vocab.Object{Summary: nlv(kv(vocab.NilLangRef, vocab.Content("Lorem ipsum dolor sic amet")))},
-			want: []string{"Lorem", "ipsum", "dolor", "amet"},
+			want: []string{"lorem", "ipsum", "dolor", "sic", "amet"},
 		},
 		{
 			name: "skip media URI content",
@@ -332,12 +352,12 @@ func Test_ExtractContent(t *testing.T) {
 		{
 			name: "*Object content",
 			arg:  &vocab.Object{Content: nlv(kv(vocab.NilLangRef, vocab.Content("Lorem ipsum dolor sic amet")))},
-			want: []string{"Lorem", "ipsum", "dolor", "amet"},
+			want: []string{"lorem", "ipsum", "dolor", "sic", "amet"},
 		},
 		{
 			name: "Object content",
 			arg:  vocab.Object{Content: nlv(kv(vocab.NilLangRef, vocab.Content("Lorem ipsum dolor sic amet")))},
-			want: []string{"Lorem", "ipsum", "dolor", "amet"},
+			want: []string{"lorem", "ipsum", "dolor", "sic", "amet"},
 		},
 		{
 			name: "skip media URI content",
@@ -514,6 +534,11 @@ func Test_ExtractRecipients(t *testing.T) {
 			arg:  &vocab.Object{},
 			want: nil,
 		},
+		{
+			name: "IRI",
+			arg:  vocab.IRI("https://example.com/1"),
+			want: []uint64{getRef(vocab.PublicNS)},
+		},
 		{
 			name: "Object",
 			arg:  &vocab.Object{To: vocab.ItemCollection{vocab.IRI("https://example.com/~johnDoe")}},
@@ -675,6 +700,79 @@ func TestExtractUpdated(t *testing.T) {
 	}
 }
 
+func TestExtractPublishedTime(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  vocab.LinkOrIRI
+		want []uint64
+	}{
+		{
+			name: "empty",
+			arg:  nil,
+			want: nil,
+		},
+		{
+			name: "nil published",
+			arg:  &vocab.Object{},
+			want: nil,
+		},
+		{
+			name: "non nil published",
+			arg: &vocab.Object{
+				Published: time.Unix(7213, 5000),
+			},
+			want: []uint64{uint64(time.Unix(7213, 5000).UnixMicro()) | 1<<63},
+		},
+		{
+			name: "published before 1970",
+			arg: &vocab.Object{
+				Published: time.Date(1969, 7, 20, 20, 17, 0, 0, time.UTC),
+			},
+			want: []uint64{1<<63 - uint64(-time.Date(1969, 7, 20, 20, 17, 0, 0, time.UTC).UnixMicro())},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractPublishedTime(tt.arg); !cmp.Equal(got, tt.want) {
+				t.Errorf("ExtractPublishedTime() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestExtractUpdatedTime(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  vocab.LinkOrIRI
+		want []uint64
+	}{
+		{
+			name: "empty",
+			arg:  nil,
+			want: nil,
+		},
+		{
+			name: "nil updated",
+			arg:  &vocab.Object{},
+			want: nil,
+		},
+		{
+			name: "non nil updated",
+			arg: &vocab.Object{
+				Updated: time.Unix(3666, 5000),
+			},
+			want: []uint64{uint64(time.Unix(3666, 5000).UnixMicro()) | 1<<63},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractUpdatedTime(tt.arg); !cmp.Equal(got, tt.want) {
+				t.Errorf("ExtractUpdatedTime() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
 func TestExtractCollectionItems(t *testing.T) {
 	tests := []struct {
 		name string
@@ -718,3 +816,223 @@ func TestExtractCollectionItems(t *testing.T) {
 		})
 	}
 }
+
+var taggedObject = &vocab.Object{
+	ID: "https://example.com/notes/1",
+	Tag: vocab.ItemCollection{
+		&vocab.Link{Type: HashtagType, Href: "https://example.com/tags/golang", Name: nlv(kv(vocab.NilLangRef, vocab.Content("#GoLang")))},
+		&vocab.Object{Type: HashtagType, Name: nlv(kv(vocab.NilLangRef, vocab.Content("Café")))},
+		&vocab.Link{Type: MentionType, Href: "https://example.com/~jdoe"},
+		&vocab.Object{ID: "https://example.com/~alice", Type: MentionType},
+		&vocab.Object{Type: vocab.NoteType, Name: nlv(kv(vocab.NilLangRef, vocab.Content("#note")))},
+	},
+}
+
+func TestExtractHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  vocab.LinkOrIRI
+		want []string
+	}{
+		{
+			name: "empty",
+			arg:  nil,
+			want: []string{},
+		},
+		{
+			name: "no tags",
+			arg:  &vocab.Object{ID: "https://example.com"},
+			want: []string{},
+		},
+		{
+			name: "hashtags",
+			arg:  taggedObject,
+			want: []string{"golang", "café"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractHashtags(tt.arg); !cmp.Equal(got, tt.want) {
+				t.Errorf("ExtractHashtags() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  vocab.LinkOrIRI
+		want []uint64
+	}{
+		{
+			name: "empty",
+			arg:  nil,
+			want: []uint64{},
+		},
+		{
+			name: "mentions",
+			arg:  taggedObject,
+			want: []uint64{
+				getRef("https://example.com/~jdoe"),
+				getRef("https://example.com/~alice"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractMentions(tt.arg); !cmp.Equal(got, tt.want) {
+				t.Errorf("ExtractMentions() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestExtractTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  vocab.LinkOrIRI
+		want []uint64
+	}{
+		{
+			name: "empty",
+			arg:  nil,
+			want: nil,
+		},
+		{
+			name: "object without target",
+			arg:  &vocab.Object{ID: "https://example.com"},
+			want: nil,
+		},
+		{
+			name: "activity",
+			arg:  &vocab.Activity{Type: vocab.AddType, Target: vocab.IRI("https://example.com/featured")},
+			want: []uint64{getRef("https://example.com/featured")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractTarget(tt.arg); !cmp.Equal(got, tt.want) {
+				t.Errorf("ExtractTarget() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestExtractTag(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  vocab.LinkOrIRI
+		want []uint64
+	}{
+		{
+			name: "empty",
+			arg:  nil,
+			want: nil,
+		},
+		{
+			name: "tags without IRIs are skipped",
+			arg: &vocab.Object{
+				Tag: vocab.ItemCollection{
+					vocab.IRI("https://example.com/tags/one"),
+					&vocab.Object{ID: "https://example.com/tags/two"},
+					&vocab.Object{Name: nlv(kv(vocab.NilLangRef, vocab.Content("#three")))},
+				},
+			},
+			want: []uint64{getRef("https://example.com/tags/one"), getRef("https://example.com/tags/two")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractTag(tt.arg); !cmp.Equal(got, tt.want) {
+				t.Errorf("ExtractTag() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestExtractContext(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  vocab.LinkOrIRI
+		want []uint64
+	}{
+		{
+			name: "empty",
+			arg:  nil,
+			want: nil,
+		},
+		{
+			name: "context",
+			arg:  &vocab.Object{Context: vocab.IRI("https://example.com/threads/1")},
+			want: []uint64{getRef("https://example.com/threads/1")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractContext(tt.arg); !cmp.Equal(got, tt.want) {
+				t.Errorf("ExtractContext() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestExtractURL(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  vocab.LinkOrIRI
+		want []uint64
+	}{
+		{
+			name: "empty",
+			arg:  nil,
+			want: nil,
+		},
+		{
+			name: "object urls",
+			arg: &vocab.Object{URL: vocab.ItemCollection{
+				vocab.IRI("https://example.com/1"),
+				vocab.IRI("https://example.com/2"),
+			}},
+			want: []uint64{getRef("https://example.com/1"), getRef("https://example.com/2")},
+		},
+		{
+			name: "link href",
+			arg:  &vocab.Link{ID: "https://example.com/links/1", Href: "https://example.com/1"},
+			want: []uint64{getRef("https://example.com/1")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractURL(tt.arg); !cmp.Equal(got, tt.want) {
+				t.Errorf("ExtractURL() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestExtractAudience(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  vocab.LinkOrIRI
+		want []uint64
+	}{
+		{
+			name: "empty",
+			arg:  nil,
+			want: nil,
+		},
+		{
+			name: "audience",
+			arg:  &vocab.Object{Audience: vocab.ItemCollection{vocab.IRI("https://example.com/groups/1")}},
+			want: []uint64{getRef("https://example.com/groups/1")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractAudience(tt.arg); !cmp.Equal(got, tt.want) {
+				t.Errorf("ExtractAudience() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
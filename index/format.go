@@ -0,0 +1,482 @@
+package index
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"slices"
+
+	vocab "github.com/go-ap/activitypub"
+)
+
+// The binary format of the Index is composed of a header, followed by a list of sections:
+//
+//	file    = header section*
+//	header  = magic[8] version[2] reserved[2]
+//	section = kind[1] type[1] length[4] payload[length] checksum[4]
+//
+// The integers are big endian, the magic is "GOAPIDX\x00", and the checksum is the CRC-32 (Castagnoli)
+// of the kind, type, length and payload of the section.
+//
+// The sections of a snapshot are:
+//   - one refs section, with the IRIs of the documents for every reference, including the colliding ones.
+//   - one index section for every index Type, with the MarshalBinary payload of the index. The ByID index payload
+//     is a single portable roaring bitmap, and the ByPublished and ByUpdated payloads are the times of the documents.
+//     The payloads of the token, full-text and trigram indexes are the gob encodings of their maps, in which
+//     the bitmaps use the portable roaring serialization.
+//
+// The delta sections can be appended after the snapshot, and they contain the documents that got added,
+// updated or removed since the previous write. When loading, they get applied in order.
+//
+// The refs section payload is:
+//
+//	refs = count[uvarint] (ref[8] iris)*
+//	iris = count[uvarint] (length[uvarint] iri[length])*
+//
+// The ByPublished and ByUpdated sections payload is:
+//
+//	times = count[uvarint] (ref[8] time[8])*
+//
+// The delta section payload is:
+//
+//	delta   = count[uvarint] record*
+//	record  = opRemove[1] ref[8] | opPut[1] ref[8] iris count[uvarint] entry*
+//	entry   = type[1] length[uvarint] payload[length]
+//
+// where the payload of each entry contains the tokens of the document in the index of that type:
+//
+//	tokens    = count[uvarint] (length[uvarint] token[length])*
+//	time      = count[uvarint] time[8]*
+//	text      = indexed[1] (length[uvarint] count[uvarint] term* count[uvarint] language*)?
+//	term      = length[uvarint] value[length] count[uvarint] position[uvarint]*
+//	language  = length[uvarint] tag[length]
+//	values    = count[uvarint] nlv*
+//	nlv       = count[uvarint] (length[uvarint] tag[length] length[uvarint] value[length])*
+//
+// The tokens are for the token indexes, and they are the big endian integers, or the bytes of the strings,
+// like the keys of the segments. The time is for the ByPublished and ByUpdated indexes, and it is missing
+// for the documents without one. The text is for the full-text index, where indexed is 0 for the documents
+// that are not in it, and the values are for the trigram indexes.
+const (
+	// FormatVersion is the version of the binary format written by the Index.
+	// The loaders reject the data written using a different version.
+	FormatVersion uint16 = 1
+
+	formatMagic = "GOAPIDX\x00"
+
+	headerSize        = len(formatMagic) + 4
+	sectionHeaderSize = 6
+	checksumSize      = 4
+)
+
+const (
+	sectionRefs uint8 = iota + 1
+	sectionIndex
+	sectionDelta
+	// NOTE(marius): the following section kinds are used only by the segments, see OpenSegment.
+	sectionRefTable
+	sectionDeleted
+	sectionTokens
+)
+
+const (
+	opPut uint8 = iota + 1
+	opRemove
+)
+
+var (
+	// ErrInvalidFormat is returned when loading data that is not a valid Index binary encoding.
+	ErrInvalidFormat = errors.New("invalid index format")
+	// ErrUnsupportedVersion is returned when loading data written using a different FormatVersion.
+	ErrUnsupportedVersion = errors.New("unsupported index format version")
+	// ErrChecksum is returned when the checksum of a section doesn't match its contents.
+	ErrChecksum = errors.New("index section checksum mismatch")
+	// ErrDeltaUnsupported is returned when writing a delta for an Index containing index types
+	// that can't encode the tokens of a single document.
+	ErrDeltaUnsupported = errors.New("index type doesn't support deltas")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// documentCodec is implemented by the indexes that can encode the tokens of a single document,
+// which is needed for writing them in the delta sections.
+type documentCodec interface {
+	encodeDocument(ref uint64) ([]byte, error)
+	decodeDocument(ref uint64, data []byte) error
+}
+
+func appendHeader(b []byte) []byte {
+	b = append(b, formatMagic...)
+	b = binary.BigEndian.AppendUint16(b, FormatVersion)
+	return binary.BigEndian.AppendUint16(b, 0)
+}
+
+func appendSection(b []byte, kind uint8, typ Type, payload []byte) []byte {
+	start := len(b)
+	b = append(b, kind, uint8(typ))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)))
+	b = append(b, payload...)
+	return binary.BigEndian.AppendUint32(b, crc32.Checksum(b[start:], castagnoli))
+}
+
+// appendBytes appends v to b, prefixed by its length.
+func appendBytes(b []byte, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendIRIs(b []byte, iris vocab.IRIs) []byte {
+	b = binary.AppendUvarint(b, uint64(len(iris)))
+	for _, iri := range iris {
+		b = binary.AppendUvarint(b, uint64(len(iri)))
+		b = append(b, iri...)
+	}
+	return b
+}
+
+// iris returns the IRIs of the ref document, the first one being the one in the Ref map.
+// For the segments, they are looked up in the segment's reference table.
+func (i *Index) iris(ref uint64) vocab.IRIs {
+	iri, ok := i.Ref[ref]
+	if !ok {
+		if i.table != nil {
+			return i.table.iris(ref)
+		}
+		return nil
+	}
+	return append(vocab.IRIs{iri}, i.Collisions[ref]...)
+}
+
+func (i *Index) appendSnapshot(b []byte) ([]byte, error) {
+	b = appendHeader(b)
+
+	refs := binary.AppendUvarint(nil, uint64(len(i.Ref)))
+	for _, ref := range slices.Sorted(maps.Keys(i.Ref)) {
+		refs = binary.BigEndian.AppendUint64(refs, ref)
+		refs = appendIRIs(refs, i.iris(ref))
+	}
+	b = appendSection(b, sectionRefs, 0, refs)
+
+	for _, typ := range slices.Sorted(maps.Keys(i.Indexes)) {
+		m, ok := i.Indexes[typ].(encoding.BinaryMarshaler)
+		if !ok {
+			return nil, fmt.Errorf("%w: index type %d can't be encoded", ErrInvalidFormat, typ)
+		}
+		payload, err := m.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		b = appendSection(b, sectionIndex, typ, payload)
+	}
+	return b, nil
+}
+
+func (i *Index) appendDelta(b []byte) ([]byte, error) {
+	types := slices.Sorted(maps.Keys(i.Indexes))
+	for _, typ := range types {
+		if _, ok := i.Indexes[typ].(documentCodec); !ok {
+			return nil, fmt.Errorf("%w: %d", ErrDeltaUnsupported, typ)
+		}
+	}
+
+	records := binary.AppendUvarint(nil, uint64(len(i.changed)))
+	for _, ref := range slices.Sorted(maps.Keys(i.changed)) {
+		iris := i.iris(ref)
+		if len(iris) == 0 {
+			records = append(records, opRemove)
+			records = binary.BigEndian.AppendUint64(records, ref)
+			continue
+		}
+		records = append(records, opPut)
+		records = binary.BigEndian.AppendUint64(records, ref)
+		records = appendIRIs(records, iris)
+		records = binary.AppendUvarint(records, uint64(len(types)))
+		for _, typ := range types {
+			entry, err := i.Indexes[typ].(documentCodec).encodeDocument(ref)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, uint8(typ))
+			records = binary.AppendUvarint(records, uint64(len(entry)))
+			records = append(records, entry...)
+		}
+	}
+	return appendSection(b, sectionDelta, 0, records), nil
+}
+
+// WriteTo writes a snapshot of the whole index to w, using the binary format described in FormatVersion.
+// The changes written are not included in the next WriteDeltaTo.
+func (i *Index) WriteTo(w io.Writer) (int64, error) {
+	i.w.Lock()
+	defer i.w.Unlock()
+
+	b, err := i.appendSnapshot(nil)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	if err == nil {
+		clear(i.changed)
+	}
+	return int64(n), err
+}
+
+// WriteDeltaTo writes to w a delta section containing the documents that got added, updated or removed
+// since the previous WriteTo or WriteDeltaTo. The deltas are meant to be appended to a snapshot,
+// so saving the index doesn't need to rewrite all of it.
+//
+// It returns an error wrapping ErrDeltaUnsupported if any of the indexes can't encode the tokens of a document.
+func (i *Index) WriteDeltaTo(w io.Writer) (int64, error) {
+	i.w.Lock()
+	defer i.w.Unlock()
+
+	b, err := i.appendDelta(nil)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	if err == nil {
+		clear(i.changed)
+	}
+	return int64(n), err
+}
+
+// ReadFrom loads the index from r, which contains a snapshot written by WriteTo, optionally followed by
+// deltas written by WriteDeltaTo.
+// The indexes already present in i are reused, so they keep their extraction functions and tokenizers,
+// and the index types not present in the snapshot are dropped.
+func (i *Index) ReadFrom(r io.Reader) (int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return int64(len(data)), err
+	}
+	return int64(len(data)), i.UnmarshalBinary(data)
+}
+
+func (i *Index) MarshalBinary() ([]byte, error) {
+	i.w.RLock()
+	defer i.w.RUnlock()
+
+	return i.appendSnapshot(nil)
+}
+
+func (i *Index) UnmarshalBinary(data []byte) error {
+	if len(data) < headerSize || string(data[:len(formatMagic)]) != formatMagic {
+		return fmt.Errorf("%w: missing header", ErrInvalidFormat)
+	}
+	if v := binary.BigEndian.Uint16(data[len(formatMagic):]); v != FormatVersion {
+		return fmt.Errorf("%w: %d, expected %d", ErrUnsupportedVersion, v, FormatVersion)
+	}
+
+	i.w.Lock()
+	defer i.w.Unlock()
+
+	existing := i.Indexes
+	i.Ref = make(map[uint64]vocab.IRI)
+	i.Collisions = make(map[uint64]vocab.IRIs)
+	i.refsShared = false
+	i.Indexes = make(map[Type]Indexable)
+	i.changed = make(map[uint64]struct{})
+	i.generation++
+
+	for data = data[headerSize:]; len(data) > 0; {
+		kind, typ, payload, rest, err := readSection(data)
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case sectionRefs:
+			err = i.decodeRefs(payload)
+		case sectionIndex:
+			err = i.decodeIndex(Type(typ), payload, existing)
+		case sectionDelta:
+			err = i.decodeDelta(payload)
+		default:
+			err = fmt.Errorf("%w: unknown section kind %d", ErrInvalidFormat, kind)
+		}
+		if err != nil {
+			return err
+		}
+		data = rest
+	}
+	return nil
+}
+
+func readSection(data []byte) (kind, typ uint8, payload, rest []byte, err error) {
+	if kind, typ, payload, rest, err = splitSection(data); err != nil {
+		return 0, 0, nil, nil, err
+	}
+	if err = checkSection(data[:len(data)-len(rest)]); err != nil {
+		return 0, 0, nil, nil, err
+	}
+	return kind, typ, payload, rest, nil
+}
+
+// splitSection returns the first section in data, without verifying its checksum.
+func splitSection(data []byte) (kind, typ uint8, payload, rest []byte, err error) {
+	if len(data) < sectionHeaderSize {
+		return 0, 0, nil, nil, fmt.Errorf("%w: truncated section header", ErrInvalidFormat)
+	}
+	length := int(binary.BigEndian.Uint32(data[2:]))
+	end := sectionHeaderSize + length
+	if length < 0 || len(data) < end+checksumSize {
+		return 0, 0, nil, nil, fmt.Errorf("%w: truncated section", ErrInvalidFormat)
+	}
+	return data[0], data[1], data[sectionHeaderSize:end], data[end+checksumSize:], nil
+}
+
+// checkSection verifies the checksum of the section, which contains its header, payload and checksum.
+func checkSection(section []byte) error {
+	end := len(section) - checksumSize
+	if crc32.Checksum(section[:end], castagnoli) != binary.BigEndian.Uint32(section[end:]) {
+		return fmt.Errorf("%w: section kind %d, type %d", ErrChecksum, section[0], section[1])
+	}
+	return nil
+}
+
+// reader decodes the values of the section payloads, keeping the first error.
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.err = fmt.Errorf("%w: invalid varint", ErrInvalidFormat)
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *reader) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if uint64(len(r.b)) < n {
+		r.err = fmt.Errorf("%w: truncated payload", ErrInvalidFormat)
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+// chunk returns the bytes prefixed by their length, see appendBytes.
+func (r *reader) chunk() []byte {
+	return r.bytes(r.uvarint())
+}
+
+func (r *reader) byte() uint8 {
+	if v := r.bytes(1); len(v) == 1 {
+		return v[0]
+	}
+	return 0
+}
+
+func (r *reader) uint64() uint64 {
+	if v := r.bytes(8); len(v) == 8 {
+		return binary.BigEndian.Uint64(v)
+	}
+	return 0
+}
+
+func (r *reader) iris() vocab.IRIs {
+	count := r.uvarint()
+	iris := make(vocab.IRIs, 0, min(count, uint64(len(r.b))))
+	for j := uint64(0); j < count && r.err == nil; j++ {
+		iris = append(iris, vocab.IRI(r.bytes(r.uvarint())))
+	}
+	return iris
+}
+
+func (i *Index) setIRIs(ref uint64, iris vocab.IRIs) {
+	i.mutableRefs()
+	delete(i.Collisions, ref)
+	if len(iris) == 0 {
+		delete(i.Ref, ref)
+		return
+	}
+	i.Ref[ref] = iris[0]
+	if len(iris) > 1 {
+		i.Collisions[ref] = iris[1:]
+	}
+}
+
+func (i *Index) decodeRefs(payload []byte) error {
+	r := reader{b: payload}
+	count := r.uvarint()
+	for j := uint64(0); j < count && r.err == nil; j++ {
+		ref := r.uint64()
+		i.setIRIs(ref, r.iris())
+	}
+	return r.err
+}
+
+func (i *Index) decodeIndex(typ Type, payload []byte, existing map[Type]Indexable) error {
+	in, ok := existing[typ]
+	if !ok {
+		in, ok = PartialWithTokenizer(i.Tokenizer(), typ).Indexes[typ]
+	}
+	if !ok {
+		return fmt.Errorf("%w: unknown index type %d", ErrInvalidFormat, typ)
+	}
+	u, ok := in.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return fmt.Errorf("%w: index type %d can't be decoded", ErrInvalidFormat, typ)
+	}
+	if err := u.UnmarshalBinary(payload); err != nil {
+		return err
+	}
+	i.Indexes[typ] = in
+	return nil
+}
+
+func (i *Index) decodeDelta(payload []byte) error {
+	r := reader{b: payload}
+	count := r.uvarint()
+	for j := uint64(0); j < count && r.err == nil; j++ {
+		op := r.byte()
+		ref := r.uint64()
+		if r.err != nil {
+			break
+		}
+		for _, in := range i.Indexes {
+			if rm, ok := in.(Removable); ok {
+				rm.Remove(ref)
+			}
+		}
+		switch op {
+		case opRemove:
+			i.setIRIs(ref, nil)
+		case opPut:
+			i.setIRIs(ref, r.iris())
+			entries := r.uvarint()
+			for k := uint64(0); k < entries && r.err == nil; k++ {
+				typ := Type(r.byte())
+				entry := r.bytes(r.uvarint())
+				if r.err != nil {
+					break
+				}
+				dc, ok := i.Indexes[typ].(documentCodec)
+				if !ok {
+					return fmt.Errorf("%w: delta for missing index type %d", ErrInvalidFormat, typ)
+				}
+				if err := dc.decodeDocument(ref, entry); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("%w: unknown delta operation %d", ErrInvalidFormat, op)
+		}
+	}
+	return r.err
+}
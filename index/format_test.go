@@ -0,0 +1,285 @@
+package index
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+)
+
+var emptyFullIndex = []byte{
+	0x47, 0x4f, 0x41, 0x50, 0x49, 0x44, 0x58, 0x0, 0x0, 0x1, 0x0, 0x0,
+	0x1, 0x0, 0x0, 0x0, 0x0, 0x1, 0x0, 0xc0, 0x9f, 0xde, 0xd2,
+}
+
+func TestIndex_MarshalBinary(t *testing.T) {
+	type fields struct {
+		Ref     map[uint64]vocab.IRI
+		Indexes map[Type]Indexable
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			fields:  fields{},
+			want:    emptyFullIndex,
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &Index{
+				Ref:     tt.fields.Ref,
+				Indexes: tt.fields.Indexes,
+			}
+			got, err := i.MarshalBinary()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MarshalBinary() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MarshalBinary() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// withByte returns a copy of data with the byte at position pos replaced by b.
+func withByte(data []byte, pos int, b byte) []byte {
+	result := bytes.Clone(data)
+	result[pos] = b
+	return result
+}
+
+func TestIndex_UnmarshalBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     []byte
+		want    *Index
+		wantErr error
+	}{
+		{
+			name: "empty",
+			arg:  emptyFullIndex,
+			want: &Index{Ref: make(map[uint64]vocab.IRI), Indexes: make(map[Type]Indexable)},
+		},
+		{
+			name:    "nil",
+			arg:     nil,
+			wantErr: ErrInvalidFormat,
+		},
+		{
+			name:    "invalid magic",
+			arg:     withByte(emptyFullIndex, 0, 'X'),
+			wantErr: ErrInvalidFormat,
+		},
+		{
+			name:    "newer version",
+			arg:     withByte(emptyFullIndex, 9, 2),
+			wantErr: ErrUnsupportedVersion,
+		},
+		{
+			name:    "checksum mismatch",
+			arg:     withByte(emptyFullIndex, 18, 1),
+			wantErr: ErrChecksum,
+		},
+		{
+			name:    "truncated section",
+			arg:     emptyFullIndex[:len(emptyFullIndex)-2],
+			wantErr: ErrInvalidFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := Index{}
+			err := i.UnmarshalBinary(tt.arg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UnmarshalBinary() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (!reflect.DeepEqual(i.Ref, tt.want.Ref) || len(i.Indexes) != len(tt.want.Indexes)) {
+				t.Errorf("UnmarshalBinary() = %v, want %v", i.Ref, tt.want.Ref)
+			}
+		})
+	}
+}
+
+// sameIndexes checks that the got index returns the same results as the want one.
+func sameIndexes(t *testing.T, got, want *Index) {
+	t.Helper()
+
+	if !cmp.Equal(got.Ref, want.Ref) {
+		t.Errorf("Ref = %s", cmp.Diff(want.Ref, got.Ref))
+	}
+	if len(got.Indexes) != len(want.Indexes) {
+		t.Errorf("Indexes = %d types, want %d", len(got.Indexes), len(want.Indexes))
+	}
+	for typ, in := range want.Indexes {
+		if w, g := Indexed(in), Indexed(got.Indexes[typ]); w != nil && (g == nil || !g.Equals(w)) {
+			t.Errorf("Indexed(%d) = %v, want %v", typ, g, w)
+		}
+	}
+	for ref := range want.Ref {
+		for _, typ := range []Type{ByPublished, ByUpdated} {
+			w, wok := timestamp(want.Indexes[typ], ref)
+			g, gok := timestamp(got.Indexes[typ], ref)
+			if w != g || wok != gok {
+				t.Errorf("time %d of %d = %d, want %d", typ, ref, g, w)
+			}
+		}
+	}
+	for _, typ := range []string{string(vocab.NoteType), string(vocab.ArticleType)} {
+		w := roaring64.FastOr(GetBitmaps[string](want.Indexes[ByType], typ)...)
+		g := roaring64.FastOr(GetBitmaps[string](got.Indexes[ByType], typ)...)
+		if !g.Equals(w) {
+			t.Errorf("ByType %s = %v, want %v", typ, g, w)
+		}
+	}
+	for _, q := range []string{"fox", "dog", `"lazy dog"`, "cat"} {
+		if !cmp.Equal(got.Search(q), want.Search(q)) {
+			t.Errorf("Search(%q) = %s", q, cmp.Diff(want.Search(q), got.Search(q)))
+		}
+	}
+	for _, frag := range []string{"ick bro", "azy"} {
+		w := SubstringSearch(want.Indexes[ByContentTrigram], frag, nil)
+		g := SubstringSearch(got.Indexes[ByContentTrigram], frag, nil)
+		if (g == nil) != (w == nil) || (w != nil && !g.Equals(w)) {
+			t.Errorf("SubstringSearch(%q) = %v, want %v", frag, g, w)
+		}
+	}
+}
+
+func TestIndex_WriteDeltaTo(t *testing.T) {
+	in := Partial(extendedIndexTypes...)
+	in.Add(removableObjects...)
+
+	buff := bytes.Buffer{}
+	if _, err := in.WriteTo(&buff); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	in.Add(&vocab.Object{
+		ID:      "https://example.com/3",
+		Type:    vocab.ArticleType,
+		Content: vocab.DefaultNaturalLanguage("The brown cat"),
+	})
+	in.Update(removableObjects[1], &vocab.Object{
+		ID:      "https://example.com/2",
+		Type:    vocab.ArticleType,
+		Content: vocab.DefaultNaturalLanguage("The lazy cat"),
+	})
+	if _, err := in.WriteDeltaTo(&buff); err != nil {
+		t.Fatalf("WriteDeltaTo() error = %v", err)
+	}
+
+	in.Remove("https://example.com/1")
+	if _, err := in.WriteDeltaTo(&buff); err != nil {
+		t.Fatalf("WriteDeltaTo() error = %v", err)
+	}
+
+	got := Partial(extendedIndexTypes...)
+	if _, err := got.ReadFrom(bytes.NewReader(buff.Bytes())); err != nil {
+		t.Fatalf("ReadFrom() error = %v", err)
+	}
+	sameIndexes(t, got, in)
+
+	t.Run("snapshot of the loaded index", func(t *testing.T) {
+		data, err := got.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() error = %v", err)
+		}
+		reloaded := Index{}
+		if err = reloaded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary() error = %v", err)
+		}
+		sameIndexes(t, &reloaded, in)
+	})
+}
+
+func TestIndex_WriteDeltaTo_times(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	objects := []vocab.LinkOrIRI{
+		timedObject("https://example.com/1", now.Add(-72*time.Hour), time.Time{}),
+		timedObject("https://example.com/2", now.Add(-48*time.Hour), now.Add(-24*time.Hour)),
+		timedObject("https://example.com/3", now.Add(-24*time.Hour), time.Time{}),
+	}
+	in := Partial(ByID, ByPublished, ByUpdated)
+	in.Add(objects...)
+
+	buff := bytes.Buffer{}
+	if _, err := in.WriteTo(&buff); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	in.Update(objects[0], timedObject("https://example.com/1", now.Add(-72*time.Hour), now))
+	in.Update(objects[1], timedObject("https://example.com/2", now.Add(-96*time.Hour), time.Time{}))
+	in.Remove("https://example.com/3")
+	in.Add(timedObject("https://example.com/4", now.Add(-time.Hour), time.Time{}))
+	if _, err := in.WriteDeltaTo(&buff); err != nil {
+		t.Fatalf("WriteDeltaTo() error = %v", err)
+	}
+
+	got := Partial(ByID, ByPublished, ByUpdated)
+	if _, err := got.ReadFrom(bytes.NewReader(buff.Bytes())); err != nil {
+		t.Fatalf("ReadFrom() error = %v", err)
+	}
+	sameIndexes(t, got, in)
+
+	refs := make([]uint64, 0, 4)
+	for _, iri := range []vocab.IRI{"https://example.com/1", "https://example.com/2", "https://example.com/3", "https://example.com/4"} {
+		refs = append(refs, HashFn(iri))
+	}
+	want := []uint64{refs[0], refs[3], refs[1], refs[2]}
+	SortByTime(got.Indexes, refs)
+	if !cmp.Equal(refs, want) {
+		t.Errorf("SortByTime() after ReadFrom() = %s", cmp.Diff(want, refs))
+	}
+}
+
+func Test_decodeDocument_truncated(t *testing.T) {
+	in := Partial(extendedIndexTypes...)
+	in.Add(removableObjects...)
+	ref := HashFn(removableObjects[0])
+
+	for typ, idx := range in.Indexes {
+		dc, ok := idx.(documentCodec)
+		if !ok {
+			continue
+		}
+		data, err := dc.encodeDocument(ref)
+		if err != nil {
+			t.Fatalf("encodeDocument() for type %d error = %v", typ, err)
+		}
+		if len(data) < 2 {
+			continue
+		}
+		empty := Partial(typ)
+		if err = empty.Indexes[typ].(documentCodec).decodeDocument(ref, data[:len(data)-1]); !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("decodeDocument() of truncated payload for type %d error = %v, want %v", typ, err, ErrInvalidFormat)
+		}
+	}
+}
+
+type unsupportedIndex struct{}
+
+func (unsupportedIndex) Add(vocab.LinkOrIRI) uint64 { return 0 }
+
+func TestIndex_WriteDeltaTo_unsupported(t *testing.T) {
+	in := Partial(ByID)
+	in.Indexes[ByType] = unsupportedIndex{}
+
+	if _, err := in.WriteDeltaTo(&bytes.Buffer{}); !errors.Is(err, ErrDeltaUnsupported) {
+		t.Errorf("WriteDeltaTo() error = %v, want %v", err, ErrDeltaUnsupported)
+	}
+	if _, err := in.WriteTo(&bytes.Buffer{}); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("WriteTo() error = %v, want %v", err, ErrInvalidFormat)
+	}
+}
@@ -2,30 +2,85 @@ package index
 
 import (
 	"encoding"
+	"sync"
 
 	"github.com/RoaringBitmap/roaring/roaring64"
 	vocab "github.com/go-ap/activitypub"
 )
 
-type full roaring64.Bitmap
+// full is the index containing all the documents, which is used for the ByID index.
+type full struct {
+	w   sync.RWMutex
+	bmp *roaring64.Bitmap
+	// shared is set when the bitmap is shared with a snapshot of the index.
+	shared bool
+}
 
 func All() Indexable {
-	return (*full)(new(roaring64.Bitmap))
+	return &full{bmp: roaring64.New()}
 }
 
 func (f *full) Add(i vocab.LinkOrIRI) uint64 {
-	bmp := (*roaring64.Bitmap)(f)
 	r := HashFn(i)
-	bmp.Add(r)
+	f.w.Lock()
+	defer f.w.Unlock()
+	f.mutable().Add(r)
 	return r
 }
 
+// mutable returns the bitmap for modifying it, copying it first if it's shared with a snapshot.
+func (f *full) mutable() *roaring64.Bitmap {
+	if f.shared {
+		f.bmp = f.bmp.Clone()
+		f.shared = false
+	}
+	return f.bmp
+}
+
+// Remove removes the ref document from the index.
+func (f *full) Remove(ref uint64) {
+	f.w.Lock()
+	defer f.w.Unlock()
+	f.mutable().Remove(ref)
+}
+
+func (f *full) encodeDocument(ref uint64) ([]byte, error) {
+	f.w.RLock()
+	defer f.w.RUnlock()
+	if f.bmp.Contains(ref) {
+		return []byte{1}, nil
+	}
+	return []byte{0}, nil
+}
+
+func (f *full) decodeDocument(ref uint64, data []byte) error {
+	if len(data) == 1 && data[0] == 1 {
+		f.w.Lock()
+		defer f.w.Unlock()
+		f.mutable().Add(ref)
+	}
+	return nil
+}
+
+// indexed returns a copy of the bitmap of all the documents.
+func (f *full) indexed() *roaring64.Bitmap {
+	f.w.RLock()
+	defer f.w.RUnlock()
+	return f.bmp.Clone()
+}
+
 func (f *full) UnmarshalBinary(data []byte) error {
-	return (*roaring64.Bitmap)(f).UnmarshalBinary(data)
+	f.w.Lock()
+	defer f.w.Unlock()
+	f.bmp = roaring64.New()
+	f.shared = false
+	return f.bmp.UnmarshalBinary(data)
 }
 
 func (f *full) MarshalBinary() (data []byte, err error) {
-	return (*roaring64.Bitmap)(f).MarshalBinary()
+	f.w.RLock()
+	defer f.w.RUnlock()
+	return f.bmp.MarshalBinary()
 }
 
 var _ encoding.BinaryMarshaler = new(full)
@@ -1,10 +1,10 @@
 package index
 
 import (
-	"bytes"
-	"encoding/gob"
+	"slices"
 	"sync"
 
+	"github.com/RoaringBitmap/roaring/roaring64"
 	vocab "github.com/go-ap/activitypub"
 )
 
@@ -22,15 +22,60 @@ const (
 	ByInReplyTo
 	ByPublished
 	ByUpdated
+	// ByText is the full-text index of the name, summary and content of the objects,
+	// which is used by Index.Search.
+	ByText
+	// ByNameTrigram is the substring index of the name and preferred username of the objects.
+	ByNameTrigram
+	// ByContentTrigram is the substring index of the content of the objects.
+	ByContentTrigram
+	// ByHashtag is the index of the names of the Hashtag tags of the objects.
+	ByHashtag
+	// ByMention is the index of the IRIs of the Mention tags of the objects.
+	ByMention
+	// ByTag is the index of the IRIs of the items of the tag property of the objects.
+	ByTag
+	// ByContext is the index of the context of the objects, which is used for the threads.
+	ByContext
+	// ByURL is the index of the URLs of the objects, and of the hrefs of the links.
+	ByURL
+	// ByTarget is the index of the target of the activities.
+	ByTarget
+	// ByAudience is the index of the audience of the objects.
+	ByAudience
+	// ByIDTrigram is the substring index of the IDs of the objects.
+	ByIDTrigram
+	// ByAttributedToTrigram is the substring index of the attributedTo IRIs of the objects.
+	ByAttributedToTrigram
+	// ByInReplyToTrigram is the substring index of the inReplyTo IRIs of the objects.
+	ByInReplyToTrigram
 )
 
 // Index represents a full index
 // It contains the fast tokenized bitmaps, together with a cross-reference map that provides the corresponding
 // [vocab.IRI] list that results after resolving the bitmap searches.
 type Index struct {
-	w       sync.RWMutex
-	Ref     map[uint64]vocab.IRI
-	Indexes map[Type]Indexable
+	w   sync.RWMutex
+	Ref map[uint64]vocab.IRI
+	// Collisions contains the IRIs of the documents whose references collide with the one of
+	// a different document that is already present in Ref.
+	Collisions map[uint64]vocab.IRIs
+	Indexes    map[Type]Indexable
+	// changed contains the references of the documents added, updated or removed since the last write.
+	changed map[uint64]struct{}
+	// readOnly is set for the segments, whose indexes can't be modified.
+	readOnly bool
+	// table contains the segment references, which are looked up when they are not present in Ref.
+	table *refTable
+	// deleted contains the references of the documents removed in a segment.
+	// It's not modified after the segment is opened, so it's shared with the snapshots.
+	deleted *roaring64.Bitmap
+	// refsShared is set when Ref and Collisions are shared with a snapshot of the index, see mutableRefs.
+	refsShared bool
+	// generation is the number of changes made to the index, see Generation.
+	generation uint64
+	// tokenizer is the Tokenizer used for the "summary" and "content" properties, see Tokenizer.
+	tokenizer Tokenizer
 }
 
 var objectIndexTypes = []Type{
@@ -47,18 +92,37 @@ var activityIndexTypes = append(objectIndexTypes, ByActor, ByObject)
 var allIndexTypes = append(append(objectIndexTypes, actorIndexTypes...), activityIndexTypes...)
 
 // Full returns a full index data type.
-// The complete list of types can be found in the "ByXX" constants.
+// It contains the indexes of the "ByXX" constants from ByID to ByUpdated, together with the types added by Register.
+//
+// NOTE(marius): the full-text, trigram and the rest of the property indexes, from ByText to ByInReplyToTrigram,
+// increase considerably the memory and the size of the index, so they need to be requested explicitly
+// using Partial.
 func Full() *Index {
-	return Partial(allIndexTypes...)
+	return Partial(append(registeredTypes(), allIndexTypes...)...)
+}
+
+// FullWithTokenizer returns a full index data type, which uses the tok Tokenizer for the "summary"
+// and "content" properties.
+func FullWithTokenizer(tok Tokenizer) *Index {
+	return PartialWithTokenizer(tok, append(registeredTypes(), allIndexTypes...)...)
 }
 
 // Partial returns a partial index. It will create tokenized bitmaps only for the types it receives as parameters.
 // The types can be found in the "ByXX" constants.
 func Partial(types ...Type) *Index {
+	return PartialWithTokenizer(DefaultTokenizer, types...)
+}
+
+// PartialWithTokenizer returns a partial index, which uses the tok Tokenizer for the "summary"
+// and "content" properties. See Partial for details.
+func PartialWithTokenizer(tok Tokenizer, types ...Type) *Index {
 	i := Index{
-		w:       sync.RWMutex{},
-		Ref:     make(map[uint64]vocab.IRI),
-		Indexes: make(map[Type]Indexable),
+		w:          sync.RWMutex{},
+		Ref:        make(map[uint64]vocab.IRI),
+		Collisions: make(map[uint64]vocab.IRIs),
+		Indexes:    make(map[Type]Indexable),
+		changed:    make(map[uint64]struct{}),
+		tokenizer:  tok,
 	}
 	for _, typ := range types {
 		switch typ {
@@ -71,9 +135,9 @@ func Partial(types ...Type) *Index {
 		case ByPreferredUsername:
 			i.Indexes[typ] = NewTokenIndex(ExtractPreferredUsername)
 		case BySummary:
-			i.Indexes[typ] = NewTokenIndex(ExtractSummary)
+			i.Indexes[typ] = NewTokenIndex(ExtractSummaryWith(tok))
 		case ByContent:
-			i.Indexes[typ] = NewTokenIndex(ExtractContent)
+			i.Indexes[typ] = NewTokenIndex(ExtractContentWith(tok))
 		case ByActor:
 			i.Indexes[typ] = NewTokenIndex(ExtractActor)
 		case ByObject:
@@ -85,55 +149,206 @@ func Partial(types ...Type) *Index {
 		case ByInReplyTo:
 			i.Indexes[typ] = NewTokenIndex(ExtractInReplyTo)
 		case ByPublished:
-			i.Indexes[typ] = NewIndex(ExtractPublished, ExtractID)
+			i.Indexes[typ] = NewTimeIndex(ExtractPublishedTime)
 		case ByUpdated:
-			i.Indexes[typ] = NewIndex(ExtractUpdated, ExtractID)
+			i.Indexes[typ] = NewTimeIndex(ExtractUpdatedTime)
+		case ByText:
+			i.Indexes[typ] = NewTextIndex(ExtractText)
+		case ByNameTrigram:
+			i.Indexes[typ] = NewTrigramIndex(ExtractNameValues)
+		case ByContentTrigram:
+			i.Indexes[typ] = NewTrigramIndex(ExtractContentValues)
+		case ByHashtag:
+			i.Indexes[typ] = NewTokenIndex(ExtractHashtags)
+		case ByMention:
+			i.Indexes[typ] = NewTokenIndex(ExtractMentions)
+		case ByTag:
+			i.Indexes[typ] = NewTokenIndex(ExtractTag)
+		case ByContext:
+			i.Indexes[typ] = NewTokenIndex(ExtractContext)
+		case ByURL:
+			i.Indexes[typ] = NewTokenIndex(ExtractURL)
+		case ByTarget:
+			i.Indexes[typ] = NewTokenIndex(ExtractTarget)
+		case ByAudience:
+			i.Indexes[typ] = NewTokenIndex(ExtractAudience)
+		case ByIDTrigram:
+			i.Indexes[typ] = NewTrigramIndex(ExtractIDValues)
+		case ByAttributedToTrigram:
+			i.Indexes[typ] = NewTrigramIndex(ExtractAttributedToValues)
+		case ByInReplyToTrigram:
+			i.Indexes[typ] = NewTrigramIndex(ExtractInReplyToValues)
+		default:
+			if newFn, ok := registered(typ); ok {
+				i.Indexes[typ] = newFn()
+			}
 		}
 	}
 	return &i
 }
 
+// Tokenizer returns the Tokenizer used for the tokens of the BySummary and ByContent indexes, which needs to be
+// used for tokenizing the values searched in them too.
+// The indexes that were not created using FullWithTokenizer or PartialWithTokenizer, like the segments,
+// use the DefaultTokenizer.
+func (i *Index) Tokenizer() Tokenizer {
+	if i.tokenizer == nil {
+		return DefaultTokenizer
+	}
+	return i.tokenizer
+}
+
+// Collation returns the Collation of the tokens of the BySummary and ByContent indexes, which are folded by
+// their Tokenizer. The Tokenizers other than TextTokenizer are considered to use the DefaultCollation.
+//
+// NOTE(marius): the tokens of the ByName and ByPreferredUsername indexes, and the trigrams, are always folded
+// using the DefaultCollation.
+func (i *Index) Collation() Collation {
+	switch tok := i.Tokenizer().(type) {
+	case TextTokenizer:
+		return tok.Collation
+	case *TextTokenizer:
+		return tok.Collation
+	}
+	return DefaultCollation
+}
+
 // Add adds a [vocab.LinkOrIRI] object to the index.
+// The segments are read-only, so adding to them doesn't do anything.
 func (i *Index) Add(items ...vocab.LinkOrIRI) {
 	i.w.Lock()
 	defer i.w.Unlock()
 
+	if i.readOnly {
+		return
+	}
+
 	for _, li := range items {
-		ref := HashFn(li)
-		if ref == 0 {
-			continue
-		}
+		i.add(li)
+	}
+}
 
-		i.Ref[ref] = li.GetLink()
+func (i *Index) add(li vocab.LinkOrIRI) {
+	ref := HashFn(li)
+	if ref == 0 {
+		return
+	}
+
+	i.markChanged(ref)
+	i.mutableRefs()
 
-		for _, bmp := range i.Indexes {
-			_ = bmp.Add(li)
+	iri := li.GetLink()
+	if prev, ok := i.Ref[ref]; ok && prev != iri {
+		// NOTE(marius): the references of two different IRIs collide, so we keep the new IRI in the collision
+		// table, and the searches return both of them for the reference.
+		if i.Collisions == nil {
+			i.Collisions = make(map[uint64]vocab.IRIs)
+		}
+		if !i.Collisions[ref].Contains(iri) {
+			i.Collisions[ref] = append(i.Collisions[ref], iri)
 		}
+	} else {
+		i.Ref[ref] = iri
+	}
+
+	for _, bmp := range i.Indexes {
+		_ = bmp.Add(li)
 	}
 }
 
-type bareIndex struct {
-	Ref     map[uint64]vocab.IRI
-	Indexes map[Type]Indexable
+func (i *Index) markChanged(ref uint64) {
+	if i.changed == nil {
+		i.changed = make(map[uint64]struct{})
+	}
+	i.changed[ref] = struct{}{}
+	i.generation++
 }
 
-func (i *Index) MarshalBinary() ([]byte, error) {
-	buff := bytes.Buffer{}
-	b := bareIndex{Ref: i.Ref, Indexes: i.Indexes}
-	err := gob.NewEncoder(&buff).Encode(b)
-	return buff.Bytes(), err
+// View calls fn with the indexes while holding the read lock of the index, so they can be queried while
+// other goroutines add or remove documents. The bitmaps returned by the index queries are copies,
+// so they can be used, and modified, after fn returns.
+// The fn function must not call the other methods of the index.
+func (i *Index) View(fn func(indexes map[Type]Indexable)) {
+	i.w.RLock()
+	defer i.w.RUnlock()
+
+	fn(i.Indexes)
 }
 
-func (i *Index) UnmarshalBinary(data []byte) error {
-	b := bareIndex{
-		Ref:     make(map[uint64]vocab.IRI),
-		Indexes: make(map[Type]Indexable),
+// IRIs returns the IRIs of the documents corresponding to the ref reference.
+// There is more than one of them only if their references collide, in which case the bitmap searches
+// can't tell them apart, and the callers need to verify which of them is an actual match.
+func (i *Index) IRIs(ref uint64) vocab.IRIs {
+	i.w.RLock()
+	defer i.w.RUnlock()
+
+	return i.iris(ref)
+}
+
+// Remove removes the documents corresponding to the iris from all the indexes, and from the cross-reference map.
+// The indexes that don't implement the Removable interface keep the documents, so the searches using them
+// can still return the removed IRIs.
+func (i *Index) Remove(iris ...vocab.IRI) {
+	i.w.Lock()
+	defer i.w.Unlock()
+
+	if i.readOnly {
+		return
+	}
+
+	for _, iri := range iris {
+		i.remove(iri)
 	}
-	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&b)
-	if err != nil {
-		return err
+}
+
+func (i *Index) remove(iri vocab.IRI) {
+	ref := LookupRef(iri)
+	if ref == 0 {
+		return
+	}
+	i.markChanged(ref)
+	i.mutableRefs()
+
+	if others, ok := i.Collisions[ref]; ok {
+		// NOTE(marius): the reference is shared with other documents, so we can't remove it from the index
+		// bitmaps, and it can still be returned for the tokens of the removed document.
+		if i.Ref[ref] == iri {
+			i.Ref[ref], others = others[0], others[1:]
+		} else {
+			others = slices.DeleteFunc(others, func(o vocab.IRI) bool { return o == iri })
+		}
+		if len(others) == 0 {
+			delete(i.Collisions, ref)
+		} else {
+			i.Collisions[ref] = others
+		}
+		return
+	}
+	if prev, ok := i.Ref[ref]; ok && prev != iri {
+		// NOTE(marius): the reference belongs to a different document.
+		return
+	}
+	for _, in := range i.Indexes {
+		if r, ok := in.(Removable); ok {
+			r.Remove(ref)
+		}
+	}
+	delete(i.Ref, ref)
+}
+
+// Update replaces the old document in the index with the updated one, eg: for an edited object,
+// or for an object that got deleted and replaced by a Tombstone.
+// The tokens of the old document are removed, so it doesn't match them anymore.
+func (i *Index) Update(old, updated vocab.LinkOrIRI) {
+	i.w.Lock()
+	defer i.w.Unlock()
+
+	if i.readOnly {
+		return
+	}
+
+	if old != nil {
+		i.remove(old.GetLink())
 	}
-	i.Ref = b.Ref
-	i.Indexes = b.Indexes
-	return nil
+	i.add(updated)
 }
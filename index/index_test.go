@@ -1,11 +1,22 @@
 package index
 
 import (
+	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 
 	vocab "github.com/go-ap/activitypub"
+	"golang.org/x/text/language"
+)
+
+// extendedIndexTypes contains all the index types, including the ones that are not part of Full.
+var extendedIndexTypes = append(
+	slices.Clone(allIndexTypes),
+	ByText, ByNameTrigram, ByContentTrigram, ByHashtag, ByMention, ByTag, ByContext, ByURL, ByTarget, ByAudience,
+	ByIDTrigram, ByAttributedToTrigram, ByInReplyToTrigram,
 )
 
 func TestFull(t *testing.T) {
@@ -48,6 +59,65 @@ func TestFull(t *testing.T) {
 	}
 }
 
+func TestFull_optIn(t *testing.T) {
+	full := Full()
+	for _, typ := range extendedIndexTypes[len(allIndexTypes):] {
+		if _, ok := full.Indexes[typ]; ok {
+			t.Errorf("Full() contains the index type [%v], which needs to be requested using Partial", typ)
+		}
+		if _, ok := Partial(typ).Indexes[typ]; !ok {
+			t.Errorf("Partial(%v) is missing the index type", typ)
+		}
+	}
+}
+
+func TestIndex_Tokenizer(t *testing.T) {
+	caseOnly := TextTokenizer{Text: DefaultTextOptions, Collation: CaseFold}
+	splitComma := TokenizerFunc(func(text string, _ language.Tag) []string {
+		return strings.Split(text, ",")
+	})
+	tests := []struct {
+		name          string
+		in            *Index
+		wantTokenizer Tokenizer
+		wantCollation Collation
+	}{
+		{
+			name:          "default",
+			in:            Partial(ByContent),
+			wantTokenizer: DefaultTokenizer,
+			wantCollation: DefaultCollation,
+		},
+		{
+			name:          "text tokenizer",
+			in:            PartialWithTokenizer(caseOnly, ByContent),
+			wantTokenizer: caseOnly,
+			wantCollation: CaseFold,
+		},
+		{
+			name:          "custom tokenizer",
+			in:            PartialWithTokenizer(splitComma, ByContent),
+			wantCollation: DefaultCollation,
+		},
+		{
+			name:          "decoded index",
+			in:            &Index{},
+			wantTokenizer: DefaultTokenizer,
+			wantCollation: DefaultCollation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantTokenizer != nil && !reflect.DeepEqual(tt.in.Tokenizer(), tt.wantTokenizer) {
+				t.Errorf("Tokenizer() = %v, want %v", tt.in.Tokenizer(), tt.wantTokenizer)
+			}
+			if got := tt.in.Collation(); got != tt.wantCollation {
+				t.Errorf("Collation() = %v, want %v", got, tt.wantCollation)
+			}
+		})
+	}
+}
+
 func TestIndex_Add(t *testing.T) {
 	type fields struct {
 		Ref     map[uint64]vocab.IRI
@@ -76,73 +146,192 @@ func TestIndex_Add(t *testing.T) {
 	}
 }
 
-var emptyFullIndex = []byte{
-	0x2d, 0xff, 0x87, 0x3, 0x1, 0x1, 0x9, 0x62, 0x61, 0x72, 0x65, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x1, 0xff, 0x88, 0x0,
-	0x1, 0x2, 0x1, 0x3, 0x52, 0x65, 0x66, 0x1, 0xff, 0x8c, 0x0, 0x1, 0x7, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x65, 0x73,
-	0x1, 0xff, 0x8e, 0x0, 0x0, 0x0, 0x2b, 0xff, 0x8b, 0x4, 0x1, 0x1, 0x1a, 0x6d, 0x61, 0x70, 0x5b, 0x75, 0x69, 0x6e,
-	0x74, 0x36, 0x34, 0x5d, 0x61, 0x63, 0x74, 0x69, 0x76, 0x69, 0x74, 0x79, 0x70, 0x75, 0x62, 0x2e, 0x49, 0x52, 0x49,
-	0x1, 0xff, 0x8c, 0x0, 0x1, 0x6, 0x1, 0xff, 0x8a, 0x0, 0x0, 0xa, 0xff, 0x89, 0x5, 0x1, 0x2, 0xff, 0x8a, 0x0, 0x0,
-	0x0, 0x2e, 0xff, 0x8d, 0x4, 0x1, 0x1, 0x1e, 0x6d, 0x61, 0x70, 0x5b, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x2e, 0x54, 0x79,
-	0x70, 0x65, 0x5d, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x2e, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x61, 0x62, 0x6c, 0x65, 0x1,
-	0xff, 0x8e, 0x0, 0x1, 0x4, 0x1, 0x10, 0x0, 0x0, 0x3, 0xff, 0x88, 0x0,
+var removableObjects = []vocab.LinkOrIRI{
+	&vocab.Object{
+		ID:           "https://example.com/1",
+		Type:         vocab.NoteType,
+		AttributedTo: vocab.IRI("https://example.com/~jdoe"),
+		Content:      vocab.DefaultNaturalLanguage("The quick brown fox"),
+	},
+	&vocab.Object{
+		ID:           "https://example.com/2",
+		Type:         vocab.NoteType,
+		AttributedTo: vocab.IRI("https://example.com/~alice"),
+		Content:      vocab.DefaultNaturalLanguage("The lazy dog"),
+	},
 }
 
-func TestIndex_MarshalBinary(t *testing.T) {
-	type fields struct {
-		Ref     map[uint64]vocab.IRI
-		Indexes map[Type]Indexable
+func TestIndex_Remove(t *testing.T) {
+	i := Partial(extendedIndexTypes...)
+	i.Add(removableObjects...)
+	i.Remove("https://example.com/1", "https://example.com/666")
+
+	ref := HashFn(removableObjects[0])
+	if _, ok := i.Ref[ref]; ok {
+		t.Errorf("Remove() kept the reference of the removed document")
 	}
-	tests := []struct {
-		name    string
-		fields  fields
-		want    []byte
-		wantErr bool
-	}{
-		{
-			name:    "empty",
-			fields:  fields{},
-			want:    emptyFullIndex,
-			wantErr: false,
-		},
+	for typ, in := range i.Indexes {
+		if all := Indexed(in); all != nil && all.Contains(ref) {
+			t.Errorf("Remove() kept the removed document in the %v index", typ)
+		}
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			i := &Index{
-				Ref:     tt.fields.Ref,
-				Indexes: tt.fields.Indexes,
-			}
-			got, err := i.MarshalBinary()
-			if (err != nil) != tt.wantErr {
-				t.Errorf("MarshalBinary() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("MarshalBinary() got = %#v, want %#v", got, tt.want)
-			}
-		})
+	if got := i.Search("fox"); len(got) > 0 {
+		t.Errorf("Search() after Remove() = %v, want empty", got)
+	}
+	if got := Indexed(i.Indexes[ByID]); got.GetCardinality() != 1 || !got.Contains(HashFn(removableObjects[1])) {
+		t.Errorf("Remove() removed other documents %v", got.ToArray())
 	}
 }
 
-func TestIndex_UnmarshalBinary(t *testing.T) {
-	tests := []struct {
-		name    string
-		arg     []byte
-		want    Index
-		wantErr bool
-	}{
-		{
-			name:    "empty",
-			arg:     emptyFullIndex,
-			want:    Index{Ref: make(map[uint64]vocab.IRI), Indexes: make(map[Type]Indexable)},
-			wantErr: false,
-		},
+func TestIndex_Update(t *testing.T) {
+	i := Partial(extendedIndexTypes...)
+	i.Add(removableObjects...)
+
+	tombstone := &vocab.Object{
+		ID:   "https://example.com/1",
+		Type: vocab.TombstoneType,
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			i := Index{}
-			if err := i.UnmarshalBinary(tt.arg); (err != nil) != tt.wantErr {
-				t.Errorf("UnmarshalBinary() error = %v, wantErr %v", err, tt.wantErr)
+	i.Update(removableObjects[0], tombstone)
+
+	ref := HashFn(tombstone)
+	if iri := i.Ref[ref]; iri != tombstone.ID {
+		t.Errorf("Update() reference = %s, want %s", iri, tombstone.ID)
+	}
+	if got := GetBitmaps[string](i.Indexes[ByType], string(vocab.NoteType))[0]; got.Contains(ref) {
+		t.Errorf("Update() kept the old type of the document")
+	}
+	if got := GetBitmaps[string](i.Indexes[ByType], string(vocab.TombstoneType))[0]; !got.Contains(ref) {
+		t.Errorf("Update() didn't index the new type of the document")
+	}
+	if got := GetBitmaps[uint64](i.Indexes[ByAttributedTo], HashFn(vocab.IRI("https://example.com/~jdoe")))[0]; got.Contains(ref) {
+		t.Errorf("Update() kept the old attributedTo of the document")
+	}
+	if got := i.Search("fox"); len(got) > 0 {
+		t.Errorf("Search() after Update() = %v, want empty", got)
+	}
+}
+
+// collidingHash returns the same reference for all the IRIs with the same length.
+func collidingHash(li vocab.LinkOrIRI) uint64 {
+	if li == nil {
+		return 0
+	}
+	return uint64(len(li.GetLink()))
+}
+
+func TestIndex_Add_collisions(t *testing.T) {
+	defer func(fn HashFnType) { HashFn = fn }(HashFn)
+	HashFn = collidingHash
+
+	i := Partial(extendedIndexTypes...)
+	i.Add(removableObjects...)
+	i.Add(removableObjects[1])
+
+	ref := HashFn(removableObjects[0])
+	want := vocab.IRIs{"https://example.com/1", "https://example.com/2"}
+	if got := i.IRIs(ref); !reflect.DeepEqual(got, want) {
+		t.Errorf("IRIs() = %v, want %v", got, want)
+	}
+	if got := i.Search("fox"); len(got) != 2 {
+		t.Errorf("Search() = %v, want both colliding IRIs", got)
+	}
+
+	i.Remove("https://example.com/1")
+	want = vocab.IRIs{"https://example.com/2"}
+	if got := i.IRIs(ref); !reflect.DeepEqual(got, want) {
+		t.Errorf("IRIs() after Remove() = %v, want %v", got, want)
+	}
+	if _, ok := i.Collisions[ref]; ok {
+		t.Errorf("Remove() kept the collisions for %d", ref)
+	}
+	if all := Indexed(i.Indexes[ByID]); !all.Contains(ref) {
+		t.Errorf("Remove() removed the reference shared with another document")
+	}
+}
+
+func TestIndex_Add_sequentialRefs(t *testing.T) {
+	defer func(fn HashFnType) { HashFn = fn }(HashFn)
+	HashFn = NewSequentialRefs().Hash
+
+	i := Partial(extendedIndexTypes...)
+	i.Add(removableObjects...)
+
+	if len(i.Collisions) > 0 {
+		t.Errorf("Add() collisions = %v, want none", i.Collisions)
+	}
+	for _, ob := range removableObjects {
+		want := vocab.IRIs{ob.GetLink()}
+		if got := i.IRIs(HashFn(ob)); !reflect.DeepEqual(got, want) {
+			t.Errorf("IRIs() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestIndex_Remove_sequentialRefs(t *testing.T) {
+	defer func(fn HashFnType) { HashFn = fn }(HashFn)
+	defer func(fn HashFnType) { LookupFn = fn }(LookupFn)
+	refs := NewSequentialRefs()
+	HashFn, LookupFn = refs.Hash, refs.Lookup
+
+	i := Partial(extendedIndexTypes...)
+	i.Add(removableObjects...)
+
+	i.Remove("https://example.com/missing")
+	if ref := refs.Lookup(vocab.IRI("https://example.com/missing")); ref != 0 {
+		t.Errorf("Remove() allocated the reference %d for a missing IRI", ref)
+	}
+	removed := removableObjects[0].GetLink()
+	ref := refs.Lookup(removed)
+	i.Remove(removed)
+	if got := i.IRIs(ref); len(got) > 0 {
+		t.Errorf("IRIs() after Remove() = %v, want none", got)
+	}
+}
+
+func TestIndex_concurrent(t *testing.T) {
+	i := Partial(extendedIndexTypes...)
+	objects := make([]vocab.LinkOrIRI, 0, 200)
+	for j := range 200 {
+		objects = append(objects, textObject(vocab.IRI(fmt.Sprintf("https://example.com/%d", j)), "The quick brown fox"))
+	}
+
+	wg := sync.WaitGroup{}
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := w; j < len(objects); j += 4 {
+				i.Add(objects[j])
+				if j%10 == 0 {
+					i.Remove(objects[j].GetLink())
+					i.Update(nil, objects[j])
+				}
 			}
-		})
+		}()
+	}
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				_ = i.Search("fox")
+				_ = i.IRIs(HashFn(objects[j]))
+				i.View(func(indexes map[Type]Indexable) {
+					for _, b := range GetBitmaps(indexes[ByType], string(vocab.NoteType)) {
+						b.AndNot(Indexed(indexes[ByID]))
+					}
+					_ = GetBitmaps[uint64](indexes[ByID], HashFn(objects[j]))
+					_ = SubstringSearch(indexes[ByContentTrigram], "quick", nil)
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(i.Search("fox")); got != len(objects) {
+		t.Errorf("Search() after concurrent Add() = %d results, want %d", got, len(objects))
+	}
+	if got := GetBitmaps(i.Indexes[ByType], string(vocab.NoteType))[0].GetCardinality(); got != uint64(len(objects)) {
+		t.Errorf("ByType after concurrent Add() = %d documents, want %d", got, len(objects))
 	}
 }
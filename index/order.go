@@ -0,0 +1,51 @@
+package index
+
+import (
+	"cmp"
+	"slices"
+)
+
+// timed is implemented by the indexes containing the times of the documents, see ByPublished and ByUpdated.
+type timed interface {
+	time(ref uint64) (uint64, bool)
+}
+
+// timestamp returns the time of the document with the ref reference from the in time index,
+// and if the document has one.
+func timestamp(in Indexable, ref uint64) (uint64, bool) {
+	if t, ok := in.(timed); ok {
+		return t.time(ref)
+	}
+	return 0, false
+}
+
+// SortByTime sorts the refs references by the time their documents were last updated, or published,
+// the most recent first, the same as [vocab.ItemOrderTimestamp] orders the items.
+// The times come from the ByUpdated and ByPublished indexes, and the documents with the same time, or without one,
+// keep their order, with the ones without a time at the end.
+// When the index can be modified concurrently, the indexes must be accessed through [Index.View].
+func SortByTime(indexes map[Type]Indexable, refs []uint64) {
+	times := make(map[uint64]uint64, len(refs))
+	for _, ref := range refs {
+		t, ok := timestamp(indexes[ByUpdated], ref)
+		if !ok {
+			t, ok = timestamp(indexes[ByPublished], ref)
+		}
+		if ok {
+			times[ref] = t
+		}
+	}
+	slices.SortStableFunc(refs, func(a, b uint64) int {
+		ta, okA := times[a]
+		tb, okB := times[b]
+		switch {
+		case !okA && !okB:
+			return 0
+		case !okA:
+			return 1
+		case !okB:
+			return -1
+		}
+		return cmp.Compare(tb, ta)
+	})
+}
@@ -0,0 +1,97 @@
+package index
+
+import (
+	"testing"
+	"time"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func timedObject(id vocab.IRI, published, updated time.Time) *vocab.Object {
+	return &vocab.Object{ID: id, Type: vocab.NoteType, Published: published, Updated: updated}
+}
+
+func TestSortByTime(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	objects := []vocab.LinkOrIRI{
+		timedObject("https://example.com/old", now.Add(-48*time.Hour), time.Time{}),
+		timedObject("https://example.com/untimed", time.Time{}, time.Time{}),
+		timedObject("https://example.com/updated", now.Add(-72*time.Hour), now),
+		timedObject("https://example.com/new", now.Add(-time.Hour), time.Time{}),
+		timedObject("https://example.com/minute", now.Add(-time.Hour-time.Minute), time.Time{}),
+		timedObject("https://example.com/1969", time.Date(1969, 7, 20, 20, 17, 0, 0, time.UTC), time.Time{}),
+		vocab.IRI("https://example.com/iri"),
+	}
+	refs := func(iris ...vocab.IRI) []uint64 {
+		r := make([]uint64, 0, len(iris))
+		for _, iri := range iris {
+			r = append(r, HashFn(iri))
+		}
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		indexes []Type
+		arg     []uint64
+		want    []uint64
+	}{
+		{
+			name:    "published and updated",
+			indexes: []Type{ByID, ByPublished, ByUpdated},
+			arg:     refs("https://example.com/iri", "https://example.com/old", "https://example.com/untimed", "https://example.com/updated", "https://example.com/new"),
+			want:    refs("https://example.com/updated", "https://example.com/new", "https://example.com/old", "https://example.com/iri", "https://example.com/untimed"),
+		},
+		{
+			name:    "published",
+			indexes: []Type{ByID, ByPublished},
+			arg:     refs("https://example.com/old", "https://example.com/updated", "https://example.com/new"),
+			want:    refs("https://example.com/new", "https://example.com/old", "https://example.com/updated"),
+		},
+		{
+			name:    "same hour",
+			indexes: []Type{ByID, ByPublished, ByUpdated},
+			arg:     refs("https://example.com/minute", "https://example.com/new"),
+			want:    refs("https://example.com/new", "https://example.com/minute"),
+		},
+		{
+			name:    "before 1970",
+			indexes: []Type{ByID, ByPublished},
+			arg:     refs("https://example.com/1969", "https://example.com/old", "https://example.com/untimed"),
+			want:    refs("https://example.com/old", "https://example.com/1969", "https://example.com/untimed"),
+		},
+		{
+			name:    "without time indexes",
+			indexes: []Type{ByID},
+			arg:     refs("https://example.com/old", "https://example.com/updated", "https://example.com/new"),
+			want:    refs("https://example.com/old", "https://example.com/updated", "https://example.com/new"),
+		},
+		{
+			name:    "empty",
+			indexes: []Type{ByID, ByPublished, ByUpdated},
+			arg:     []uint64{},
+			want:    []uint64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := Partial(tt.indexes...)
+			in.Add(objects...)
+			SortByTime(in.Indexes, tt.arg)
+			if !cmp.Equal(tt.arg, tt.want) {
+				t.Errorf("SortByTime() = %s", cmp.Diff(tt.want, tt.arg))
+			}
+		})
+	}
+	t.Run("segment", func(t *testing.T) {
+		in := Full()
+		in.Add(objects...)
+		s := openSegment(t, writeSegment(t, in))
+		got := refs("https://example.com/old", "https://example.com/updated", "https://example.com/new")
+		SortByTime(s.Indexes, got)
+		if want := refs("https://example.com/updated", "https://example.com/new", "https://example.com/old"); !cmp.Equal(got, want) {
+			t.Errorf("SortByTime() on segment = %s", cmp.Diff(want, got))
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package index
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sync"
+
+	vocab "github.com/go-ap/activitypub"
+)
+
+// SequentialRefs is an alternative reference strategy to hashing the IRIs, which allocates sequential
+// references to the IRIs, in the order they are first seen, so they can't collide.
+//
+// It can be used by replacing the HashFn, and the LookupFn, so the searches and the removals don't allocate
+// references for the IRIs that were never indexed:
+//
+//	refs := index.NewSequentialRefs()
+//	index.HashFn = refs.Hash
+//	index.LookupFn = refs.Lookup
+//
+// Unlike the hashes, the references depend on the order of the IRIs, so the SequentialRefs needs to be
+// persisted together with the indexes that use it.
+type SequentialRefs struct {
+	w    sync.Mutex
+	refs map[vocab.IRI]uint64
+	last uint64
+}
+
+// NewSequentialRefs initializes an empty sequential references table.
+func NewSequentialRefs() *SequentialRefs {
+	return &SequentialRefs{refs: make(map[vocab.IRI]uint64)}
+}
+
+// Hash returns the reference of the IRI of li, allocating the next one if it's the first time the IRI is seen.
+// It has the HashFnType signature, and it returns 0 for nil values.
+func (s *SequentialRefs) Hash(li vocab.LinkOrIRI) uint64 {
+	if li == nil {
+		return 0
+	}
+	iri := li.GetLink()
+
+	s.w.Lock()
+	defer s.w.Unlock()
+
+	if s.refs == nil {
+		s.refs = make(map[vocab.IRI]uint64)
+	}
+	if ref, ok := s.refs[iri]; ok {
+		return ref
+	}
+	s.last++
+	s.refs[iri] = s.last
+	return s.last
+}
+
+// Lookup returns the reference of the IRI of li, or 0 if the IRI was never seen.
+// Unlike Hash, it doesn't allocate a reference for the new IRIs, and it can be used as the LookupFn.
+func (s *SequentialRefs) Lookup(li vocab.LinkOrIRI) uint64 {
+	if li == nil {
+		return 0
+	}
+
+	s.w.Lock()
+	defer s.w.Unlock()
+
+	return s.refs[li.GetLink()]
+}
+
+func (s *SequentialRefs) MarshalBinary() ([]byte, error) {
+	s.w.Lock()
+	defer s.w.Unlock()
+
+	buff := bytes.Buffer{}
+	err := gob.NewEncoder(&buff).Encode(s.refs)
+	return buff.Bytes(), err
+}
+
+func (s *SequentialRefs) UnmarshalBinary(data []byte) error {
+	refs := make(map[vocab.IRI]uint64)
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&refs); err != nil {
+		return err
+	}
+
+	s.w.Lock()
+	defer s.w.Unlock()
+
+	s.refs = refs
+	s.last = 0
+	for _, ref := range refs {
+		s.last = max(s.last, ref)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package index
+
+import (
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+)
+
+func TestSequentialRefs_Hash(t *testing.T) {
+	refs := NewSequentialRefs()
+	tests := []struct {
+		name string
+		arg  vocab.LinkOrIRI
+		want uint64
+	}{
+		{
+			name: "nil",
+			arg:  nil,
+			want: 0,
+		},
+		{
+			name: "first",
+			arg:  vocab.IRI("https://example.com/1"),
+			want: 1,
+		},
+		{
+			name: "second",
+			arg:  &vocab.Object{ID: "https://example.com/2"},
+			want: 2,
+		},
+		{
+			name: "first again",
+			arg:  &vocab.Object{ID: "https://example.com/1"},
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := refs.Hash(tt.arg); got != tt.want {
+				t.Errorf("Hash() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSequentialRefs_Lookup(t *testing.T) {
+	refs := NewSequentialRefs()
+	refs.Hash(vocab.IRI("https://example.com/1"))
+
+	tests := []struct {
+		name string
+		arg  vocab.LinkOrIRI
+		want uint64
+	}{
+		{
+			name: "nil",
+			arg:  nil,
+			want: 0,
+		},
+		{
+			name: "present",
+			arg:  &vocab.Object{ID: "https://example.com/1"},
+			want: 1,
+		},
+		{
+			name: "missing",
+			arg:  vocab.IRI("https://example.com/2"),
+			want: 0,
+		},
+		{
+			name: "missing again",
+			arg:  vocab.IRI("https://example.com/2"),
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := refs.Lookup(tt.arg); got != tt.want {
+				t.Errorf("Lookup() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+	if got := refs.Hash(vocab.IRI("https://example.com/3")); got != 2 {
+		t.Errorf("Hash() after Lookup() = %d, want 2", got)
+	}
+}
+
+func TestSequentialRefs_MarshalBinary(t *testing.T) {
+	refs := NewSequentialRefs()
+	refs.Hash(vocab.IRI("https://example.com/1"))
+	refs.Hash(vocab.IRI("https://example.com/2"))
+
+	data, err := refs.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	got := SequentialRefs{}
+	if err = got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if ref := got.Hash(vocab.IRI("https://example.com/2")); ref != 2 {
+		t.Errorf("Hash() after UnmarshalBinary() = %d, want 2", ref)
+	}
+	if ref := got.Hash(vocab.IRI("https://example.com/3")); ref != 3 {
+		t.Errorf("Hash() after UnmarshalBinary() = %d, want 3", ref)
+	}
+}
@@ -0,0 +1,74 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// MinCustomType is the first Type that can be used by the applications for registering their own index types.
+// The types lower than it are reserved for the ones of this package.
+// NOTE(marius): the Type is stored in a single byte by the binary format of the indexes, so there can be
+// at most 64 custom types, from MinCustomType to 127.
+const MinCustomType Type = 64
+
+var (
+	// ErrTypeRegistered is returned when registering an index Type that is already in use.
+	ErrTypeRegistered = errors.New("index type already registered")
+	// ErrReservedType is returned when registering an index Type lower than MinCustomType.
+	ErrReservedType = errors.New("index type reserved")
+)
+
+var registry = struct {
+	sync.RWMutex
+	types map[Type]func() Indexable
+}{types: make(map[Type]func() Indexable)}
+
+// Register adds the typ index type, whose indexes get created by newFn, eg:
+//
+//	Register(ByMediaType, func() Indexable { return NewTokenIndex(ExtractMediaType) })
+//
+// The registered types are part of the indexes returned by Full, and can be used with Partial, and for loading
+// the indexes written by WriteTo.
+// The typ must not be lower than MinCustomType, or ErrReservedType is returned, and it can be registered only once,
+// or ErrTypeRegistered is returned. As Type is an int8, this limits the applications to 64 custom index types.
+func Register(typ Type, newFn func() Indexable) error {
+	if typ < MinCustomType {
+		return fmt.Errorf("%w: %d is lower than %d", ErrReservedType, typ, MinCustomType)
+	}
+	if newFn == nil {
+		return fmt.Errorf("invalid index constructor for type %d", typ)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.types[typ]; ok {
+		return fmt.Errorf("%w: %d", ErrTypeRegistered, typ)
+	}
+	registry.types[typ] = newFn
+	return nil
+}
+
+// registered returns the constructor of the typ index type registered by the application.
+func registered(typ Type) (func() Indexable, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	newFn, ok := registry.types[typ]
+	return newFn, ok
+}
+
+// registeredTypes returns the index types registered by the application.
+func registeredTypes() []Type {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	types := make([]Type, 0, len(registry.types))
+	for typ := range registry.types {
+		types = append(types, typ)
+	}
+	slices.Sort(types)
+	return types
+}
@@ -0,0 +1,114 @@
+package index
+
+import (
+	"errors"
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+)
+
+const byMediaType = MinCustomType + 1
+
+func extractMediaType(li vocab.LinkOrIRI) []string {
+	ob, ok := li.(*vocab.Object)
+	if !ok || ob.MediaType == "" {
+		return nil
+	}
+	return []string{string(ob.MediaType)}
+}
+
+// registerMediaType registers the byMediaType index type for the duration of the test.
+func registerMediaType(t *testing.T) {
+	t.Helper()
+	if err := Register(byMediaType, func() Indexable { return NewTokenIndex(extractMediaType) }); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	t.Cleanup(func() {
+		registry.Lock()
+		defer registry.Unlock()
+		delete(registry.types, byMediaType)
+	})
+}
+
+func TestRegister(t *testing.T) {
+	registerMediaType(t)
+
+	tests := []struct {
+		name    string
+		typ     Type
+		newFn   func() Indexable
+		wantErr error
+	}{
+		{
+			name:    "builtin",
+			typ:     ByType,
+			newFn:   func() Indexable { return NewTokenIndex(extractMediaType) },
+			wantErr: ErrReservedType,
+		},
+		{
+			name:    "duplicate",
+			typ:     byMediaType,
+			newFn:   func() Indexable { return NewTokenIndex(extractMediaType) },
+			wantErr: ErrTypeRegistered,
+		},
+		{
+			name:  "nil constructor",
+			typ:   byMediaType + 1,
+			newFn: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Register(tt.typ, tt.newFn)
+			if err == nil {
+				t.Errorf("Register() error = nil, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Register() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, ok := registered(byMediaType + 1); ok {
+		t.Errorf("Register() with a nil constructor registered the type")
+	}
+}
+
+func TestRegister_indexes(t *testing.T) {
+	registerMediaType(t)
+
+	markdown := &vocab.Object{ID: "https://example.com/md", Type: vocab.NoteType, MediaType: "text/markdown"}
+	html := &vocab.Object{ID: "https://example.com/html", Type: vocab.NoteType, MediaType: "text/html"}
+
+	full := Full()
+	if _, ok := full.Indexes[byMediaType]; !ok {
+		t.Fatalf("Full() is missing the registered index type")
+	}
+	if _, ok := Partial(ByType, byMediaType).Indexes[byMediaType]; !ok {
+		t.Errorf("Partial() is missing the registered index type")
+	}
+	full.Add(markdown, html)
+
+	got := GetBitmaps(full.Indexes[byMediaType], "text/markdown")
+	if len(got) != 1 || !got[0].Contains(HashFn(markdown)) || got[0].GetCardinality() != 1 {
+		t.Errorf("GetBitmaps(text/markdown) = %v, want [%d]", got, HashFn(markdown))
+	}
+
+	t.Run("format", func(t *testing.T) {
+		data, err := full.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() error = %v", err)
+		}
+		loaded := Partial()
+		if err = loaded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary() error = %v", err)
+		}
+		sameIndexes(t, loaded, full)
+	})
+	t.Run("segment", func(t *testing.T) {
+		s := openSegment(t, writeSegment(t, full))
+		got := GetBitmaps(s.Indexes[byMediaType], "text/html")
+		if len(got) != 1 || !got[0].Contains(HashFn(html)) || got[0].GetCardinality() != 1 {
+			t.Errorf("GetBitmaps(text/html) = %v, want [%d]", got, HashFn(html))
+		}
+	})
+}
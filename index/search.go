@@ -0,0 +1,522 @@
+package index
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	vocab "github.com/go-ap/activitypub"
+	"golang.org/x/text/language"
+)
+
+// Scored represents a search result together with its relevance score.
+// Higher scores represent more relevant results.
+type Scored[T any] struct {
+	Value T
+	Score float64
+}
+
+const (
+	// bm25K1 controls how fast the score saturates with the frequency of a term in a document.
+	bm25K1 = 1.2
+	// bm25B controls how much the length of a document penalizes its score.
+	bm25B = 0.75
+	// fieldPositionGap separates the positions of the terms of different values of a document,
+	// so the phrase queries don't match across them.
+	fieldPositionGap = 100
+)
+
+// ExtractTextFnType is the type of the functions that return the natural language values that
+// get indexed by the full-text index.
+type ExtractTextFnType func(vocab.LinkOrIRI) []vocab.NaturalLanguageValues
+
+// ExtractText returns the "name", "summary" and "content" properties of the [vocab.LinkOrIRI].
+func ExtractText(li vocab.LinkOrIRI) []vocab.NaturalLanguageValues {
+	result := make([]vocab.NaturalLanguageValues, 0, 3)
+	switch it := li.(type) {
+	case vocab.Link:
+		result = append(result, it.Name)
+	case *vocab.Link:
+		result = append(result, it.Name)
+	case vocab.Item:
+		_ = vocab.OnObject(it, func(ob *vocab.Object) error {
+			result = append(result, ob.Name, ob.Summary, ob.Content)
+			return nil
+		})
+	}
+	return result
+}
+
+// textIndex is a full-text index, which keeps for every term the positions where it appears in
+// each of the documents, besides the bitmap of the documents containing it.
+// The positions are used for the term frequencies of the BM25 ranking, and for the phrase queries.
+type textIndex struct {
+	w sync.RWMutex
+	// postings contains the bitmaps of the documents for each term.
+	postings map[string]*roaring64.Bitmap
+	// positions contains the positions of each term, for every document containing it.
+	positions map[string]map[uint64][]uint32
+	// lengths contains the number of terms of every document.
+	lengths map[uint64]uint32
+	// terms is the forward map containing the distinct terms of every document, which is used for removing them.
+	terms map[uint64][]string
+	// languages contains the bitmaps of the documents for each language their text has been analyzed as,
+	// so the queries are analyzed the same way for each of them.
+	languages map[string]*roaring64.Bitmap
+	// shared contains the terms whose postings and positions are shared with the snapshots of the index.
+	shared      cow[string]
+	totalLength uint64
+	extractFn   ExtractTextFnType
+}
+
+// NewTextIndex initializes a full-text index, for the natural language values returned by extractFn.
+// The HTML of the values is converted to text using the DefaultTextOptions.
+func NewTextIndex(extractFn ExtractTextFnType) Indexable {
+	return &textIndex{
+		postings:  make(map[string]*roaring64.Bitmap),
+		positions: make(map[string]map[uint64][]uint32),
+		lengths:   make(map[uint64]uint32),
+		terms:     make(map[uint64][]string),
+		languages: make(map[string]*roaring64.Bitmap),
+		extractFn: extractFn,
+	}
+}
+
+func (t *textIndex) Add(li vocab.LinkOrIRI) uint64 {
+	ref := HashFn(li)
+	if ref == 0 || t.extractFn == nil {
+		return ref
+	}
+	values := t.extractFn(li)
+
+	t.w.Lock()
+	defer t.w.Unlock()
+
+	if _, ok := t.lengths[ref]; ok {
+		// NOTE(marius): the document has already been indexed.
+		return ref
+	}
+
+	offset := uint32(0)
+	for _, nlv := range values {
+		if contentHasDataURI(nlv) {
+			continue
+		}
+		for lang, v := range nlv {
+			terms := analyzeText(PlainText(v.String(), DefaultTextOptions), language.Tag(lang))
+			if len(terms) > 0 {
+				t.addLanguage(analysisLanguage(language.Tag(lang)), ref)
+			}
+			for _, tt := range terms {
+				t.addTerm(tt.value, ref, offset+tt.pos)
+			}
+			t.lengths[ref] += uint32(len(terms))
+			t.totalLength += uint64(len(terms))
+			if len(terms) > 0 {
+				offset += terms[len(terms)-1].pos + fieldPositionGap
+			}
+		}
+	}
+	if _, ok := t.lengths[ref]; !ok {
+		t.lengths[ref] = 0
+	}
+	return ref
+}
+
+func (t *textIndex) addTerm(value string, ref uint64, pos uint32) {
+	postings, positions := t.mutable(value)
+	if _, ok := positions[ref]; !ok {
+		t.terms[ref] = append(t.terms[ref], value)
+	}
+	postings.Add(ref)
+	positions[ref] = append(positions[ref], pos)
+}
+
+// addLanguage adds the ref document to the bitmap of the lang language.
+// NOTE(marius): the language bitmaps are cloned when taking a snapshot, so they can be modified in place.
+func (t *textIndex) addLanguage(lang string, ref uint64) {
+	docs, ok := t.languages[lang]
+	if !ok {
+		docs = roaring64.New()
+		t.languages[lang] = docs
+	}
+	docs.Add(ref)
+}
+
+// mutable returns the postings and positions of the value term for modifying them, creating them if they
+// don't exist, or copying them if they are shared with a snapshot.
+func (t *textIndex) mutable(value string) (*roaring64.Bitmap, map[uint64][]uint32) {
+	postings, ok := t.postings[value]
+	switch {
+	case !ok:
+		postings = roaring64.New()
+		t.postings[value] = postings
+		t.positions[value] = make(map[uint64][]uint32)
+		t.shared.own(value)
+	case t.shared.own(value):
+		postings = postings.Clone()
+		t.postings[value] = postings
+		t.positions[value] = maps.Clone(t.positions[value])
+	}
+	return postings, t.positions[value]
+}
+
+// Remove removes the ref document from the postings and positions of all its terms.
+func (t *textIndex) Remove(ref uint64) {
+	t.w.Lock()
+	defer t.w.Unlock()
+
+	for _, value := range t.terms[ref] {
+		if _, ok := t.postings[value]; !ok {
+			continue
+		}
+		postings, positions := t.mutable(value)
+		postings.Remove(ref)
+		delete(positions, ref)
+		if postings.IsEmpty() {
+			delete(t.postings, value)
+			delete(t.positions, value)
+		}
+	}
+	for lang, docs := range t.languages {
+		docs.Remove(ref)
+		if docs.IsEmpty() {
+			delete(t.languages, lang)
+		}
+	}
+	t.totalLength -= uint64(t.lengths[ref])
+	delete(t.lengths, ref)
+	delete(t.terms, ref)
+}
+
+// get returns the bitmap of the documents containing all the terms of the key.
+// The key is analyzed as each of the languages of the indexed documents, and the resulting terms are
+// matched only with the documents of the corresponding language.
+func (t *textIndex) get(key string) *roaring64.Bitmap {
+	t.w.RLock()
+	defer t.w.RUnlock()
+
+	result := roaring64.New()
+	for lang, docs := range t.languages {
+		terms := analyzeText(key, language.Make(lang))
+		if len(terms) == 0 {
+			continue
+		}
+		result.Or(t.matchTerms(terms, docs))
+	}
+	return result
+}
+
+// matchTerms returns the documents of the docs bitmap which contain all the terms.
+func (t *textIndex) matchTerms(terms []term, docs *roaring64.Bitmap) *roaring64.Bitmap {
+	result := docs.Clone()
+	for _, tt := range terms {
+		b, ok := t.postings[tt.value]
+		if !ok {
+			return roaring64.New()
+		}
+		result.And(b)
+	}
+	return result
+}
+
+// not returns the bitmap of the documents that don't contain the analyzed key.
+func (t *textIndex) not(key string) *roaring64.Bitmap {
+	all := roaring64.New()
+	t.w.RLock()
+	for ref := range t.lengths {
+		all.Add(ref)
+	}
+	t.w.RUnlock()
+	all.AndNot(t.get(key))
+	return all
+}
+
+// clause is a part of a search query, either a single term, or a phrase of multiple terms.
+type clause []term
+
+// parseQuery splits the query into clauses, analyzing its words as the lang language: the text between
+// double quotes results in a phrase clause, and the rest of the words result in single term clauses.
+// The clauses that contain only stopwords are dropped.
+func parseQuery(query string, lang language.Tag) []clause {
+	clauses := make([]clause, 0)
+	for i, part := range strings.Split(query, `"`) {
+		terms := analyzeText(part, lang)
+		if i%2 == 1 {
+			if len(terms) > 0 {
+				clauses = append(clauses, terms)
+			}
+			continue
+		}
+		for _, tt := range terms {
+			clauses = append(clauses, clause{tt})
+		}
+	}
+	return clauses
+}
+
+// Search returns the references of the documents matching any of the clauses of the query,
+// ordered by their BM25 score.
+// The words between double quotes are searched as a phrase, eg: `"free software" licenses`.
+//
+// The query is analyzed as each of the languages of the indexed documents, and its clauses are matched only
+// with the documents of the corresponding language. The documents having text in multiple languages get
+// the best of their scores.
+func (t *textIndex) Search(query string) []Scored[uint64] {
+	t.w.RLock()
+	defer t.w.RUnlock()
+
+	docCount := float64(len(t.lengths))
+	if docCount == 0 {
+		return nil
+	}
+	avgLength := float64(t.totalLength) / docCount
+
+	scores := make(map[uint64]float64)
+	for lang, docs := range t.languages {
+		langScores := make(map[uint64]float64)
+		for _, c := range parseQuery(query, language.Make(lang)) {
+			for ref, tfs := range t.matchClause(c, docs) {
+				for i, tt := range c {
+					n := float64(t.postings[tt.value].GetCardinality())
+					idf := math.Log(1 + (docCount-n+0.5)/(n+0.5))
+					tf := float64(tfs[i])
+					norm := tf + bm25K1*(1-bm25B+bm25B*float64(t.lengths[ref])/avgLength)
+					langScores[ref] += idf * tf * (bm25K1 + 1) / norm
+				}
+			}
+		}
+		for ref, score := range langScores {
+			scores[ref] = max(scores[ref], score)
+		}
+	}
+	if len(scores) == 0 {
+		return nil
+	}
+
+	result := make([]Scored[uint64], 0, len(scores))
+	for ref, score := range scores {
+		result = append(result, Scored[uint64]{Value: ref, Score: score})
+	}
+	slices.SortFunc(result, func(a, b Scored[uint64]) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Value, b.Value)
+	})
+	return result
+}
+
+// matchClause returns the documents of the docs bitmap matching the c clause, together with the frequencies
+// of its terms. For the phrases, the frequencies are the number of times the whole phrase appears in the document.
+func (t *textIndex) matchClause(c clause, docs *roaring64.Bitmap) map[uint64][]int {
+	result := make(map[uint64][]int)
+	it := t.matchTerms(c, docs).Iterator()
+	for it.HasNext() {
+		ref := it.Next()
+		tfs := make([]int, len(c))
+		if len(c) == 1 {
+			tfs[0] = len(t.positions[c[0].value][ref])
+		} else {
+			count := t.phraseCount(c, ref)
+			if count == 0 {
+				continue
+			}
+			for i := range tfs {
+				tfs[i] = count
+			}
+		}
+		result[ref] = tfs
+	}
+	return result
+}
+
+// phraseCount returns the number of times the terms of the phrase appear in the document,
+// at the same distances from each other as in the query.
+func (t *textIndex) phraseCount(phrase clause, ref uint64) int {
+	count := 0
+	for _, start := range t.positions[phrase[0].value][ref] {
+		found := true
+		for _, tt := range phrase[1:] {
+			want := start + tt.pos - phrase[0].pos
+			if _, ok := slices.BinarySearch(t.positions[tt.value][ref], want); !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			count++
+		}
+	}
+	return count
+}
+
+// encodeDocument encodes the terms of the ref document, with their positions, and its length and languages.
+// See the "text" payload of the Index binary format.
+func (t *textIndex) encodeDocument(ref uint64) ([]byte, error) {
+	t.w.RLock()
+	defer t.w.RUnlock()
+
+	length, ok := t.lengths[ref]
+	if !ok {
+		return []byte{0}, nil
+	}
+	b := binary.AppendUvarint([]byte{1}, uint64(length))
+	terms := slices.Sorted(slices.Values(t.terms[ref]))
+	b = binary.AppendUvarint(b, uint64(len(terms)))
+	for _, value := range terms {
+		b = appendBytes(b, []byte(value))
+		positions := t.positions[value][ref]
+		b = binary.AppendUvarint(b, uint64(len(positions)))
+		for _, pos := range positions {
+			b = binary.AppendUvarint(b, uint64(pos))
+		}
+	}
+	languages := make([]string, 0)
+	for _, lang := range slices.Sorted(maps.Keys(t.languages)) {
+		if t.languages[lang].Contains(ref) {
+			languages = append(languages, lang)
+		}
+	}
+	b = binary.AppendUvarint(b, uint64(len(languages)))
+	for _, lang := range languages {
+		b = appendBytes(b, []byte(lang))
+	}
+	return b, nil
+}
+
+func (t *textIndex) decodeDocument(ref uint64, data []byte) error {
+	r := reader{b: data}
+	if r.byte() == 0 {
+		return r.err
+	}
+	length := r.uvarint()
+	if length > math.MaxUint32 {
+		return fmt.Errorf("%w: invalid document length", ErrInvalidFormat)
+	}
+	positions := make(map[string][]uint32)
+	count := r.uvarint()
+	for j := uint64(0); j < count && r.err == nil; j++ {
+		value := string(r.chunk())
+		n := r.uvarint()
+		for k := uint64(0); k < n && r.err == nil; k++ {
+			positions[value] = append(positions[value], uint32(r.uvarint()))
+		}
+	}
+	count = r.uvarint()
+	languages := make([]string, 0, min(count, uint64(len(r.b))))
+	for j := uint64(0); j < count && r.err == nil; j++ {
+		languages = append(languages, string(r.chunk()))
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	t.w.Lock()
+	defer t.w.Unlock()
+
+	for value, positions := range positions {
+		for _, pos := range positions {
+			t.addTerm(value, ref, pos)
+		}
+	}
+	for _, lang := range languages {
+		t.addLanguage(lang, ref)
+	}
+	t.lengths[ref] = uint32(length)
+	t.totalLength += length
+	return nil
+}
+
+// bareTextIndex is the serialization format of the textIndex.
+// The bitmaps of the documents, and the terms of every document, are rebuilt from the positions when decoding.
+type bareTextIndex struct {
+	Positions map[string]map[uint64][]uint32
+	Lengths   map[uint64]uint32
+	Languages map[string][]uint64
+}
+
+func (t *textIndex) MarshalBinary() ([]byte, error) {
+	t.w.RLock()
+	defer t.w.RUnlock()
+
+	languages := make(map[string][]uint64, len(t.languages))
+	for lang, docs := range t.languages {
+		languages[lang] = docs.ToArray()
+	}
+	buff := bytes.Buffer{}
+	err := gob.NewEncoder(&buff).Encode(bareTextIndex{Positions: t.positions, Lengths: t.lengths, Languages: languages})
+	return buff.Bytes(), err
+}
+
+func (t *textIndex) UnmarshalBinary(data []byte) error {
+	b := bareTextIndex{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&b); err != nil {
+		return err
+	}
+
+	t.w.Lock()
+	defer t.w.Unlock()
+
+	t.positions = b.Positions
+	if t.positions == nil {
+		t.positions = make(map[string]map[uint64][]uint32)
+	}
+	t.lengths = b.Lengths
+	if t.lengths == nil {
+		t.lengths = make(map[uint64]uint32)
+	}
+	t.languages = make(map[string]*roaring64.Bitmap, len(b.Languages))
+	for lang, refs := range b.Languages {
+		t.languages[lang] = roaring64.BitmapOf(refs...)
+	}
+	t.postings = make(map[string]*roaring64.Bitmap, len(t.positions))
+	t.terms = make(map[uint64][]string, len(t.lengths))
+	t.shared = cow[string]{}
+	for value, docs := range t.positions {
+		t.postings[value] = roaring64.New()
+		for ref := range docs {
+			t.postings[value].Add(ref)
+			t.terms[ref] = append(t.terms[ref], value)
+		}
+	}
+	t.totalLength = 0
+	for _, l := range t.lengths {
+		t.totalLength += uint64(l)
+	}
+	return nil
+}
+
+// Search does a full-text search of the query in the ByText index, and returns the IRIs of the matching
+// documents ordered by relevance, using the BM25 ranking function.
+// The query words are analyzed the same way as the indexed text: without stopwords, stemmed, and folded,
+// once for each of the languages of the indexed documents, which are matched only with the query terms
+// of their language.
+// The words between double quotes are searched as a phrase, eg: `"free software" licenses`.
+//
+// It returns nil if the index doesn't contain a ByText index.
+func (i *Index) Search(query string) []Scored[vocab.IRI] {
+	i.w.RLock()
+	defer i.w.RUnlock()
+
+	ti, ok := i.Indexes[ByText].(*textIndex)
+	if !ok {
+		return nil
+	}
+	refs := ti.Search(query)
+	result := make([]Scored[vocab.IRI], 0, len(refs))
+	for _, r := range refs {
+		for _, iri := range i.iris(r.Value) {
+			result = append(result, Scored[vocab.IRI]{Value: iri, Score: r.Score})
+		}
+	}
+	return result
+}
@@ -0,0 +1,258 @@
+package index
+
+import (
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/text/language"
+)
+
+func textObject(id vocab.IRI, content string) *vocab.Object {
+	return &vocab.Object{
+		ID:      id,
+		Type:    vocab.NoteType,
+		Content: nlv(kv(vocab.NilLangRef, vocab.Content(content))),
+	}
+}
+
+var searchObjects = []vocab.LinkOrIRI{
+	textObject("https://example.com/1", "<p>The quick brown fox jumps over the lazy dog</p>"),
+	textObject("https://example.com/2", "A fox, a fox, and another fox in the garden"),
+	textObject("https://example.com/3", "The dog sleeps, the brown cat is quick"),
+	textObject("https://example.com/4", "Free software licenses"),
+	&vocab.Object{
+		ID:      "https://example.com/5",
+		Name:    nlv(kv(vocab.NilLangRef, vocab.Content("Brown"))),
+		Summary: nlv(kv(vocab.NilLangRef, vocab.Content("Fox"))),
+	},
+}
+
+func searchIRIs(res []Scored[vocab.IRI]) vocab.IRIs {
+	iris := make(vocab.IRIs, 0, len(res))
+	for _, r := range res {
+		iris = append(iris, r.Value)
+	}
+	return iris
+}
+
+func TestIndex_Search(t *testing.T) {
+	i := Partial(ByText)
+	i.Add(searchObjects...)
+
+	tests := []struct {
+		name  string
+		query string
+		want  vocab.IRIs
+	}{
+		{
+			name:  "empty",
+			query: "",
+			want:  vocab.IRIs{},
+		},
+		{
+			name:  "only stopwords",
+			query: "the and of",
+			want:  vocab.IRIs{},
+		},
+		{
+			name:  "not found",
+			query: "elephant",
+			want:  vocab.IRIs{},
+		},
+		{
+			name:  "term frequency ranks higher",
+			query: "fox",
+			want:  vocab.IRIs{"https://example.com/2", "https://example.com/5", "https://example.com/1"},
+		},
+		{
+			name:  "stemmed and folded, rarer terms rank higher",
+			query: "FOX Sleeping",
+			want:  vocab.IRIs{"https://example.com/3", "https://example.com/2", "https://example.com/5", "https://example.com/1"},
+		},
+		{
+			name:  "more matching terms rank higher",
+			query: "quick brown dog",
+			want:  vocab.IRIs{"https://example.com/3", "https://example.com/1", "https://example.com/5"},
+		},
+		{
+			name:  "phrase",
+			query: `"quick brown"`,
+			want:  vocab.IRIs{"https://example.com/1"},
+		},
+		{
+			name:  "phrase with stopwords",
+			query: `"over the lazy dog"`,
+			want:  vocab.IRIs{"https://example.com/1"},
+		},
+		{
+			name:  "phrase does not match across values",
+			query: `"brown fox"`,
+			want:  vocab.IRIs{"https://example.com/1"},
+		},
+		{
+			name:  "phrase and term",
+			query: `"free software" garden`,
+			want:  vocab.IRIs{"https://example.com/4", "https://example.com/2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := searchIRIs(i.Search(tt.query))
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %s", tt.query, cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestIndex_Search_languages(t *testing.T) {
+	i := Partial(ByText)
+	i.Add(
+		&vocab.Object{
+			ID:      "https://example.com/fr",
+			Content: nlv(kv(language.French, vocab.Content("Les chevaux des maisons"))),
+		},
+		&vocab.Object{
+			ID:      "https://example.com/en",
+			Content: nlv(kv(language.English, vocab.Content("Never say die"))),
+		},
+		&vocab.Object{
+			ID:      "https://example.com/de",
+			Content: nlv(kv(language.German, vocab.Content("Die Häuser"))),
+		},
+	)
+
+	tests := []struct {
+		name  string
+		query string
+		want  vocab.IRIs
+	}{
+		{
+			name:  "analyzed as french",
+			query: "cheval",
+			want:  vocab.IRIs{"https://example.com/fr"},
+		},
+		{
+			name:  "plural analyzed as french",
+			query: "maisons",
+			want:  vocab.IRIs{"https://example.com/fr"},
+		},
+		{
+			name:  "german stopword is searched in english",
+			query: "die",
+			want:  vocab.IRIs{"https://example.com/en"},
+		},
+		{
+			name:  "analyzed as german",
+			query: "haus",
+			want:  vocab.IRIs{"https://example.com/de"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := searchIRIs(i.Search(tt.query))
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %s", tt.query, cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestIndex_Search_withoutTextIndex(t *testing.T) {
+	i := Partial(ByType)
+	i.Add(searchObjects...)
+	if got := i.Search("fox"); got != nil {
+		t.Errorf("Search() = %v, want nil", got)
+	}
+}
+
+func Test_textIndex_MarshalBinary(t *testing.T) {
+	ti := NewTextIndex(ExtractText).(*textIndex)
+	for _, ob := range searchObjects {
+		ti.Add(ob)
+	}
+	data, err := ti.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+
+	got := textIndex{}
+	if err = got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	for _, q := range []string{"fox", `"quick brown" dog`, "software"} {
+		if !cmp.Equal(got.Search(q), ti.Search(q)) {
+			t.Errorf("Search(%q) after UnmarshalBinary() = %s", q, cmp.Diff(ti.Search(q), got.Search(q)))
+		}
+	}
+}
+
+func Test_textIndex_get(t *testing.T) {
+	ti := NewTextIndex(ExtractText).(*textIndex)
+	for _, ob := range searchObjects {
+		ti.Add(ob)
+	}
+	tests := []struct {
+		key  string
+		want []uint64
+	}{
+		{key: "fox", want: []uint64{HashFn(vocab.IRI("https://example.com/1")), HashFn(vocab.IRI("https://example.com/2")), HashFn(vocab.IRI("https://example.com/5"))}},
+		{key: "lazy dogs", want: []uint64{HashFn(vocab.IRI("https://example.com/1"))}},
+		{key: "elephant", want: []uint64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got := ti.get(tt.key)
+			for _, ref := range tt.want {
+				if !got.Contains(ref) {
+					t.Errorf("get(%q) doesn't contain %d", tt.key, ref)
+				}
+			}
+			if got.GetCardinality() != uint64(len(tt.want)) {
+				t.Errorf("get(%q) = %v, want %v", tt.key, got.ToArray(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_textIndex_Remove(t *testing.T) {
+	removed := HashFn(searchObjects[1])
+
+	without := NewTextIndex(ExtractText).(*textIndex)
+	for _, ob := range searchObjects {
+		if HashFn(ob) != removed {
+			without.Add(ob)
+		}
+	}
+
+	ti := NewTextIndex(ExtractText).(*textIndex)
+	for _, ob := range searchObjects {
+		ti.Add(ob)
+	}
+	data, err := ti.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	decoded := textIndex{}
+	if err = decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+
+	for name, in := range map[string]*textIndex{"added": ti, "decoded": &decoded} {
+		t.Run(name, func(t *testing.T) {
+			in.Remove(removed)
+			for _, q := range []string{"fox", `"quick brown" dog`, "garden"} {
+				if !cmp.Equal(in.Search(q), without.Search(q)) {
+					t.Errorf("Search(%q) after Remove() = %s", q, cmp.Diff(without.Search(q), in.Search(q)))
+				}
+			}
+			if in.totalLength != without.totalLength {
+				t.Errorf("Remove() total length = %d, want %d", in.totalLength, without.totalLength)
+			}
+			if _, ok := in.postings["garden"]; ok {
+				t.Errorf("Remove() kept the postings of the removed document's terms")
+			}
+		})
+	}
+}
@@ -0,0 +1,688 @@
+package index
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	vocab "github.com/go-ap/activitypub"
+)
+
+// The segments are read-only indexes, which are meant to be memory-mapped instead of being decoded
+// into the heap when loading them. They use the same section framing as the binary format of the Index:
+//
+//	segment = header section*
+//	header  = magic[8] version[2] reserved[2]
+//
+// The magic is "GOAPSEG\x00", and the sections of a segment are:
+//   - one ref table section, with the IRIs of the documents, sorted by their references.
+//   - one deleted section, with the portable roaring bitmap of the documents removed in the segment.
+//   - one tokens section for every token index, with the token dictionary sorted by the token bytes, and
+//     the portable roaring bitmaps of the tokens.
+//   - one index section for the other index types, with the MarshalBinary payload of the index,
+//     which gets decoded when opening the segment.
+//
+// The ref table section payload is:
+//
+//	table = count[8] (ref[8] offset[8])* iris*
+//
+// where the offsets point to the IRIs of each reference, relative to the end of the table entries.
+//
+// The tokens section payload is:
+//
+//	tokens  = kind[1] count[8] padding[1] zero[padding] (keyOffset[8] keyLength[8] bitmapOffset[8] bitmapLength[8])* blob
+//
+// where the offsets are relative to the blob, and the padding aligns the blob and the bitmaps in it to 8 bytes,
+// so the bitmaps can be used directly from the mapped memory.
+const (
+	// SegmentVersion is the version of the segment format.
+	SegmentVersion uint16 = 1
+
+	segmentMagic = "GOAPSEG\x00"
+
+	refEntrySize   = 16
+	tokenEntrySize = 32
+)
+
+const (
+	keyString uint8 = iota + 1
+	keyUint32
+	keyUint64
+)
+
+// Segment is a read-only Index, whose token dictionaries and bitmaps are memory-mapped from a file,
+// and are loaded only when the searches need them.
+// The Index of the segment can be used for searching like any other, but adding or removing documents
+// doesn't do anything. For applying changes, write them in a new segment using an in-memory Index,
+// and combine them using Compact.
+type Segment struct {
+	*Index
+	data  []byte
+	unmap func() error
+}
+
+// OpenSegment maps the segment file at path into memory.
+// Only the ref table, the ByID bitmap and the index types that don't support segments, like the ByText
+// index, get decoded when opening it, the checksums of the mapped sections are verified only by Verify.
+//
+// The Segment must be closed when it's not needed anymore.
+func OpenSegment(path string) (*Segment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if st.Size() < int64(headerSize) {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidFormat)
+	}
+	data, unmap, err := mapFile(f, int(st.Size()))
+	if err != nil {
+		return nil, err
+	}
+	i, err := loadSegment(data)
+	if err != nil {
+		_ = unmap()
+		return nil, err
+	}
+	return &Segment{Index: i, data: data, unmap: unmap}, nil
+}
+
+// Close unmaps the segment, after which its Index doesn't contain any documents.
+func (s *Segment) Close() error {
+	s.w.Lock()
+	defer s.w.Unlock()
+
+	if s.unmap == nil {
+		return nil
+	}
+	s.Indexes = make(map[Type]Indexable)
+	s.table = nil
+	s.data = nil
+	err := s.unmap()
+	s.unmap = nil
+	return err
+}
+
+// Verify checks the checksums of all the sections of the segment.
+func (s *Segment) Verify() error {
+	s.w.RLock()
+	defer s.w.RUnlock()
+
+	if len(s.data) < headerSize {
+		return fmt.Errorf("%w: closed segment", ErrInvalidFormat)
+	}
+	for data := s.data[headerSize:]; len(data) > 0; {
+		_, _, _, rest, err := readSection(data)
+		if err != nil {
+			return err
+		}
+		data = rest
+	}
+	return nil
+}
+
+func loadSegment(data []byte) (*Index, error) {
+	if len(data) < headerSize || string(data[:len(segmentMagic)]) != segmentMagic {
+		return nil, fmt.Errorf("%w: missing segment header", ErrInvalidFormat)
+	}
+	if v := binary.BigEndian.Uint16(data[len(segmentMagic):]); v != SegmentVersion {
+		return nil, fmt.Errorf("%w: %d, expected %d", ErrUnsupportedVersion, v, SegmentVersion)
+	}
+
+	i := &Index{
+		Ref:        make(map[uint64]vocab.IRI),
+		Collisions: make(map[uint64]vocab.IRIs),
+		Indexes:    make(map[Type]Indexable),
+		changed:    make(map[uint64]struct{}),
+		readOnly:   true,
+		table:      &refTable{},
+		deleted:    roaring64.New(),
+	}
+	for data = data[headerSize:]; len(data) > 0; {
+		kind, typ, payload, rest, err := splitSection(data)
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case sectionRefTable:
+			i.table, err = decodeRefTable(payload)
+		case sectionTokens:
+			i.Indexes[Type(typ)], err = decodeTokens(payload)
+		case sectionDeleted:
+			if err = checkSection(data[:len(data)-len(rest)]); err == nil {
+				err = i.deleted.UnmarshalBinary(payload)
+			}
+		case sectionIndex:
+			if err = checkSection(data[:len(data)-len(rest)]); err == nil {
+				err = i.decodeIndex(Type(typ), payload, nil)
+			}
+		default:
+			err = fmt.Errorf("%w: unknown segment section kind %d", ErrInvalidFormat, kind)
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = rest
+	}
+	return i, nil
+}
+
+// refTable is the table of the references of a segment, sorted, so they can be looked up without decoding it.
+type refTable struct {
+	entries []byte
+	iriData []byte
+}
+
+func decodeRefTable(payload []byte) (*refTable, error) {
+	if len(payload) < 8 {
+		return nil, fmt.Errorf("%w: truncated ref table", ErrInvalidFormat)
+	}
+	count := binary.BigEndian.Uint64(payload)
+	if count > uint64(len(payload)-8)/refEntrySize {
+		return nil, fmt.Errorf("%w: truncated ref table", ErrInvalidFormat)
+	}
+	end := 8 + int(count)*refEntrySize
+	return &refTable{entries: payload[8:end], iriData: payload[end:]}, nil
+}
+
+func (t *refTable) len() int {
+	return len(t.entries) / refEntrySize
+}
+
+func (t *refTable) ref(j int) uint64 {
+	return binary.BigEndian.Uint64(t.entries[j*refEntrySize:])
+}
+
+func (t *refTable) irisAt(j int) vocab.IRIs {
+	off := binary.BigEndian.Uint64(t.entries[j*refEntrySize+8:])
+	if off >= uint64(len(t.iriData)) {
+		return nil
+	}
+	r := reader{b: t.iriData[off:]}
+	iris := r.iris()
+	if r.err != nil {
+		return nil
+	}
+	return iris
+}
+
+func (t *refTable) iris(ref uint64) vocab.IRIs {
+	j := sort.Search(t.len(), func(j int) bool { return t.ref(j) >= ref })
+	if j == t.len() || t.ref(j) != ref {
+		return nil
+	}
+	return t.irisAt(j)
+}
+
+func (t *refTable) all() iter.Seq2[uint64, vocab.IRIs] {
+	return func(yield func(uint64, vocab.IRIs) bool) {
+		for j := 0; j < t.len(); j++ {
+			if !yield(t.ref(j), t.irisAt(j)) {
+				return
+			}
+		}
+	}
+}
+
+// segmentTokens is a token index of a segment, which looks up the tokens in the sorted dictionary,
+// and decodes their bitmaps on demand.
+type segmentTokens[T Tokenizable] struct {
+	entries []byte
+	blob    []byte
+}
+
+func decodeTokens(payload []byte) (Indexable, error) {
+	if len(payload) < 10 {
+		return nil, fmt.Errorf("%w: truncated tokens", ErrInvalidFormat)
+	}
+	kind := payload[0]
+	count := binary.BigEndian.Uint64(payload[1:])
+	start := 10 + int(payload[9])
+	if len(payload) < start || count > uint64(len(payload)-start)/tokenEntrySize {
+		return nil, fmt.Errorf("%w: truncated tokens", ErrInvalidFormat)
+	}
+	end := start + int(count)*tokenEntrySize
+	entries, blob := payload[start:end], payload[end:]
+	switch kind {
+	case keyString:
+		return &segmentTokens[string]{entries: entries, blob: blob}, nil
+	case keyUint32:
+		return &segmentTokens[uint32]{entries: entries, blob: blob}, nil
+	case keyUint64:
+		return &segmentTokens[uint64]{entries: entries, blob: blob}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown token kind %d", ErrInvalidFormat, kind)
+}
+
+func (s *segmentTokens[T]) len() int {
+	return len(s.entries) / tokenEntrySize
+}
+
+// slice returns the blob bytes at the offset and length stored at the pos of the j entry.
+func (s *segmentTokens[T]) slice(j, pos int) []byte {
+	e := s.entries[j*tokenEntrySize+pos:]
+	off, l := binary.BigEndian.Uint64(e), binary.BigEndian.Uint64(e[8:])
+	if off > uint64(len(s.blob)) || l > uint64(len(s.blob))-off {
+		return nil
+	}
+	return s.blob[off : off+l]
+}
+
+func (s *segmentTokens[T]) key(j int) []byte {
+	return s.slice(j, 0)
+}
+
+// bitmap returns the bitmap of the j entry, which uses the mapped memory, so it must not be modified
+// or used after closing the segment.
+func (s *segmentTokens[T]) bitmap(j int) *roaring64.Bitmap {
+	b := roaring64.New()
+	if _, err := b.FromUnsafeBytes(s.slice(j, 16)); err != nil {
+		return roaring64.New()
+	}
+	return b
+}
+
+func (s *segmentTokens[T]) find(key T) (int, bool) {
+	k := encodeKey(key)
+	j := sort.Search(s.len(), func(j int) bool { return bytes.Compare(s.key(j), k) >= 0 })
+	return j, j < s.len() && bytes.Equal(s.key(j), k)
+}
+
+// Add doesn't do anything, as the segments are read-only.
+func (s *segmentTokens[T]) Add(vocab.LinkOrIRI) uint64 {
+	return 0
+}
+
+// get returns a copy of the bitmap corresponding to the key.
+func (s *segmentTokens[T]) get(key T) *roaring64.Bitmap {
+	b := roaring64.New()
+	if j, ok := s.find(key); ok {
+		_ = b.UnmarshalBinary(s.slice(j, 16))
+	}
+	return b
+}
+
+// not returns the OR-ed bitmap values for all the tokens not corresponding to the key.
+func (s *segmentTokens[T]) not(key T) *roaring64.Bitmap {
+	skip, ok := s.find(key)
+	b := roaring64.New()
+	for j := 0; j < s.len(); j++ {
+		if ok && j == skip {
+			continue
+		}
+		b.Or(s.bitmap(j))
+	}
+	return b
+}
+
+func (s *segmentTokens[T]) indexed() *roaring64.Bitmap {
+	b := roaring64.New()
+	for j := 0; j < s.len(); j++ {
+		b.Or(s.bitmap(j))
+	}
+	return b
+}
+
+func (s *segmentTokens[T]) keyKind() uint8 {
+	return keyKindOf[T]()
+}
+
+func (s *segmentTokens[T]) tokens() iter.Seq2[[]byte, *roaring64.Bitmap] {
+	return func(yield func([]byte, *roaring64.Bitmap) bool) {
+		for j := 0; j < s.len(); j++ {
+			if !yield(s.key(j), s.bitmap(j)) {
+				return
+			}
+		}
+	}
+}
+
+// segmentEncoder is implemented by the token indexes, which can be written in the tokens sections of a segment.
+type segmentEncoder interface {
+	keyKind() uint8
+	tokens() iter.Seq2[[]byte, *roaring64.Bitmap]
+}
+
+func (i *tokenMap[T]) keyKind() uint8 {
+	return keyKindOf[T]()
+}
+
+func (i *tokenMap[T]) tokens() iter.Seq2[[]byte, *roaring64.Bitmap] {
+	return func(yield func([]byte, *roaring64.Bitmap) bool) {
+		i.w.RLock()
+		defer i.w.RUnlock()
+		for tok, b := range i.m {
+			if !yield(encodeKey(tok), b) {
+				return
+			}
+		}
+	}
+}
+
+func keyKindOf[T Tokenizable]() uint8 {
+	var tok T
+	switch any(tok).(type) {
+	case uint32:
+		return keyUint32
+	case uint64:
+		return keyUint64
+	}
+	return keyString
+}
+
+// encodeKey returns the bytes of the tok token, the integers being big endian, so they sort the same.
+func encodeKey[T Tokenizable](tok T) []byte {
+	switch v := any(tok).(type) {
+	case uint32:
+		return binary.BigEndian.AppendUint32(nil, v)
+	case uint64:
+		return binary.BigEndian.AppendUint64(nil, v)
+	}
+	return []byte(reflect.ValueOf(tok).String())
+}
+
+// decodeKey returns the token encoded by encodeKey, and false if the length of b doesn't match the integer tokens.
+func decodeKey[T Tokenizable](b []byte) (T, bool) {
+	var tok T
+	switch p := any(&tok).(type) {
+	case *uint32:
+		if len(b) != 4 {
+			return tok, false
+		}
+		*p = binary.BigEndian.Uint32(b)
+	case *uint64:
+		if len(b) != 8 {
+			return tok, false
+		}
+		*p = binary.BigEndian.Uint64(b)
+	default:
+		reflect.ValueOf(&tok).Elem().SetString(string(b))
+	}
+	return tok, true
+}
+
+// WriteSegment writes the index to w as a segment, which can be opened using OpenSegment.
+// The documents removed since the last write are recorded in the segment, so they get removed
+// from the older segments when combining them with Compact.
+func (i *Index) WriteSegment(w io.Writer) (int64, error) {
+	i.w.Lock()
+	defer i.w.Unlock()
+
+	b, err := appendSegment(nil, i)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	if err == nil {
+		clear(i.changed)
+	}
+	return int64(n), err
+}
+
+// Compact writes to w a segment combining the indexes, which can be segments or in-memory indexes,
+// and are received from the oldest to the newest. The documents of every index replace the ones
+// with the same references in the older indexes, and the documents removed from it are removed
+// from the older ones.
+func Compact(w io.Writer, indexes ...*Index) (int64, error) {
+	for _, i := range indexes {
+		i.w.RLock()
+		defer i.w.RUnlock()
+	}
+
+	b, err := appendSegment(nil, indexes...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// documents returns the references of the documents of the index.
+func (i *Index) documents() *roaring64.Bitmap {
+	b := roaring64.New()
+	for ref := range i.allIRIs() {
+		b.Add(ref)
+	}
+	return b
+}
+
+// removed returns the references of the documents removed from the index.
+func (i *Index) removed() *roaring64.Bitmap {
+	b := roaring64.New()
+	if i.deleted != nil {
+		b.Or(i.deleted)
+	}
+	for ref := range i.changed {
+		if len(i.iris(ref)) == 0 {
+			b.Add(ref)
+		}
+	}
+	return b
+}
+
+func (i *Index) allIRIs() iter.Seq2[uint64, vocab.IRIs] {
+	return func(yield func(uint64, vocab.IRIs) bool) {
+		for ref := range i.Ref {
+			if !yield(ref, i.iris(ref)) {
+				return
+			}
+		}
+		if i.table == nil {
+			return
+		}
+		for ref, iris := range i.table.all() {
+			if _, ok := i.Ref[ref]; ok {
+				continue
+			}
+			if !yield(ref, iris) {
+				return
+			}
+		}
+	}
+}
+
+func appendSegment(b []byte, sources ...*Index) ([]byte, error) {
+	// NOTE(marius): the documents of every source shadow the ones in the older sources, so for every source
+	// we compute the references present, or removed, in the newer ones.
+	docs := make([]*roaring64.Bitmap, len(sources))
+	shadows := make([]*roaring64.Bitmap, len(sources))
+	newer := roaring64.New()
+	for k := len(sources) - 1; k >= 0; k-- {
+		shadows[k] = newer.Clone()
+		docs[k] = sources[k].documents()
+		newer.Or(docs[k])
+		newer.Or(sources[k].removed())
+	}
+
+	refs := make(map[uint64]vocab.IRIs)
+	deleted := roaring64.New()
+	types := make(map[Type]struct{})
+	for _, s := range sources {
+		removed := s.removed()
+		for _, ref := range removed.ToArray() {
+			delete(refs, ref)
+		}
+		deleted.Or(removed)
+		for ref, iris := range s.allIRIs() {
+			refs[ref] = iris
+		}
+		for typ := range s.Indexes {
+			types[typ] = struct{}{}
+		}
+	}
+	for ref := range refs {
+		deleted.Remove(ref)
+	}
+
+	b = append(b, segmentMagic...)
+	b = binary.BigEndian.AppendUint16(b, SegmentVersion)
+	b = binary.BigEndian.AppendUint16(b, 0)
+
+	b = appendRefTable(b, refs)
+	payload, err := deleted.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	b = appendSection(b, sectionDeleted, 0, payload)
+
+	for _, typ := range slices.Sorted(maps.Keys(types)) {
+		if b, err = appendSegmentIndex(b, typ, sources, docs, shadows); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func appendRefTable(b []byte, refs map[uint64]vocab.IRIs) []byte {
+	sorted := slices.Sorted(maps.Keys(refs))
+	table := binary.BigEndian.AppendUint64(nil, uint64(len(sorted)))
+	var iris []byte
+	for _, ref := range sorted {
+		table = binary.BigEndian.AppendUint64(table, ref)
+		table = binary.BigEndian.AppendUint64(table, uint64(len(iris)))
+		iris = appendIRIs(iris, refs[ref])
+	}
+	return appendSection(b, sectionRefTable, 0, append(table, iris...))
+}
+
+func appendSegmentIndex(b []byte, typ Type, sources []*Index, docs, shadows []*roaring64.Bitmap) ([]byte, error) {
+	var encoders, codecs int
+	present := 0
+	for _, s := range sources {
+		in, ok := s.Indexes[typ]
+		if !ok {
+			continue
+		}
+		present++
+		if _, ok := in.(segmentEncoder); ok {
+			encoders++
+		}
+		if _, ok := in.(documentCodec); ok {
+			codecs++
+		}
+	}
+
+	switch {
+	case typ == ByID:
+		ids := roaring64.New()
+		for k, s := range sources {
+			if in, ok := s.Indexes[typ]; ok {
+				ids.Or(roaring64.AndNot(Indexed(in), shadows[k]))
+			}
+		}
+		payload, err := ids.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+		return appendSection(b, sectionIndex, typ, payload), nil
+	case encoders == present:
+		return appendTokens(b, typ, sources, shadows)
+	case codecs == present:
+		return appendDocuments(b, typ, sources, docs, shadows)
+	}
+	return nil, fmt.Errorf("%w: index type %d can't be written to a segment", ErrInvalidFormat, typ)
+}
+
+// appendTokens merges the token bitmaps of the typ indexes of the sources, and appends them as a tokens section.
+func appendTokens(b []byte, typ Type, sources []*Index, shadows []*roaring64.Bitmap) ([]byte, error) {
+	kind := uint8(0)
+	merged := make(map[string]*roaring64.Bitmap)
+	for k, s := range sources {
+		in, ok := s.Indexes[typ].(segmentEncoder)
+		if !ok {
+			continue
+		}
+		if kind != 0 && kind != in.keyKind() {
+			return nil, fmt.Errorf("%w: index type %d has different token kinds", ErrInvalidFormat, typ)
+		}
+		kind = in.keyKind()
+		for key, bmp := range in.tokens() {
+			m, ok := merged[string(key)]
+			if !ok {
+				m = roaring64.New()
+				merged[string(key)] = m
+			}
+			m.Or(roaring64.AndNot(bmp, shadows[k]))
+		}
+	}
+
+	keys := slices.Sorted(maps.Keys(merged))
+	keys = slices.DeleteFunc(keys, func(k string) bool { return merged[k].IsEmpty() })
+
+	// NOTE(marius): the blob starts after the kind, count, padding length, padding and entries.
+	blobStart := len(b) + sectionHeaderSize + 10 + len(keys)*tokenEntrySize
+	padding := (8 - blobStart%8) % 8
+
+	payload := append([]byte{kind}, binary.BigEndian.AppendUint64(nil, uint64(len(keys)))...)
+	payload = append(payload, uint8(padding))
+	payload = append(payload, make([]byte, padding)...)
+	var blob []byte
+	for _, key := range keys {
+		data, err := merged[key].ToBytes()
+		if err != nil {
+			return nil, err
+		}
+		keyOffset := len(blob)
+		blob = append(blob, key...)
+		blob = append(blob, make([]byte, (8-len(blob)%8)%8)...)
+		payload = binary.BigEndian.AppendUint64(payload, uint64(keyOffset))
+		payload = binary.BigEndian.AppendUint64(payload, uint64(len(key)))
+		payload = binary.BigEndian.AppendUint64(payload, uint64(len(blob)))
+		payload = binary.BigEndian.AppendUint64(payload, uint64(len(data)))
+		blob = append(blob, data...)
+	}
+	return appendSection(b, sectionTokens, typ, append(payload, blob...)), nil
+}
+
+// appendDocuments merges the documents of the typ indexes of the sources, which don't support the tokens
+// sections, and appends the result as an index section.
+func appendDocuments(b []byte, typ Type, sources []*Index, docs, shadows []*roaring64.Bitmap) ([]byte, error) {
+	merged, ok := Partial(typ).Indexes[typ]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown index type %d", ErrInvalidFormat, typ)
+	}
+	dst, ok := merged.(documentCodec)
+	if !ok {
+		return nil, fmt.Errorf("%w: index type %d can't be written to a segment", ErrInvalidFormat, typ)
+	}
+	for k, s := range sources {
+		src, ok := s.Indexes[typ].(documentCodec)
+		if !ok {
+			continue
+		}
+		it := roaring64.AndNot(docs[k], shadows[k]).Iterator()
+		for it.HasNext() {
+			ref := it.Next()
+			data, err := src.encodeDocument(ref)
+			if err != nil {
+				return nil, err
+			}
+			if err = dst.decodeDocument(ref, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	m, ok := merged.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("%w: index type %d can't be encoded", ErrInvalidFormat, typ)
+	}
+	payload, err := m.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return appendSection(b, sectionIndex, typ, payload), nil
+}
@@ -0,0 +1,296 @@
+package index
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+)
+
+var segmentObjects = append(append([]vocab.LinkOrIRI{}, searchObjects...), trigramObjects...)
+
+func writeSegment(t *testing.T, indexes ...*Index) string {
+	t.Helper()
+	buf := bytes.Buffer{}
+	if _, err := Compact(&buf, indexes...); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "index.seg")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("unable to write segment: %v", err)
+	}
+	return path
+}
+
+func openSegment(t *testing.T, path string) *Segment {
+	t.Helper()
+	s, err := OpenSegment(path)
+	if err != nil {
+		t.Fatalf("OpenSegment() error = %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+// sameSegment checks that the got index returns the same documents as the want one.
+func sameSegment(t *testing.T, want, got *Index) {
+	t.Helper()
+	if len(got.Indexes) != len(want.Indexes) {
+		t.Errorf("index types = %d, want %d", len(got.Indexes), len(want.Indexes))
+	}
+	for typ, in := range want.Indexes {
+		w, g := Indexed(in), Indexed(got.Indexes[typ])
+		if w == nil {
+			continue
+		}
+		if g == nil || !g.Equals(w) {
+			t.Errorf("Indexed(%d) = %v, want %v", typ, g, w.ToArray())
+		}
+	}
+	for ref := range want.Ref {
+		if !cmp.Equal(got.IRIs(ref), want.IRIs(ref)) {
+			t.Errorf("IRIs(%d) = %s", ref, cmp.Diff(want.IRIs(ref), got.IRIs(ref)))
+		}
+	}
+	for _, q := range []string{"fox", `"quick brown" dog`, "software"} {
+		if !cmp.Equal(got.Search(q), want.Search(q)) {
+			t.Errorf("Search(%q) = %s", q, cmp.Diff(want.Search(q), got.Search(q)))
+		}
+	}
+	for _, tok := range []string{"note", "brown", "creme", "missing"} {
+		for _, typ := range []Type{ByType, ByName, ByContent} {
+			w, g := GetBitmaps(want.Indexes[typ], tok), GetBitmaps(got.Indexes[typ], tok)
+			if len(g) != 1 || !g[0].Equals(w[0]) {
+				t.Errorf("GetBitmaps(%d, %q) = %v, want %v", typ, tok, g, w)
+			}
+			wn, gn := want.Indexes[typ].(bitmaps[string]).not(tok), got.Indexes[typ].(bitmaps[string]).not(tok)
+			if !gn.Equals(wn) {
+				t.Errorf("not(%d, %q) = %v, want %v", typ, tok, gn.ToArray(), wn.ToArray())
+			}
+		}
+	}
+	for _, iri := range []vocab.IRI{"https://example.com/1", "https://example.com/~jdoe"} {
+		w, g := GetBitmaps(want.Indexes[ByID], HashFn(iri)), GetBitmaps(got.Indexes[ByID], HashFn(iri))
+		if len(g) != 1 || !g[0].Equals(w[0]) {
+			t.Errorf("GetBitmaps(ByID, %s) = %v, want %v", iri, g, w)
+		}
+	}
+}
+
+func TestOpenSegment(t *testing.T) {
+	in := Partial(extendedIndexTypes...)
+	in.Add(segmentObjects...)
+
+	s := openSegment(t, writeSegment(t, in))
+	sameSegment(t, in, s.Index)
+
+	if _, ok := s.Indexes[ByType].(*segmentTokens[string]); !ok {
+		t.Errorf("OpenSegment() ByType index = %T, want a mapped token index", s.Indexes[ByType])
+	}
+	if _, ok := s.Indexes[ByRecipients].(*segmentTokens[uint64]); !ok {
+		t.Errorf("OpenSegment() ByRecipients index = %T, want a mapped token index", s.Indexes[ByRecipients])
+	}
+	if err := s.Verify(); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestOpenSegment_errors(t *testing.T) {
+	in := Partial(ByID, ByType)
+	in.Add(segmentObjects...)
+	buf := bytes.Buffer{}
+	if _, err := in.WriteSegment(&buf); err != nil {
+		t.Fatalf("WriteSegment() error = %v", err)
+	}
+	valid := buf.Bytes()
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{
+			name: "empty",
+			data: nil,
+			want: ErrInvalidFormat,
+		},
+		{
+			name: "index format",
+			data: emptyFullIndex,
+			want: ErrInvalidFormat,
+		},
+		{
+			name: "version",
+			data: withByte(valid, 9, 2),
+			want: ErrUnsupportedVersion,
+		},
+		{
+			name: "truncated",
+			data: valid[:len(valid)-1],
+			want: ErrInvalidFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "index.seg")
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatalf("unable to write segment: %v", err)
+			}
+			s, err := OpenSegment(path)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("OpenSegment() error = %v, want %v", err, tt.want)
+			}
+			if s != nil {
+				_ = s.Close()
+			}
+		})
+	}
+}
+
+func TestSegment_Verify(t *testing.T) {
+	in := Partial(ByID, ByType)
+	in.Add(segmentObjects...)
+	path := writeSegment(t, in)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unable to read segment: %v", err)
+	}
+	// NOTE(marius): the last bytes of the file are the ByType token bitmaps, which are not verified when opening.
+	if err = os.WriteFile(path, withByte(data, len(data)-6, data[len(data)-6]^0xff), 0o600); err != nil {
+		t.Fatalf("unable to write segment: %v", err)
+	}
+	s := openSegment(t, path)
+	if err = s.Verify(); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrChecksum)
+	}
+	_ = s.Close()
+	if err = s.Verify(); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Verify() after Close() error = %v, want %v", err, ErrInvalidFormat)
+	}
+}
+
+func TestSegment_readOnly(t *testing.T) {
+	in := Partial(extendedIndexTypes...)
+	in.Add(segmentObjects[:2]...)
+	s := openSegment(t, writeSegment(t, in))
+
+	s.Add(segmentObjects[2])
+	s.Remove(segmentObjects[0].GetLink())
+	s.Update(segmentObjects[1], segmentObjects[3])
+	sameSegment(t, in, s.Index)
+}
+
+func TestCompact(t *testing.T) {
+	updated := textObject("https://example.com/2", "A cat in the garden")
+
+	base := Partial(extendedIndexTypes...)
+	base.Add(segmentObjects[:4]...)
+
+	changes := Partial(extendedIndexTypes...)
+	changes.Add(updated, segmentObjects[6])
+	changes.Remove(segmentObjects[0].GetLink())
+
+	want := Partial(extendedIndexTypes...)
+	want.Add(segmentObjects[2:4]...)
+	want.Add(updated, segmentObjects[6])
+
+	t.Run("segments", func(t *testing.T) {
+		older := openSegment(t, writeSegment(t, base))
+		newer := openSegment(t, writeSegment(t, changes))
+		s := openSegment(t, writeSegment(t, older.Index, newer.Index))
+		sameSegment(t, want, s.Index)
+		if len(s.IRIs(HashFn(segmentObjects[0]))) != 0 {
+			t.Errorf("Compact() kept the removed document")
+		}
+		if !s.deleted.Contains(HashFn(segmentObjects[0])) {
+			t.Errorf("Compact() didn't record the removed document")
+		}
+	})
+	t.Run("in-memory", func(t *testing.T) {
+		s := openSegment(t, writeSegment(t, base, changes))
+		sameSegment(t, want, s.Index)
+	})
+	t.Run("re-added", func(t *testing.T) {
+		again := Partial(extendedIndexTypes...)
+		again.Add(segmentObjects[0])
+		s := openSegment(t, writeSegment(t, base, changes, again))
+		if s.deleted.Contains(HashFn(segmentObjects[0])) {
+			t.Errorf("Compact() recorded the re-added document as removed")
+		}
+		if !GetBitmaps(s.Indexes[ByID], HashFn(segmentObjects[0]))[0].Contains(HashFn(segmentObjects[0])) {
+			t.Errorf("Compact() didn't contain the re-added document")
+		}
+	})
+}
+
+func TestIndex_WriteSegment(t *testing.T) {
+	in := Partial(ByID, ByType, ByText)
+	in.Add(segmentObjects...)
+	in.Remove("https://example.com/missing")
+
+	buf := bytes.Buffer{}
+	if _, err := in.WriteSegment(&buf); err != nil {
+		t.Fatalf("WriteSegment() error = %v", err)
+	}
+	if len(in.changed) != 0 {
+		t.Errorf("WriteSegment() kept %d changes", len(in.changed))
+	}
+
+	s, err := loadSegment(buf.Bytes())
+	if err != nil {
+		t.Fatalf("loadSegment() error = %v", err)
+	}
+	if !s.deleted.Contains(HashFn(vocab.IRI("https://example.com/missing"))) {
+		t.Errorf("WriteSegment() didn't record the removed document")
+	}
+	if _, err = in.WriteSegment(&buf); err != nil {
+		t.Fatalf("WriteSegment() error = %v", err)
+	}
+
+	in.Indexes[ByPublished] = struct{ Indexable }{}
+	if _, err = in.WriteSegment(&buf); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("WriteSegment() error = %v, want %v", err, ErrInvalidFormat)
+	}
+}
+
+func Test_encodeKey(t *testing.T) {
+	type named string
+	tests := []struct {
+		name string
+		got  []byte
+		want []byte
+	}{
+		{name: "string", got: encodeKey("note"), want: []byte("note")},
+		{name: "named string", got: encodeKey(named("note")), want: []byte("note")},
+		{name: "uint32", got: encodeKey(uint32(0x01020304)), want: []byte{1, 2, 3, 4}},
+		{name: "uint64", got: encodeKey(uint64(0x0102030405060708)), want: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !bytes.Equal(tt.got, tt.want) {
+				t.Errorf("encodeKey() = %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_decodeKey(t *testing.T) {
+	type named string
+	if got, ok := decodeKey[named](encodeKey(named("note"))); !ok || got != "note" {
+		t.Errorf("decodeKey() = %q, %t, want %q, true", got, ok, "note")
+	}
+	if got, ok := decodeKey[uint32](encodeKey(uint32(0x01020304))); !ok || got != 0x01020304 {
+		t.Errorf("decodeKey() = %x, %t, want %x, true", got, ok, 0x01020304)
+	}
+	if got, ok := decodeKey[uint64](encodeKey(uint64(0x0102030405060708))); !ok || got != 0x0102030405060708 {
+		t.Errorf("decodeKey() = %x, %t, want %x, true", got, ok, uint64(0x0102030405060708))
+	}
+	if _, ok := decodeKey[uint64]([]byte{1, 2, 3, 4}); ok {
+		t.Errorf("decodeKey() of a truncated uint64 = true, want false")
+	}
+}
@@ -0,0 +1,178 @@
+package index
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	vocab "github.com/go-ap/activitypub"
+)
+
+// cow keeps track of the values of an index that are shared with its snapshots, and need to be copied
+// before being modified. Before the first snapshot, all the values are owned by the index.
+type cow[K comparable] struct {
+	owned map[K]struct{}
+}
+
+// share marks all the values as shared.
+func (c *cow[K]) share() {
+	c.owned = make(map[K]struct{})
+}
+
+// own marks the k value as owned by the index, and returns true if it was shared,
+// in which case the caller needs to copy it before modifying it.
+func (c *cow[K]) own(k K) bool {
+	if c.owned == nil {
+		return false
+	}
+	if _, ok := c.owned[k]; ok {
+		return false
+	}
+	c.owned[k] = struct{}{}
+	return true
+}
+
+// mutableRefs copies the Ref and Collisions maps of the index before they get modified, if they are shared
+// with a snapshot. Like for the bitmaps of the token maps, they are shared when taking the snapshot, and
+// they get copied only by the first change made after it.
+// NOTE(marius): the IRIs of the collisions are modified in place when removing documents, so they get copied too.
+func (i *Index) mutableRefs() {
+	if !i.refsShared {
+		return
+	}
+	i.Ref = maps.Clone(i.Ref)
+	collisions := make(map[uint64]vocab.IRIs, len(i.Collisions))
+	for ref, iris := range i.Collisions {
+		collisions[ref] = slices.Clone(iris)
+	}
+	i.Collisions = collisions
+	i.refsShared = false
+}
+
+// snapshotter is implemented by the indexes that can return an immutable view of their contents.
+type snapshotter interface {
+	snapshot() Indexable
+}
+
+// Snapshot returns an immutable view of the index, which can be queried, eg: using SearchIndex, while
+// documents are being added to, or removed from, i, without seeing these changes.
+// The bitmaps, and the references of the documents, are shared between the index and the snapshot,
+// and they get copied only when the index modifies them, so taking a snapshot doesn't copy them.
+//
+// Like the segments, the snapshot is read-only, so adding documents to it doesn't do anything.
+// The indexes of custom types that don't support snapshots are shared with i, so the snapshot sees
+// the changes to them.
+// The snapshots of a Segment can't be used after closing it.
+func (i *Index) Snapshot() *Index {
+	i.w.Lock()
+	defer i.w.Unlock()
+
+	i.refsShared = true
+	s := Index{
+		Ref:        i.Ref,
+		Collisions: i.Collisions,
+		Indexes:    make(map[Type]Indexable, len(i.Indexes)),
+		changed:    make(map[uint64]struct{}),
+		readOnly:   true,
+		table:      i.table,
+		deleted:    i.deleted,
+		generation: i.generation,
+		tokenizer:  i.tokenizer,
+		refsShared: true,
+	}
+	for typ, in := range i.Indexes {
+		if sn, ok := in.(snapshotter); ok {
+			in = sn.snapshot()
+		}
+		s.Indexes[typ] = in
+	}
+	return &s
+}
+
+// Generation returns the number of changes made to the index, which increases with every document
+// that gets added, updated or removed. The snapshots keep the generation of the index they were taken from,
+// so it can be used for checking if an index changed since a snapshot.
+func (i *Index) Generation() uint64 {
+	i.w.RLock()
+	defer i.w.RUnlock()
+
+	return i.generation
+}
+
+func (i *tokenMap[T]) snapshot() Indexable {
+	i.w.Lock()
+	defer i.w.Unlock()
+
+	i.shared.share()
+	s := &tokenMap[T]{
+		m:               maps.Clone(i.m),
+		docs:            make(map[uint64][]T),
+		refsExtractFn:   i.refsExtractFn,
+		tokensExtractFn: i.tokensExtractFn,
+	}
+	s.shared.share()
+	return s
+}
+
+func (f *full) snapshot() Indexable {
+	f.w.Lock()
+	defer f.w.Unlock()
+
+	f.shared = true
+	return &full{bmp: f.bmp, shared: true}
+}
+
+func (t *timeIndex) snapshot() Indexable {
+	t.w.Lock()
+	defer t.w.Unlock()
+
+	t.shared = true
+	return &timeIndex{m: t.m, shared: true, extractFn: t.extractFn}
+}
+
+func (t *trigramIndex) snapshot() Indexable {
+	t.w.Lock()
+	defer t.w.Unlock()
+
+	t.shared.share()
+	s := &trigramIndex{
+		m:         maps.Clone(t.m),
+		values:    maps.Clone(t.values),
+		unpruned:  t.unpruned.Clone(),
+		extractFn: t.extractFn,
+	}
+	s.shared.share()
+	return s
+}
+
+func (t *textIndex) snapshot() Indexable {
+	t.w.Lock()
+	defer t.w.Unlock()
+
+	t.shared.share()
+	s := &textIndex{
+		postings:    maps.Clone(t.postings),
+		positions:   maps.Clone(t.positions),
+		lengths:     maps.Clone(t.lengths),
+		terms:       make(map[uint64][]string),
+		languages:   make(map[string]*roaring64.Bitmap, len(t.languages)),
+		totalLength: t.totalLength,
+		extractFn:   t.extractFn,
+	}
+	for lang, docs := range t.languages {
+		s.languages[lang] = docs.Clone()
+	}
+	s.shared.share()
+	return s
+}
+
+// snapshot returns the segment tokens, which are immutable.
+func (s *segmentTokens[T]) snapshot() Indexable {
+	return s
+}
+
+var _ snapshotter = new(tokenMap[string])
+var _ snapshotter = new(full)
+var _ snapshotter = new(trigramIndex)
+var _ snapshotter = new(textIndex)
+var _ snapshotter = new(segmentTokens[string])
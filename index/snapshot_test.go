@@ -0,0 +1,146 @@
+package index
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+)
+
+func TestIndex_Snapshot(t *testing.T) {
+	want := Partial(extendedIndexTypes...)
+	want.Add(segmentObjects[:5]...)
+
+	i := Partial(extendedIndexTypes...)
+	i.Add(segmentObjects[:5]...)
+	generation := i.Generation()
+
+	s := i.Snapshot()
+	if s.Generation() != generation {
+		t.Errorf("Snapshot() generation = %d, want %d", s.Generation(), generation)
+	}
+
+	updated := textObject("https://example.com/2", "A cat in the garden")
+	i.Update(segmentObjects[1], updated)
+	i.Remove(segmentObjects[0].GetLink())
+	i.Add(segmentObjects[6:]...)
+
+	t.Run("snapshot doesn't change", func(t *testing.T) {
+		sameIndexes(t, s, want)
+		sameSegment(t, want, s)
+	})
+	t.Run("index changes", func(t *testing.T) {
+		changed := Partial(extendedIndexTypes...)
+		changed.Add(segmentObjects[2:5]...)
+		changed.Add(updated)
+		changed.Add(segmentObjects[6:]...)
+		sameIndexes(t, i, changed)
+		if i.Generation() <= generation {
+			t.Errorf("Generation() = %d, want more than %d", i.Generation(), generation)
+		}
+	})
+	t.Run("read-only", func(t *testing.T) {
+		s.Add(segmentObjects[7])
+		s.Remove(segmentObjects[2].GetLink())
+		if s.Generation() != generation {
+			t.Errorf("Generation() after Add() = %d, want %d", s.Generation(), generation)
+		}
+		sameIndexes(t, s, want)
+	})
+	t.Run("indexes of the snapshot", func(t *testing.T) {
+		// NOTE(marius): the indexes of a snapshot can still be modified directly, without changing the index.
+		added := textObject("https://example.com/new", "Free software")
+		for _, in := range s.Indexes {
+			in.Add(added)
+			if r, ok := in.(Removable); ok {
+				r.Remove(HashFn(segmentObjects[3]))
+			}
+		}
+		if Indexed(i.Indexes[ByID]).Contains(HashFn(added)) {
+			t.Errorf("Add() on the snapshot indexes changed the index")
+		}
+		if !Indexed(i.Indexes[ByType]).Contains(HashFn(segmentObjects[3])) {
+			t.Errorf("Remove() on the snapshot indexes changed the index")
+		}
+		if len(i.Search("software")) != 1 {
+			t.Errorf("Remove() on the snapshot indexes changed the text index")
+		}
+	})
+}
+
+func TestIndex_Snapshot_refs(t *testing.T) {
+	defer func(fn HashFnType) { HashFn = fn }(HashFn)
+	HashFn = collidingHash
+
+	i := Partial(ByID)
+	i.Add(vocab.IRI("https://example.com/1"), vocab.IRI("https://example.com/2"), vocab.IRI("https://example.com/3"))
+	ref := HashFn(vocab.IRI("https://example.com/1"))
+
+	s := i.Snapshot()
+	i.Remove("https://example.com/3")
+	i.Add(vocab.IRI("https://example.com/4"), vocab.IRI("https://example.com/10"))
+
+	want := vocab.IRIs{"https://example.com/1", "https://example.com/2", "https://example.com/3"}
+	if got := s.IRIs(ref); !reflect.DeepEqual(got, want) {
+		t.Errorf("IRIs() of snapshot = %v, want %v", got, want)
+	}
+	if got := s.IRIs(HashFn(vocab.IRI("https://example.com/10"))); len(got) > 0 {
+		t.Errorf("IRIs() of snapshot = %v, want none", got)
+	}
+	want = vocab.IRIs{"https://example.com/1", "https://example.com/2", "https://example.com/4"}
+	if got := i.IRIs(ref); !reflect.DeepEqual(got, want) {
+		t.Errorf("IRIs() = %v, want %v", got, want)
+	}
+}
+
+func TestIndex_Snapshot_segment(t *testing.T) {
+	in := Partial(extendedIndexTypes...)
+	in.Add(segmentObjects...)
+	seg := openSegment(t, writeSegment(t, in))
+
+	s := seg.Snapshot()
+	sameSegment(t, in, s)
+}
+
+func TestIndex_Snapshot_concurrent(t *testing.T) {
+	i := Partial(extendedIndexTypes...)
+	i.Add(searchObjects...)
+	s := i.Snapshot()
+	want := s.Search("fox")
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := range 100 {
+			i.Add(textObject(vocab.IRI(fmt.Sprintf("https://example.com/fox/%d", j)), "Yet another fox"))
+			if j%10 == 0 {
+				_ = i.Snapshot()
+			}
+		}
+	}()
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if got := s.Search("fox"); len(got) != len(want) {
+					t.Errorf("Search() on the snapshot = %d results, want %d", len(got), len(want))
+				}
+				s.View(func(indexes map[Type]Indexable) {
+					if got := Indexed(indexes[ByID]).GetCardinality(); got != uint64(len(searchObjects)) {
+						t.Errorf("Indexed() on the snapshot = %d documents, want %d", got, len(searchObjects))
+					}
+					_ = SubstringSearch(indexes[ByContentTrigram], "fox", nil)
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(i.Search("fox")); got != len(want)+100 {
+		t.Errorf("Search() = %d results, want %d", got, len(want)+100)
+	}
+}
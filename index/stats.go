@@ -0,0 +1,218 @@
+package index
+
+import "github.com/RoaringBitmap/roaring/roaring64"
+
+// Stats contains the statistics of an Index, which can be used for deciding between using the index
+// and scanning all the documents.
+type Stats struct {
+	// Documents is the number of documents in the index, including the ones whose references collide.
+	Documents uint64
+	// Collisions is the number of documents whose references collide with the ones of other documents.
+	Collisions uint64
+	// Types contains the statistics of each index type.
+	Types map[Type]TypeStats
+}
+
+// TypeStats contains the statistics of the index of a single Type.
+type TypeStats struct {
+	// Tokens is the number of distinct tokens, which are the terms of the ByText index, the trigrams
+	// of the trigram indexes, and the references of the ByID index.
+	Tokens uint64
+	// Documents is the number of documents having at least one token.
+	Documents uint64
+	// Postings is the sum of the number of documents of every token.
+	Postings uint64
+	// BitmapBytes is the size of the token bitmaps.
+	BitmapBytes uint64
+	// Bytes is the approximate memory footprint of the index, including the bitmaps, the tokens,
+	// and the forward maps. For the segments, it is the size of the mapped sections.
+	Bytes uint64
+}
+
+// statser is implemented by the indexes that can return their statistics.
+type statser interface {
+	stats() TypeStats
+}
+
+// Stats returns the statistics of the index.
+// The index types that don't support statistics, like the custom Indexable implementations, are missing from Types.
+func (i *Index) Stats() Stats {
+	i.w.RLock()
+	defer i.w.RUnlock()
+
+	s := Stats{Types: make(map[Type]TypeStats, len(i.Indexes))}
+	s.Documents = uint64(len(i.Ref))
+	for _, iris := range i.Collisions {
+		s.Collisions += uint64(len(iris))
+	}
+	s.Documents += s.Collisions
+	if i.table != nil {
+		// NOTE(marius): for the segments we count the references, without decoding their IRIs,
+		// so the colliding documents are not counted.
+		s.Documents += uint64(i.table.len())
+	}
+	for typ, in := range i.Indexes {
+		if st, ok := in.(statser); ok {
+			s.Types[typ] = st.stats()
+		}
+	}
+	return s
+}
+
+// Selectivity returns the fraction of the documents of the index that have at least one token of typ,
+// or 0 if the index doesn't contain the statistics of typ.
+func (s Stats) Selectivity(typ Type) float64 {
+	ts, ok := s.Types[typ]
+	if !ok || s.Documents == 0 {
+		return 0
+	}
+	return float64(ts.Documents) / float64(s.Documents)
+}
+
+// cardinality is implemented by the indexes that can return the number of documents of a token,
+// without copying its bitmap.
+type cardinality[T Tokenizable] interface {
+	cardinality(key T) uint64
+}
+
+// Cardinality returns the number of documents of the in index containing any of the tokens,
+// which gives the selectivity of the tokens.
+func Cardinality[T Tokenizable](in Indexable, tokens ...T) uint64 {
+	if c, ok := in.(cardinality[T]); ok && len(tokens) == 1 {
+		return c.cardinality(tokens[0])
+	}
+	return roaring64.FastOr(GetBitmaps(in, tokens...)...).GetCardinality()
+}
+
+func (i *tokenMap[T]) cardinality(key T) uint64 {
+	i.w.RLock()
+	defer i.w.RUnlock()
+	if b, ok := i.m[key]; ok {
+		return b.GetCardinality()
+	}
+	return 0
+}
+
+func (i *tokenMap[T]) stats() TypeStats {
+	i.w.RLock()
+	defer i.w.RUnlock()
+
+	s := TypeStats{Tokens: uint64(len(i.m))}
+	docs := roaring64.New()
+	for tok, b := range i.m {
+		docs.Or(b)
+		s.Postings += b.GetCardinality()
+		s.BitmapBytes += b.GetSizeInBytes()
+		s.Bytes += tokenSize(tok)
+	}
+	for _, tokens := range i.docs {
+		s.Bytes += 8
+		for _, tok := range tokens {
+			s.Bytes += tokenSize(tok)
+		}
+	}
+	s.Documents = docs.GetCardinality()
+	s.Bytes += s.BitmapBytes
+	return s
+}
+
+// tokenSize returns the number of bytes of the tok token.
+func tokenSize[T Tokenizable](tok T) uint64 {
+	switch any(tok).(type) {
+	case uint32:
+		return 4
+	case uint64:
+		return 8
+	}
+	return uint64(len(encodeKey(tok)))
+}
+
+func (f *full) stats() TypeStats {
+	f.w.RLock()
+	defer f.w.RUnlock()
+
+	n := f.bmp.GetCardinality()
+	size := f.bmp.GetSizeInBytes()
+	return TypeStats{Tokens: n, Documents: n, Postings: n, BitmapBytes: size, Bytes: size}
+}
+
+func (t *timeIndex) stats() TypeStats {
+	t.w.RLock()
+	defer t.w.RUnlock()
+
+	times := make(map[uint64]struct{}, len(t.m))
+	for _, tm := range t.m {
+		times[tm] = struct{}{}
+	}
+	n := uint64(len(t.m))
+	return TypeStats{Tokens: uint64(len(times)), Documents: n, Postings: n, Bytes: 16 * n}
+}
+
+func (t *trigramIndex) stats() TypeStats {
+	t.w.RLock()
+	defer t.w.RUnlock()
+
+	s := TypeStats{Tokens: uint64(len(t.m)), Documents: uint64(len(t.values))}
+	for tri, b := range t.m {
+		s.Postings += b.GetCardinality()
+		s.BitmapBytes += b.GetSizeInBytes()
+		s.Bytes += uint64(len(tri))
+	}
+	s.BitmapBytes += t.unpruned.GetSizeInBytes()
+	for _, values := range t.values {
+		s.Bytes += 8
+		for _, nlv := range values {
+			for _, v := range nlv {
+				s.Bytes += uint64(len(v))
+			}
+		}
+	}
+	s.Bytes += s.BitmapBytes
+	return s
+}
+
+func (t *textIndex) stats() TypeStats {
+	t.w.RLock()
+	defer t.w.RUnlock()
+
+	s := TypeStats{Tokens: uint64(len(t.postings)), Documents: uint64(len(t.lengths))}
+	for term, b := range t.postings {
+		s.Postings += b.GetCardinality()
+		s.BitmapBytes += b.GetSizeInBytes()
+		s.Bytes += uint64(len(term))
+	}
+	for _, docs := range t.positions {
+		for _, positions := range docs {
+			s.Bytes += 8 + 4*uint64(len(positions))
+		}
+	}
+	for _, terms := range t.terms {
+		s.Bytes += 8 + 4
+		for _, term := range terms {
+			s.Bytes += uint64(len(term))
+		}
+	}
+	s.Bytes += s.BitmapBytes
+	return s
+}
+
+func (s *segmentTokens[T]) cardinality(key T) uint64 {
+	if j, ok := s.find(key); ok {
+		return s.bitmap(j).GetCardinality()
+	}
+	return 0
+}
+
+func (s *segmentTokens[T]) stats() TypeStats {
+	st := TypeStats{Tokens: uint64(s.len())}
+	docs := roaring64.New()
+	for j := 0; j < s.len(); j++ {
+		b := s.bitmap(j)
+		docs.Or(b)
+		st.Postings += b.GetCardinality()
+		st.BitmapBytes += uint64(len(s.slice(j, 16)))
+	}
+	st.Documents = docs.GetCardinality()
+	st.Bytes = uint64(len(s.entries) + len(s.blob))
+	return st
+}
@@ -0,0 +1,123 @@
+package index
+
+import (
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestIndex_Stats(t *testing.T) {
+	i := Partial(ByID, ByType, ByRecipients, ByText, ByNameTrigram)
+	i.Add(searchObjects...)
+
+	got := i.Stats()
+	if got.Documents != uint64(len(searchObjects)) {
+		t.Errorf("Stats() documents = %d, want %d", got.Documents, len(searchObjects))
+	}
+	if len(got.Types) != len(i.Indexes) {
+		t.Errorf("Stats() types = %d, want %d", len(got.Types), len(i.Indexes))
+	}
+
+	tests := []struct {
+		typ  Type
+		want TypeStats
+	}{
+		{typ: ByID, want: TypeStats{Tokens: 5, Documents: 5, Postings: 5}},
+		{typ: ByType, want: TypeStats{Tokens: 1, Documents: 4, Postings: 4}},
+		{typ: ByRecipients, want: TypeStats{}},
+		{typ: ByNameTrigram, want: TypeStats{Tokens: 3, Documents: 1, Postings: 3}},
+		{typ: ByText, want: TypeStats{Tokens: 14, Documents: 5, Postings: 20}},
+	}
+	for _, tt := range tests {
+		t.Run(typeName(tt.typ), func(t *testing.T) {
+			ts := got.Types[tt.typ]
+			if ts.Tokens != tt.want.Tokens || ts.Documents != tt.want.Documents || ts.Postings != tt.want.Postings {
+				t.Errorf("Stats() = %+v, want %+v", ts, tt.want)
+			}
+			if ts.Tokens > 0 && (ts.BitmapBytes == 0 || ts.Bytes < ts.BitmapBytes) {
+				t.Errorf("Stats() sizes = %d bitmap bytes, %d bytes", ts.BitmapBytes, ts.Bytes)
+			}
+		})
+	}
+
+	if sel := got.Selectivity(ByType); sel != 0.8 {
+		t.Errorf("Selectivity(ByType) = %f, want 0.8", sel)
+	}
+	if sel := got.Selectivity(ByName); sel != 0 {
+		t.Errorf("Selectivity(ByName) = %f, want 0", sel)
+	}
+}
+
+func typeName(typ Type) string {
+	return map[Type]string{ByID: "id", ByType: "type", ByRecipients: "recipients", ByNameTrigram: "name trigram", ByText: "text"}[typ]
+}
+
+func TestIndex_Stats_collisions(t *testing.T) {
+	defer func(fn HashFnType) { HashFn = fn }(HashFn)
+	HashFn = collidingHash
+
+	i := Partial(ByID)
+	i.Add(vocab.IRI("https://example.com/1"), vocab.IRI("https://example.com/2"), vocab.IRI("https://example.com/10"))
+	got := i.Stats()
+	if got.Documents != 3 || got.Collisions != 1 {
+		t.Errorf("Stats() = %d documents, %d collisions, want 3 and 1", got.Documents, got.Collisions)
+	}
+}
+
+func TestIndex_Stats_segment(t *testing.T) {
+	// NOTE(marius): adding a document again without removing it keeps its previous trigrams, which the segment
+	// doesn't contain, so we use only the objects with distinct IDs.
+	in := Partial(extendedIndexTypes...)
+	in.Add(searchObjects...)
+	in.Add(trigramObjects[1])
+	s := openSegment(t, writeSegment(t, in))
+
+	want, got := in.Stats(), s.Stats()
+	if got.Documents != want.Documents {
+		t.Errorf("Stats() documents = %d, want %d", got.Documents, want.Documents)
+	}
+	for typ, w := range want.Types {
+		g := got.Types[typ]
+		if g.Tokens != w.Tokens || g.Documents != w.Documents || g.Postings != w.Postings {
+			t.Errorf("Stats() of %d = %+v, want %+v", typ, g, w)
+		}
+	}
+}
+
+func TestCardinality(t *testing.T) {
+	i := Partial(extendedIndexTypes...)
+	i.Add(segmentObjects...)
+	s := openSegment(t, writeSegment(t, i))
+
+	tests := []struct {
+		name   string
+		typ    Type
+		tokens []string
+		want   uint64
+	}{
+		{name: "none", typ: ByType, want: 0},
+		{name: "missing", typ: ByType, tokens: []string{"missing"}, want: 0},
+		{name: "one", typ: ByType, tokens: []string{string(vocab.NoteType)}, want: 4},
+		{name: "many", typ: ByText, tokens: []string{"fox", "software"}, want: 4},
+		{name: "text", typ: ByText, tokens: []string{"fox"}, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, in := range map[string]*Index{"heap": i, "segment": s.Index} {
+				if got := Cardinality(in.Indexes[tt.typ], tt.tokens...); got != tt.want {
+					t.Errorf("Cardinality() on %s = %d, want %d", name, got, tt.want)
+				}
+			}
+		})
+	}
+	if got := Cardinality(i.Indexes[ByID], HashFn(segmentObjects[0])); got != 1 {
+		t.Errorf("Cardinality(ByID) = %d, want 1", got)
+	}
+	// NOTE(marius): the snapshots don't contain the forward maps, so only their sizes differ.
+	ignoreBytes := cmpopts.IgnoreFields(TypeStats{}, "Bytes")
+	if want, got := i.Stats().Types[ByType], i.Snapshot().Stats().Types[ByType]; !cmp.Equal(got, want, ignoreBytes) {
+		t.Errorf("Stats() of the snapshot = %s", cmp.Diff(want, got, ignoreBytes))
+	}
+}
@@ -0,0 +1,66 @@
+package index
+
+import (
+	"slices"
+	"strings"
+
+	vocab "github.com/go-ap/activitypub"
+)
+
+const (
+	// HashtagType and MentionType are the types that Mastodon uses for the tag items.
+	HashtagType vocab.ActivityVocabularyType = "Hashtag"
+	MentionType vocab.ActivityVocabularyType = "Mention"
+)
+
+// TagsOfType returns the items of the "tag" property of the [vocab.Item] that have the typ type.
+func TagsOfType(li vocab.LinkOrIRI, typ vocab.ActivityVocabularyType) vocab.ItemCollection {
+	it, ok := li.(vocab.Item)
+	if !ok || vocab.IsNil(it) {
+		return nil
+	}
+	result := make(vocab.ItemCollection, 0)
+	_ = vocab.OnObject(it, func(ob *vocab.Object) error {
+		return vocab.OnItem(ob.Tag, func(tag vocab.Item) error {
+			if t := tag.GetType(); t != nil && slices.Contains(t.AsTypes(), typ) {
+				result = append(result, tag)
+			}
+			return nil
+		})
+	})
+	return result
+}
+
+// TagName returns the name of the tag item, which can be either a [vocab.Link], or a [vocab.Object].
+func TagName(tag vocab.Item) vocab.NaturalLanguageValues {
+	var name vocab.NaturalLanguageValues
+	if err := vocab.OnLink(tag, func(l *vocab.Link) error {
+		name = l.Name
+		return nil
+	}); err == nil {
+		return name
+	}
+	_ = vocab.OnObject(tag, func(ob *vocab.Object) error {
+		name = ob.Name
+		return nil
+	})
+	return name
+}
+
+// TagHref returns the IRI the tag item points to: the href of the [vocab.Link] tags, and the ID of the other ones.
+func TagHref(tag vocab.Item) vocab.IRI {
+	var href vocab.IRI
+	if err := vocab.OnLink(tag, func(l *vocab.Link) error {
+		href = l.Href
+		return nil
+	}); err == nil {
+		return href
+	}
+	return tag.GetLink()
+}
+
+// NormalizeHashtag returns the hashtag name without the leading "#", and case folded,
+// which is how the ByHashtag index stores the names.
+func NormalizeHashtag(name string) string {
+	return Fold(strings.TrimPrefix(strings.TrimSpace(name), "#"), CaseFold)
+}
@@ -0,0 +1,139 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTagsOfType(t *testing.T) {
+	hashtag := &vocab.Link{Type: HashtagType, Href: "https://example.com/tags/golang", Name: vocab.DefaultNaturalLanguage("#GoLang")}
+	mention := &vocab.Object{ID: "https://example.com/~alice", Type: MentionType}
+	ob := &vocab.Object{ID: "https://example.com/1", Tag: vocab.ItemCollection{hashtag, mention}}
+
+	tests := []struct {
+		name string
+		li   vocab.LinkOrIRI
+		typ  vocab.ActivityVocabularyType
+		want vocab.ItemCollection
+	}{
+		{
+			name: "nil",
+			typ:  HashtagType,
+		},
+		{
+			name: "IRI",
+			li:   vocab.IRI("https://example.com/1"),
+			typ:  HashtagType,
+			want: vocab.ItemCollection{},
+		},
+		{
+			name: "hashtags",
+			li:   ob,
+			typ:  HashtagType,
+			want: vocab.ItemCollection{hashtag},
+		},
+		{
+			name: "mentions",
+			li:   ob,
+			typ:  MentionType,
+			want: vocab.ItemCollection{mention},
+		},
+		{
+			name: "none",
+			li:   ob,
+			typ:  "Emoji",
+			want: vocab.ItemCollection{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TagsOfType(tt.li, tt.typ); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TagsOfType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagName(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  vocab.Item
+		want vocab.NaturalLanguageValues
+	}{
+		{
+			name: "link",
+			tag:  &vocab.Link{Type: HashtagType, Name: vocab.DefaultNaturalLanguage("#GoLang")},
+			want: vocab.DefaultNaturalLanguage("#GoLang"),
+		},
+		{
+			name: "object",
+			tag:  &vocab.Object{Type: HashtagType, Name: vocab.DefaultNaturalLanguage("#rust")},
+			want: vocab.DefaultNaturalLanguage("#rust"),
+		},
+		{
+			name: "IRI",
+			tag:  vocab.IRI("https://example.com/tags/go"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TagName(tt.tag); !cmp.Equal(got, tt.want) {
+				t.Errorf("TagName() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestTagHref(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  vocab.Item
+		want vocab.IRI
+	}{
+		{
+			name: "link",
+			tag:  &vocab.Link{Type: MentionType, Href: "https://example.com/~jdoe"},
+			want: "https://example.com/~jdoe",
+		},
+		{
+			name: "object",
+			tag:  &vocab.Object{ID: "https://example.com/~alice", Type: MentionType},
+			want: "https://example.com/~alice",
+		},
+		{
+			name: "IRI",
+			tag:  vocab.IRI("https://example.com/~bob"),
+			want: "https://example.com/~bob",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TagHref(tt.tag); got != tt.want {
+				t.Errorf("TagHref() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "", want: ""},
+		{name: "#", want: ""},
+		{name: "golang", want: "golang"},
+		{name: " #GoLang ", want: "golang"},
+		{name: "#Café", want: "café"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeHashtag(tt.name); got != tt.want {
+				t.Errorf("NormalizeHashtag(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package index
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// TextOptions configures how PlainText converts the HTML markup of the natural language values to text.
+// The options are flags that can be combined, eg: TextVisibleOnly|TextDecodeEntities.
+type TextOptions uint8
+
+const (
+	// TextVisibleOnly skips the contents of the elements that don't get rendered: head, template,
+	// the ones with the "hidden" or aria-hidden="true" attributes, and the ones with the "invisible" class
+	// that Mastodon uses for shortening the displayed URLs.
+	TextVisibleOnly TextOptions = 1 << iota
+	// TextIgnoreHashtags skips the hashtag links, the anchors with rel="tag" or with the "hashtag" class.
+	TextIgnoreHashtags
+	// TextIgnoreMentions skips the mention links, the anchors with the "mention" class
+	// and the elements with the "h-card" class that wrap them.
+	TextIgnoreMentions
+	// TextDecodeEntities decodes the HTML character references, eg: "&amp;" to "&".
+	TextDecodeEntities
+)
+
+// DefaultTextOptions are the options used for the "content" and "summary" properties
+// by the filters checks and by the index extractors.
+const DefaultTextOptions = TextVisibleOnly | TextDecodeEntities
+
+// PlainText returns the text of the HTML in s, without the markup.
+// The block level elements and the line breaks are replaced with whitespace,
+// and the consecutive whitespace characters are collapsed to a single space,
+// so text split across inline elements, like "foo <b>bar</b>", results in "foo bar".
+//
+// The contents of the script and style elements are always skipped, regardless of the opts.
+// The parser is lenient and doesn't validate the markup: a "<" that doesn't start a tag is kept as text,
+// and unclosed elements end with the input.
+func PlainText(s string, opts TextOptions) string {
+	if !strings.ContainsAny(s, "<&") {
+		return collapseSpaces(s)
+	}
+
+	b := strings.Builder{}
+	b.Grow(len(s))
+
+	// NOTE(marius): open keeps the names of the elements whose content is skipped, so we can find
+	// where they end even when they contain other elements with the same name.
+	open := make([]string, 0)
+	for len(s) > 0 {
+		lt := strings.IndexByte(s, '<')
+		if lt < 0 {
+			lt = len(s)
+		}
+		if len(open) == 0 {
+			text := s[:lt]
+			if opts&TextDecodeEntities == TextDecodeEntities {
+				text = html.UnescapeString(text)
+			}
+			b.WriteString(text)
+		}
+		s = s[lt:]
+		if len(s) == 0 {
+			break
+		}
+
+		if strings.HasPrefix(s, "<!--") {
+			end := strings.Index(s[4:], "-->")
+			if end < 0 {
+				break
+			}
+			s = s[4+end+3:]
+			continue
+		}
+
+		t, rest, ok := parseTag(s)
+		if !ok {
+			// NOTE(marius): not a tag, we keep the "<" as text.
+			if len(open) == 0 {
+				b.WriteByte('<')
+			}
+			s = s[1:]
+			continue
+		}
+		s = rest
+
+		if t.closing {
+			if n := len(open); n > 0 && open[n-1] == t.name {
+				open = open[:n-1]
+			}
+			if len(open) == 0 && blockElements[t.name] {
+				b.WriteByte(' ')
+			}
+			continue
+		}
+		if rawTextElements[t.name] {
+			// NOTE(marius): the contents of script and style elements are not HTML, so we skip
+			// everything until their closing tag.
+			end := strings.Index(strings.ToLower(s), "</"+t.name)
+			if end < 0 {
+				break
+			}
+			s = s[end:]
+			if gt := strings.IndexByte(s, '>'); gt >= 0 {
+				s = s[gt+1:]
+			}
+			continue
+		}
+		if len(open) == 0 && blockElements[t.name] {
+			b.WriteByte(' ')
+		}
+		if t.selfClosing || voidElements[t.name] {
+			continue
+		}
+		if len(open) > 0 {
+			if open[0] == t.name {
+				open = append(open, t.name)
+			}
+			continue
+		}
+		if t.skip(opts) {
+			open = append(open, t.name)
+		}
+	}
+	return collapseSpaces(b.String())
+}
+
+type tag struct {
+	name        string
+	closing     bool
+	selfClosing bool
+	attrs       map[string]string
+}
+
+// skip returns if the contents of the element need to be skipped according to the opts.
+func (t tag) skip(opts TextOptions) bool {
+	classes := strings.Fields(t.attrs["class"])
+	hasClass := func(name string) bool {
+		for _, c := range classes {
+			if strings.EqualFold(c, name) {
+				return true
+			}
+		}
+		return false
+	}
+	if opts&TextVisibleOnly == TextVisibleOnly {
+		if t.name == "template" || t.name == "head" {
+			return true
+		}
+		if _, hidden := t.attrs["hidden"]; hidden {
+			return true
+		}
+		if strings.EqualFold(t.attrs["aria-hidden"], "true") || hasClass("invisible") {
+			return true
+		}
+	}
+	isHashtag := t.name == "a" && (hasClass("hashtag") || strings.EqualFold(t.attrs["rel"], "tag"))
+	if opts&TextIgnoreHashtags == TextIgnoreHashtags && isHashtag {
+		return true
+	}
+	if opts&TextIgnoreMentions == TextIgnoreMentions && !isHashtag {
+		if (t.name == "a" && hasClass("mention")) || hasClass("h-card") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTag parses the start or end tag at the beginning of s, and returns the remainder of s after it.
+// It returns false if s doesn't start with a tag.
+func parseTag(s string) (tag, string, bool) {
+	t := tag{}
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		t.closing = true
+		i++
+	}
+	if i >= len(s) || !isASCIILetter(s[i]) {
+		if !t.closing && i < len(s) && (s[i] == '!' || s[i] == '?') {
+			// NOTE(marius): doctype declarations and processing instructions.
+			if gt := strings.IndexByte(s, '>'); gt >= 0 {
+				return tag{}, s[gt+1:], true
+			}
+		}
+		return t, s, false
+	}
+	start := i
+	for i < len(s) && !isTagSpace(s[i]) && s[i] != '/' && s[i] != '>' {
+		i++
+	}
+	t.name = strings.ToLower(s[start:i])
+
+	for i < len(s) {
+		for i < len(s) && isTagSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		switch s[i] {
+		case '>':
+			return t, s[i+1:], true
+		case '/':
+			t.selfClosing = true
+			i++
+			continue
+		}
+		t.selfClosing = false
+
+		start = i
+		for i < len(s) && !isTagSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		name := strings.ToLower(s[start:i])
+		for i < len(s) && isTagSpace(s[i]) {
+			i++
+		}
+		value := ""
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isTagSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				q := s[i]
+				end := strings.IndexByte(s[i+1:], q)
+				if end < 0 {
+					return t, "", true
+				}
+				value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start = i
+				for i < len(s) && !isTagSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				value = s[start:i]
+			}
+		}
+		if t.attrs == nil {
+			t.attrs = make(map[string]string)
+		}
+		t.attrs[name] = html.UnescapeString(value)
+	}
+	// NOTE(marius): the tag is not closed, so it extends to the end of the input.
+	return t, "", true
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isTagSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.FieldsFunc(s, unicode.IsSpace), " ")
+}
+
+var rawTextElements = map[string]bool{
+	"script": true, "style": true,
+}
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true, "dd": true,
+	"details": true, "div": true, "dl": true, "dt": true, "figcaption": true, "figure": true,
+	"footer": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "li": true, "main": true, "nav": true, "ol": true, "p": true,
+	"pre": true, "section": true, "summary": true, "table": true, "td": true, "th": true,
+	"tr": true, "ul": true,
+}
@@ -0,0 +1,125 @@
+package index
+
+import "testing"
+
+func TestPlainText(t *testing.T) {
+	const mastodonPost = `<p>Hello <span class="h-card"><a href="https://example.com/@jdoe" class="u-url mention">@<span>jdoe</span></a></span>, ` +
+		`see <a href="https://example.com/very/long/path"><span class="invisible">https://</span><span class="ellipsis">example.com/very</span><span class="invisible">/long/path</span></a> ` +
+		`<a href="https://example.com/tags/golang" class="mention hashtag" rel="tag">#<span>golang</span></a></p>`
+	tests := []struct {
+		name string
+		html string
+		opts TextOptions
+		want string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "plain text",
+			html: "lorem  ipsum\n dolor",
+			opts: DefaultTextOptions,
+			want: "lorem ipsum dolor",
+		},
+		{
+			name: "inline elements",
+			html: "foo <b>bar</b>",
+			opts: DefaultTextOptions,
+			want: "foo bar",
+		},
+		{
+			name: "inline elements inside words",
+			html: "f<strong>oo</strong>",
+			opts: DefaultTextOptions,
+			want: "foo",
+		},
+		{
+			name: "block elements",
+			html: "<p>foo</p><p>bar<br>baz</p>",
+			opts: DefaultTextOptions,
+			want: "foo bar baz",
+		},
+		{
+			name: "attributes containing markup",
+			html: `<a title="a > b" href='/test'>foo</a>`,
+			opts: DefaultTextOptions,
+			want: "foo",
+		},
+		{
+			name: "comments",
+			html: "foo<!-- <b>bar</b> -->baz",
+			opts: DefaultTextOptions,
+			want: "foobaz",
+		},
+		{
+			name: "script and style",
+			html: "<style>p { color: red; }</style>foo<script>if (a < b) {}</script>",
+			want: "foo",
+		},
+		{
+			name: "entities are decoded",
+			html: "<p>fish &amp; chips &lt;3</p>",
+			opts: TextDecodeEntities,
+			want: "fish & chips <3",
+		},
+		{
+			name: "entities are kept",
+			html: "<p>fish &amp; chips</p>",
+			want: "fish &amp; chips",
+		},
+		{
+			name: "less than sign is kept",
+			html: "1 < 2",
+			opts: DefaultTextOptions,
+			want: "1 < 2",
+		},
+		{
+			name: "hidden elements",
+			html: `foo <span hidden>bar</span><span aria-hidden="true">baz</span>`,
+			opts: TextVisibleOnly,
+			want: "foo",
+		},
+		{
+			name: "hidden elements are kept",
+			html: `foo <span hidden>bar</span>`,
+			want: "foo bar",
+		},
+		{
+			name: "nested hidden elements",
+			html: `<div hidden><div>foo</div>bar</div>baz`,
+			opts: TextVisibleOnly,
+			want: "baz",
+		},
+		{
+			name: "unclosed element",
+			html: "<p>foo <b>bar",
+			opts: DefaultTextOptions,
+			want: "foo bar",
+		},
+		{
+			name: "mastodon post",
+			html: mastodonPost,
+			opts: DefaultTextOptions,
+			want: "Hello @jdoe, see example.com/very #golang",
+		},
+		{
+			name: "mastodon post without hashtags",
+			html: mastodonPost,
+			opts: DefaultTextOptions | TextIgnoreHashtags,
+			want: "Hello @jdoe, see example.com/very",
+		},
+		{
+			name: "mastodon post without mentions",
+			html: mastodonPost,
+			opts: DefaultTextOptions | TextIgnoreMentions,
+			want: "Hello , see example.com/very #golang",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlainText(tt.html, tt.opts); got != tt.want {
+				t.Errorf("PlainText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
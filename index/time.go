@@ -0,0 +1,170 @@
+package index
+
+import (
+	"encoding/binary"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	vocab "github.com/go-ap/activitypub"
+)
+
+// timeIndex is the index containing the time of every document, which is used for the ByPublished
+// and ByUpdated indexes.
+// Unlike the token indexes, it is keyed by the references of the documents, so they can be removed,
+// and written in deltas and segments, like the documents of the other indexes.
+type timeIndex struct {
+	w sync.RWMutex
+	m map[uint64]uint64
+	// shared is set when the map is shared with a snapshot of the index.
+	shared    bool
+	extractFn ExtractFnType[uint64]
+}
+
+// NewTimeIndex initializes a new index of the times returned by extractFn for the documents,
+// of which only the first one is kept.
+func NewTimeIndex(extractFn ExtractFnType[uint64]) Indexable {
+	return &timeIndex{m: make(map[uint64]uint64), extractFn: extractFn}
+}
+
+func (t *timeIndex) Add(li vocab.LinkOrIRI) uint64 {
+	ref := HashFn(li)
+	if ref == 0 || t.extractFn == nil {
+		return ref
+	}
+	times := t.extractFn(li)
+	if len(times) == 0 {
+		return ref
+	}
+
+	t.w.Lock()
+	defer t.w.Unlock()
+	t.mutable()[ref] = times[0]
+	return ref
+}
+
+// mutable returns the map for modifying it, copying it first if it's shared with a snapshot.
+func (t *timeIndex) mutable() map[uint64]uint64 {
+	if t.shared {
+		t.m = maps.Clone(t.m)
+		t.shared = false
+	}
+	if t.m == nil {
+		t.m = make(map[uint64]uint64)
+	}
+	return t.m
+}
+
+// Remove removes the time of the ref document.
+func (t *timeIndex) Remove(ref uint64) {
+	t.w.Lock()
+	defer t.w.Unlock()
+	if _, ok := t.m[ref]; ok {
+		delete(t.mutable(), ref)
+	}
+}
+
+// time returns the time of the ref document, if it has one.
+func (t *timeIndex) time(ref uint64) (uint64, bool) {
+	t.w.RLock()
+	defer t.w.RUnlock()
+	tm, ok := t.m[ref]
+	return tm, ok
+}
+
+// get returns the bitmap containing the time of the ref document.
+func (t *timeIndex) get(ref uint64) *roaring64.Bitmap {
+	b := roaring64.New()
+	if tm, ok := t.time(ref); ok {
+		b.Add(tm)
+	}
+	return b
+}
+
+// not returns the bitmap containing the times of the documents other than ref.
+func (t *timeIndex) not(ref uint64) *roaring64.Bitmap {
+	t.w.RLock()
+	defer t.w.RUnlock()
+	b := roaring64.New()
+	for r, tm := range t.m {
+		if r == ref {
+			continue
+		}
+		b.Add(tm)
+	}
+	return b
+}
+
+// indexed returns the documents that have a time.
+func (t *timeIndex) indexed() *roaring64.Bitmap {
+	t.w.RLock()
+	defer t.w.RUnlock()
+	b := roaring64.New()
+	for ref := range t.m {
+		b.Add(ref)
+	}
+	return b
+}
+
+// encodeDocument encodes the time of the ref document, as a list which is empty if it doesn't have one.
+func (t *timeIndex) encodeDocument(ref uint64) ([]byte, error) {
+	t.w.RLock()
+	defer t.w.RUnlock()
+	tm, ok := t.m[ref]
+	if !ok {
+		return binary.AppendUvarint(nil, 0), nil
+	}
+	b := binary.AppendUvarint(nil, 1)
+	return binary.BigEndian.AppendUint64(b, tm), nil
+}
+
+func (t *timeIndex) decodeDocument(ref uint64, data []byte) error {
+	r := reader{b: data}
+	count := r.uvarint()
+	times := make([]uint64, 0, min(count, uint64(len(r.b)/8)))
+	for j := uint64(0); j < count && r.err == nil; j++ {
+		times = append(times, r.uint64())
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if len(times) == 0 {
+		return nil
+	}
+
+	t.w.Lock()
+	defer t.w.Unlock()
+	t.mutable()[ref] = times[0]
+	return nil
+}
+
+func (t *timeIndex) MarshalBinary() ([]byte, error) {
+	t.w.RLock()
+	defer t.w.RUnlock()
+	b := binary.AppendUvarint(nil, uint64(len(t.m)))
+	for _, ref := range slices.Sorted(maps.Keys(t.m)) {
+		b = binary.BigEndian.AppendUint64(b, ref)
+		b = binary.BigEndian.AppendUint64(b, t.m[ref])
+	}
+	return b, nil
+}
+
+func (t *timeIndex) UnmarshalBinary(data []byte) error {
+	r := reader{b: data}
+	count := r.uvarint()
+	m := make(map[uint64]uint64, min(count, uint64(len(r.b)/16)))
+	for j := uint64(0); j < count && r.err == nil; j++ {
+		ref := r.uint64()
+		m[ref] = r.uint64()
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	t.w.Lock()
+	defer t.w.Unlock()
+	t.m = m
+	t.shared = false
+	return nil
+}
@@ -0,0 +1,100 @@
+package index
+
+import (
+	"testing"
+	"time"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTimeIndex(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ob := timedObject("https://example.com/1", now.Add(-48*time.Hour), time.Time{})
+	other := timedObject("https://example.com/2", now.Add(-24*time.Hour), time.Time{})
+	ref := HashFn(ob)
+
+	published := func(in *Index, ref uint64) []uint64 {
+		return GetBitmaps(in.Indexes[ByPublished], ref)[0].ToArray()
+	}
+
+	tests := []struct {
+		name string
+		fn   func(in *Index)
+		want []uint64
+	}{
+		{
+			name: "add",
+			fn:   func(in *Index) {},
+			want: ExtractPublishedTime(ob),
+		},
+		{
+			name: "remove",
+			fn:   func(in *Index) { in.Remove(ob.ID) },
+			want: []uint64{},
+		},
+		{
+			name: "update",
+			fn: func(in *Index) {
+				in.Update(ob, timedObject(ob.ID, now, time.Time{}))
+			},
+			want: ExtractPublishedTime(timedObject(ob.ID, now, time.Time{})),
+		},
+		{
+			name: "update without time",
+			fn: func(in *Index) {
+				in.Update(ob, timedObject(ob.ID, time.Time{}, time.Time{}))
+			},
+			want: []uint64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := Partial(ByID, ByPublished)
+			in.Add(ob, other)
+			tt.fn(in)
+			if got := published(in, ref); !cmp.Equal(got, tt.want) {
+				t.Errorf("ByPublished = %s", cmp.Diff(tt.want, got))
+			}
+			if got, want := published(in, HashFn(other)), ExtractPublishedTime(other); !cmp.Equal(got, want) {
+				t.Errorf("ByPublished of other document = %s", cmp.Diff(want, got))
+			}
+		})
+	}
+}
+
+func TestTimeIndex_snapshot(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ob := timedObject("https://example.com/1", now.Add(-48*time.Hour), time.Time{})
+
+	in := Partial(ByID, ByPublished)
+	in.Add(ob)
+	s := in.Snapshot()
+	in.Update(ob, timedObject(ob.ID, now, time.Time{}))
+
+	want := ExtractPublishedTime(ob)
+	if got := GetBitmaps(s.Indexes[ByPublished], HashFn(ob))[0].ToArray(); !cmp.Equal(got, want) {
+		t.Errorf("ByPublished of snapshot = %s", cmp.Diff(want, got))
+	}
+}
+
+func TestTimeIndex_compact(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	objects := []vocab.LinkOrIRI{
+		timedObject("https://example.com/old", now.Add(-48*time.Hour), time.Time{}),
+		timedObject("https://example.com/new", now.Add(-24*time.Hour), time.Time{}),
+	}
+
+	older := Partial(ByID, ByPublished)
+	older.Add(objects...)
+	newer := Partial(ByID, ByPublished)
+	// NOTE(marius): the new document gets backdated, so its old time must not be kept by the compaction.
+	newer.Update(objects[1], timedObject("https://example.com/new", now.Add(-72*time.Hour), time.Time{}))
+
+	s := openSegment(t, writeSegment(t, older, newer))
+	got := []uint64{HashFn(objects[1]), HashFn(objects[0])}
+	SortByTime(s.Indexes, got)
+	if want := []uint64{HashFn(objects[0]), HashFn(objects[1])}; !cmp.Equal(got, want) {
+		t.Errorf("SortByTime() on compacted segment = %s", cmp.Diff(want, got))
+	}
+}
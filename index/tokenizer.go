@@ -0,0 +1,98 @@
+package index
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/clipperhouse/uax29/v2/words"
+	vocab "github.com/go-ap/activitypub"
+	"golang.org/x/text/language"
+)
+
+// Tokenizer splits the text of a natural language value into the tokens that get indexed.
+// The lang parameter is the language of the value, or [language.Und] if it doesn't have one.
+type Tokenizer interface {
+	Tokenize(text string, lang language.Tag) []string
+}
+
+// TokenizerFunc is an adapter that allows using ordinary functions as a Tokenizer.
+type TokenizerFunc func(text string, lang language.Tag) []string
+
+func (f TokenizerFunc) Tokenize(text string, lang language.Tag) []string {
+	return f(text, lang)
+}
+
+// TextTokenizer is a Tokenizer for the HTML values of the "summary" and "content" properties.
+// It converts the HTML to text, splits it at the Unicode word boundaries (UAX #29), skips the segments
+// that don't contain letters or digits, like punctuation and whitespace, and folds the remaining words.
+//
+// Unlike a tokenizer based on word length, it keeps the short words, eg: "Go" or "art",
+// and the single character words of the Chinese and Japanese texts.
+type TextTokenizer struct {
+	// Text holds the options for converting the HTML to text.
+	Text TextOptions
+	// Collation holds the folding applied to the words, which needs to match the one used for the searches.
+	Collation Collation
+	// Stopwords returns if the folded word is a stopword in the lang language, in which case it's skipped.
+	// If it's nil, all the words are indexed.
+	Stopwords func(word string, lang language.Tag) bool
+}
+
+// DefaultTokenizer is the Tokenizer used for the "summary" and "content" properties.
+// It uses the DefaultTextOptions, the DefaultCollation, and it doesn't skip any stopwords, so any word
+// of the values can be found using the natural language values checks.
+var DefaultTokenizer Tokenizer = TextTokenizer{Text: DefaultTextOptions, Collation: DefaultCollation}
+
+func (t TextTokenizer) Tokenize(text string, lang language.Tag) []string {
+	result := make([]string, 0)
+	for _, w := range textToWords(PlainText(text, t.Text)) {
+		w = Fold(w, t.Collation)
+		if t.Stopwords != nil && t.Stopwords(w, lang) {
+			continue
+		}
+		result = append(result, w)
+	}
+	return result
+}
+
+// textToWords splits the text at the Unicode word boundaries, and returns the segments that contain
+// letters or digits.
+func textToWords(text string) []string {
+	result := make([]string, 0)
+	seg := words.FromString(text)
+	for seg.Next() {
+		if w := seg.Value(); strings.ContainsFunc(w, isWordRune) {
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// IsStopword returns if the word is one of the most common words of the lang language, which are not useful
+// for searching. The built-in lists cover English, French, German and Spanish, and the [language.Und]
+// values are treated as English. The word needs to be folded using the DefaultCollation.
+//
+// It can be used as the Stopwords function of a TextTokenizer.
+func IsStopword(word string, lang language.Tag) bool {
+	_, ok := stopwords[analysisLanguage(lang)][word]
+	return ok
+}
+
+// tokenizeNatLangVal extracts multiple tokens from the value of the [vocab.NaturalLanguageValues] value,
+// using the tok Tokenizer.
+// This is meant for the properties that can contain long texts like "summary" or "content".
+func tokenizeNatLangVal(nlv vocab.NaturalLanguageValues, tok Tokenizer) []string {
+	if nlv == nil || contentHasDataURI(nlv) || tok == nil {
+		return nil
+	}
+
+	result := make([]string, 0)
+	for lang, cc := range nlv {
+		result = append(result, tok.Tokenize(cc.String(), language.Tag(lang))...)
+	}
+	return result
+}
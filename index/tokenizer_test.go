@@ -0,0 +1,115 @@
+package index
+
+import (
+	"strings"
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/text/language"
+)
+
+func TestTextTokenizer_Tokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		tok  TextTokenizer
+		text string
+		lang language.Tag
+		want []string
+	}{
+		{
+			name: "empty",
+			tok:  DefaultTokenizer.(TextTokenizer),
+			want: []string{},
+		},
+		{
+			name: "default",
+			tok:  DefaultTokenizer.(TextTokenizer),
+			text: "<p>The <b>Go</b> art of WAR</p>",
+			want: []string{"the", "go", "art", "of", "war"},
+		},
+		{
+			name: "entities not decoded",
+			tok:  TextTokenizer{Collation: DefaultCollation},
+			text: "<p>Tom &amp; Jerry</p>",
+			want: []string{"tom", "amp", "jerry"},
+		},
+		{
+			name: "exact collation",
+			tok:  TextTokenizer{Text: DefaultTextOptions},
+			text: "Crème Brûlée",
+			want: []string{"Crème", "Brûlée"},
+		},
+		{
+			name: "english stopwords",
+			tok:  TextTokenizer{Text: DefaultTextOptions, Collation: DefaultCollation, Stopwords: IsStopword},
+			text: "The art of war",
+			lang: language.English,
+			want: []string{"art", "war"},
+		},
+		{
+			name: "french stopwords",
+			tok:  TextTokenizer{Text: DefaultTextOptions, Collation: DefaultCollation, Stopwords: IsStopword},
+			text: "L'art de la guerre",
+			lang: language.French,
+			want: []string{"l'art", "guerre"},
+		},
+		{
+			name: "language without stopwords",
+			tok:  TextTokenizer{Text: DefaultTextOptions, Collation: DefaultCollation, Stopwords: IsStopword},
+			text: "the art",
+			lang: language.Japanese,
+			want: []string{"the", "art"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.tok.Tokenize(tt.text, tt.lang)
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("Tokenize() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestIsStopword(t *testing.T) {
+	tests := []struct {
+		word string
+		lang language.Tag
+		want bool
+	}{
+		{word: "the", lang: language.Und, want: true},
+		{word: "the", lang: language.English, want: true},
+		{word: "the", lang: language.AmericanEnglish, want: true},
+		{word: "the", lang: language.French, want: false},
+		{word: "fur", lang: language.German, want: true},
+		{word: "art", lang: language.English, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang.String()+"/"+tt.word, func(t *testing.T) {
+			if got := IsStopword(tt.word, tt.lang); got != tt.want {
+				t.Errorf("IsStopword(%q, %s) = %t, want %t", tt.word, tt.lang, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPartialWithTokenizer(t *testing.T) {
+	upper := TokenizerFunc(func(text string, _ language.Tag) []string {
+		return []string{strings.ToUpper(text)}
+	})
+	i := PartialWithTokenizer(upper, ByContent, BySummary)
+	i.Add(&vocab.Object{
+		ID:      "https://example.com/1",
+		Content: nlv(kv(vocab.NilLangRef, vocab.Content("lorem ipsum"))),
+		Summary: nlv(kv(vocab.NilLangRef, vocab.Content("dolor"))),
+	})
+
+	ref := HashFn(vocab.IRI("https://example.com/1"))
+	for typ, tok := range map[Type]string{ByContent: "LOREM IPSUM", BySummary: "DOLOR"} {
+		bmps := GetBitmaps[string](i.Indexes[typ], tok)
+		if len(bmps) != 1 || !bmps[0].Contains(ref) {
+			t.Errorf("GetBitmaps(%d, %q) doesn't contain %d", typ, tok, ref)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	vocab "github.com/go-ap/activitypub"
+	"golang.org/x/text/language"
+)
+
+// trigramSize is the length, in runes, of the n-grams used by the trigram indexes.
+const trigramSize = 3
+
+// trigramIndex is an index used for substring searches in the natural language values.
+// It keeps the bitmaps of the documents containing each trigram of the folded values,
+// and the values themselves, which are used for verifying the candidates resulting from the trigrams.
+type trigramIndex struct {
+	w sync.RWMutex
+	m map[string]*roaring64.Bitmap
+	// values contains the indexed values for every document.
+	values map[uint64][]vocab.NaturalLanguageValues
+	// unpruned contains the documents whose trigrams are not indexed, because their values contain data URIs,
+	// so they are candidates for every search.
+	unpruned *roaring64.Bitmap
+	// shared contains the trigrams whose bitmaps are shared with the snapshots of the index.
+	shared    cow[string]
+	extractFn ExtractTextFnType
+}
+
+// NewTrigramIndex initializes a trigram index, for the natural language values returned by extractFn.
+// The trigrams are extracted from the values folded using the DefaultCollation, both as they are,
+// and converted to plain text using the DefaultTextOptions.
+func NewTrigramIndex(extractFn ExtractTextFnType) Indexable {
+	return &trigramIndex{
+		m:         make(map[string]*roaring64.Bitmap),
+		values:    make(map[uint64][]vocab.NaturalLanguageValues),
+		unpruned:  roaring64.New(),
+		extractFn: extractFn,
+	}
+}
+
+// ExtractNameValues returns the "name" and the "preferredUsername" properties of the [vocab.LinkOrIRI].
+// Like the natural language values checks, the name searches look into both of them.
+func ExtractNameValues(li vocab.LinkOrIRI) []vocab.NaturalLanguageValues {
+	result := make([]vocab.NaturalLanguageValues, 0, 2)
+	switch it := li.(type) {
+	case vocab.Link:
+		result = append(result, it.Name)
+	case *vocab.Link:
+		result = append(result, it.Name)
+	case vocab.Item:
+		_ = vocab.OnObject(it, func(ob *vocab.Object) error {
+			result = append(result, ob.Name)
+			return nil
+		})
+		_ = vocab.OnActor(it, func(act *vocab.Actor) error {
+			result = append(result, act.PreferredUsername)
+			return nil
+		})
+	}
+	return result
+}
+
+// ExtractContentValues returns the "content" property of the [vocab.LinkOrIRI].
+func ExtractContentValues(li vocab.LinkOrIRI) []vocab.NaturalLanguageValues {
+	result := make([]vocab.NaturalLanguageValues, 0, 1)
+	if it, ok := li.(vocab.Item); ok {
+		_ = vocab.OnObject(it, func(ob *vocab.Object) error {
+			result = append(result, ob.Content)
+			return nil
+		})
+	}
+	return result
+}
+
+// ExtractIDValues returns the ID of the [vocab.LinkOrIRI], as a natural language value.
+func ExtractIDValues(li vocab.LinkOrIRI) []vocab.NaturalLanguageValues {
+	if li == nil {
+		return nil
+	}
+	return iriValues(li.GetLink())
+}
+
+// ExtractAttributedToValues returns the IRIs of the "attributedTo" property of the [vocab.LinkOrIRI],
+// as natural language values.
+func ExtractAttributedToValues(li vocab.LinkOrIRI) []vocab.NaturalLanguageValues {
+	it, ok := li.(vocab.Item)
+	if !ok {
+		return nil
+	}
+	var iris []vocab.IRI
+	_ = vocab.OnObject(it, func(ob *vocab.Object) error {
+		iris = derefObject(ob.AttributedTo)
+		return nil
+	})
+	return iriValues(iris...)
+}
+
+// ExtractInReplyToValues returns the IRIs of the "inReplyTo" property of the [vocab.LinkOrIRI],
+// as natural language values.
+func ExtractInReplyToValues(li vocab.LinkOrIRI) []vocab.NaturalLanguageValues {
+	it, ok := li.(vocab.Item)
+	if !ok {
+		return nil
+	}
+	var iris []vocab.IRI
+	_ = vocab.OnObject(it, func(ob *vocab.Object) error {
+		iris = derefObject(ob.InReplyTo)
+		return nil
+	})
+	return iriValues(iris...)
+}
+
+// iriValues converts the non-empty IRIs to natural language values in the default language.
+// NOTE(marius): this allows us to use the trigram indexes for the substring searches in the IRI properties.
+func iriValues(iris ...vocab.IRI) []vocab.NaturalLanguageValues {
+	result := make([]vocab.NaturalLanguageValues, 0, len(iris))
+	for _, iri := range iris {
+		if iri == "" {
+			continue
+		}
+		result = append(result, vocab.DefaultNaturalLanguage(iri.String()))
+	}
+	return result
+}
+
+func (t *trigramIndex) Add(li vocab.LinkOrIRI) uint64 {
+	ref := HashFn(li)
+	if ref == 0 || t.extractFn == nil {
+		return ref
+	}
+	values := make([]vocab.NaturalLanguageValues, 0)
+	for _, nlv := range t.extractFn(li) {
+		if len(nlv) > 0 {
+			values = append(values, nlv)
+		}
+	}
+	if len(values) == 0 {
+		return ref
+	}
+
+	t.w.Lock()
+	defer t.w.Unlock()
+
+	t.add(ref, values)
+	return ref
+}
+
+func (t *trigramIndex) add(ref uint64, values []vocab.NaturalLanguageValues) {
+	t.values[ref] = values
+	for _, nlv := range values {
+		if contentHasDataURI(nlv) {
+			t.unpruned.Add(ref)
+			continue
+		}
+		for _, v := range nlv {
+			for _, s := range []string{v.String(), PlainText(v.String(), DefaultTextOptions)} {
+				for _, tri := range trigrams(Fold(s, DefaultCollation)) {
+					t.mutable(tri).Add(ref)
+				}
+			}
+		}
+	}
+}
+
+// mutable returns the bitmap of the tri trigram for modifying it, creating it if it doesn't exist,
+// or copying it if it's shared with a snapshot.
+func (t *trigramIndex) mutable(tri string) *roaring64.Bitmap {
+	b, ok := t.m[tri]
+	switch {
+	case !ok:
+		b = roaring64.New()
+		t.m[tri] = b
+		t.shared.own(tri)
+	case t.shared.own(tri):
+		b = b.Clone()
+		t.m[tri] = b
+	}
+	return b
+}
+
+// Remove removes the ref document from the bitmaps of the trigrams of its values.
+func (t *trigramIndex) Remove(ref uint64) {
+	t.w.Lock()
+	defer t.w.Unlock()
+
+	for _, nlv := range t.values[ref] {
+		for _, v := range nlv {
+			for _, s := range []string{v.String(), PlainText(v.String(), DefaultTextOptions)} {
+				for _, tri := range trigrams(Fold(s, DefaultCollation)) {
+					if _, ok := t.m[tri]; !ok {
+						continue
+					}
+					b := t.mutable(tri)
+					b.Remove(ref)
+					if b.IsEmpty() {
+						delete(t.m, tri)
+					}
+				}
+			}
+		}
+	}
+	t.unpruned.Remove(ref)
+	delete(t.values, ref)
+}
+
+// trigrams returns the distinct sequences of trigramSize runes contained in s.
+func trigrams(s string) []string {
+	result := make([]string, 0)
+	seen := make(map[string]struct{})
+	for i := 0; i < len(s); {
+		end := i
+		n := 0
+		for n < trigramSize && end < len(s) {
+			_, size := utf8.DecodeRuneInString(s[end:])
+			end += size
+			n++
+		}
+		if n < trigramSize {
+			break
+		}
+		if _, ok := seen[s[i:end]]; !ok {
+			seen[s[i:end]] = struct{}{}
+			result = append(result, s[i:end])
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return result
+}
+
+// candidates returns the documents that can contain the fragment, which are the ones containing all of its trigrams.
+// For fragments shorter than a trigram, all the documents are candidates.
+func (t *trigramIndex) candidates(fragment string) *roaring64.Bitmap {
+	tris := trigrams(Fold(fragment, DefaultCollation))
+	if len(tris) == 0 {
+		all := roaring64.New()
+		for ref := range t.values {
+			all.Add(ref)
+		}
+		return all
+	}
+	result := roaring64.New()
+	for i, tri := range tris {
+		b, ok := t.m[tri]
+		if !ok {
+			result.Clear()
+			break
+		}
+		if i == 0 {
+			result.Or(b)
+		} else {
+			result.And(b)
+		}
+	}
+	result.Or(t.unpruned)
+	return result
+}
+
+// SubstringSearch returns the documents of the in trigram index that can contain the fragment,
+// and whose values satisfy the verifyFn function.
+// An empty fragment doesn't restrict the candidates, so all the documents with values get verified.
+//
+// It returns nil if in is not a trigram index.
+func SubstringSearch(in Indexable, fragment string, verifyFn func([]vocab.NaturalLanguageValues) bool) *roaring64.Bitmap {
+	t, ok := in.(*trigramIndex)
+	if !ok {
+		return nil
+	}
+
+	t.w.RLock()
+	defer t.w.RUnlock()
+
+	candidates := t.candidates(fragment)
+	if verifyFn == nil {
+		return candidates
+	}
+	result := roaring64.New()
+	it := candidates.Iterator()
+	for it.HasNext() {
+		ref := it.Next()
+		if verifyFn(t.values[ref]) {
+			result.Add(ref)
+		}
+	}
+	return result
+}
+
+// get returns the documents containing the folded key as a substring of their values,
+// either as they are, or converted to plain text using the DefaultTextOptions.
+func (t *trigramIndex) get(key string) *roaring64.Bitmap {
+	needle := Fold(key, DefaultCollation)
+	return SubstringSearch(t, key, func(values []vocab.NaturalLanguageValues) bool {
+		for _, nlv := range values {
+			for _, v := range nlv {
+				if strings.Contains(Fold(v.String(), DefaultCollation), needle) {
+					return true
+				}
+				if strings.Contains(Fold(PlainText(v.String(), DefaultTextOptions), DefaultCollation), needle) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// not returns the documents that don't contain the folded key as a substring of their values.
+func (t *trigramIndex) not(key string) *roaring64.Bitmap {
+	all := t.indexed()
+	all.AndNot(t.get(key))
+	return all
+}
+
+// indexed returns the documents that have values in the index.
+func (t *trigramIndex) indexed() *roaring64.Bitmap {
+	t.w.RLock()
+	defer t.w.RUnlock()
+	b := roaring64.New()
+	for ref := range t.values {
+		b.Add(ref)
+	}
+	return b
+}
+
+// bareTrigramIndex is the serialization format of the trigramIndex.
+// The values are keyed by the string form of their language tags, and the trigrams are rebuilt
+// from the values when decoding.
+type bareTrigramIndex struct {
+	Values map[uint64][]map[string][]byte
+}
+
+func (t *trigramIndex) MarshalBinary() ([]byte, error) {
+	t.w.RLock()
+	defer t.w.RUnlock()
+
+	b := bareTrigramIndex{Values: make(map[uint64][]map[string][]byte, len(t.values))}
+	for ref, values := range t.values {
+		b.Values[ref] = encodeValues(values)
+	}
+	buff := bytes.Buffer{}
+	err := gob.NewEncoder(&buff).Encode(b)
+	return buff.Bytes(), err
+}
+
+func (t *trigramIndex) UnmarshalBinary(data []byte) error {
+	b := bareTrigramIndex{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&b); err != nil {
+		return err
+	}
+
+	t.w.Lock()
+	defer t.w.Unlock()
+
+	t.m = make(map[string]*roaring64.Bitmap)
+	t.values = make(map[uint64][]vocab.NaturalLanguageValues, len(b.Values))
+	t.unpruned = roaring64.New()
+	t.shared = cow[string]{}
+	for ref, values := range b.Values {
+		t.add(ref, decodeValues(values))
+	}
+	return nil
+}
+
+// encodeValues converts the natural language values to maps keyed by the string form of their language tags,
+// which can be gob encoded.
+func encodeValues(values []vocab.NaturalLanguageValues) []map[string][]byte {
+	result := make([]map[string][]byte, 0, len(values))
+	for _, nlv := range values {
+		m := make(map[string][]byte, len(nlv))
+		for lang, v := range nlv {
+			m[language.Tag(lang).String()] = v
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+// decodeValues converts the result of encodeValues back to natural language values.
+func decodeValues(values []map[string][]byte) []vocab.NaturalLanguageValues {
+	result := make([]vocab.NaturalLanguageValues, 0, len(values))
+	for _, m := range values {
+		nlv := make(vocab.NaturalLanguageValues, len(m))
+		for lang, v := range m {
+			tag, _ := language.Parse(lang)
+			nlv[vocab.LangRef(tag)] = v
+		}
+		result = append(result, nlv)
+	}
+	return result
+}
+
+// encodeDocument encodes the natural language values of the ref document, the languages of each of them
+// being sorted by their tags. See the "values" payload of the Index binary format.
+func (t *trigramIndex) encodeDocument(ref uint64) ([]byte, error) {
+	t.w.RLock()
+	defer t.w.RUnlock()
+	values := encodeValues(t.values[ref])
+	b := binary.AppendUvarint(nil, uint64(len(values)))
+	for _, m := range values {
+		b = binary.AppendUvarint(b, uint64(len(m)))
+		for _, lang := range slices.Sorted(maps.Keys(m)) {
+			b = appendBytes(b, []byte(lang))
+			b = appendBytes(b, m[lang])
+		}
+	}
+	return b, nil
+}
+
+func (t *trigramIndex) decodeDocument(ref uint64, data []byte) error {
+	r := reader{b: data}
+	count := r.uvarint()
+	values := make([]map[string][]byte, 0, min(count, uint64(len(r.b))))
+	for j := uint64(0); j < count && r.err == nil; j++ {
+		n := r.uvarint()
+		m := make(map[string][]byte, min(n, uint64(len(r.b))))
+		for k := uint64(0); k < n && r.err == nil; k++ {
+			lang := string(r.chunk())
+			m[lang] = bytes.Clone(r.chunk())
+		}
+		values = append(values, m)
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	t.w.Lock()
+	defer t.w.Unlock()
+	t.add(ref, decodeValues(values))
+	return nil
+}
@@ -0,0 +1,295 @@
+package index
+
+import (
+	"strings"
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_trigrams(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{
+			name: "empty",
+			want: []string{},
+		},
+		{
+			name: "shorter than a trigram",
+			s:    "ab",
+			want: []string{},
+		},
+		{
+			name: "one trigram",
+			s:    "abc",
+			want: []string{"abc"},
+		},
+		{
+			name: "distinct",
+			s:    "aaaa b",
+			want: []string{"aaa", "aa ", "a b"},
+		},
+		{
+			name: "multi byte runes",
+			s:    "日本語だ",
+			want: []string{"日本語", "本語だ"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trigrams(tt.s); !cmp.Equal(got, tt.want) {
+				t.Errorf("trigrams() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+var trigramObjects = []vocab.LinkOrIRI{
+	&vocab.Object{
+		ID:      "https://example.com/1",
+		Name:    nlv(kv(vocab.NilLangRef, vocab.Content("Crème brûlée"))),
+		Content: nlv(kv(vocab.NilLangRef, vocab.Content("<p>Tom &amp; Jerry</p>"))),
+	},
+	&vocab.Actor{
+		ID:                "https://example.com/~jdoe",
+		Name:              nlv(kv(vocab.NilLangRef, vocab.Content("John Doe"))),
+		PreferredUsername: nlv(kv(vocab.NilLangRef, vocab.Content("jdoe"))),
+	},
+	&vocab.Object{
+		ID:      "https://example.com/2",
+		Content: nlv(kv(vocab.NilLangRef, vocab.Content("data:image/png;base64,"+strings.Repeat("A", 64)))),
+	},
+}
+
+func Test_trigramIndex_get(t *testing.T) {
+	names := NewTrigramIndex(ExtractNameValues).(*trigramIndex)
+	contents := NewTrigramIndex(ExtractContentValues).(*trigramIndex)
+	for _, ob := range trigramObjects {
+		names.Add(ob)
+		contents.Add(ob)
+	}
+
+	tests := []struct {
+		name string
+		t    *trigramIndex
+		key  string
+		want []vocab.IRI
+	}{
+		{
+			name: "folded substring",
+			t:    names,
+			key:  "CREME BRU",
+			want: []vocab.IRI{"https://example.com/1"},
+		},
+		{
+			name: "preferred username",
+			t:    names,
+			key:  "jdo",
+			want: []vocab.IRI{"https://example.com/~jdoe"},
+		},
+		{
+			name: "shorter than a trigram",
+			t:    names,
+			key:  "oe",
+			want: []vocab.IRI{"https://example.com/~jdoe"},
+		},
+		{
+			name: "trigrams present, substring missing",
+			t:    names,
+			key:  "doe creme",
+			want: []vocab.IRI{},
+		},
+		{
+			name: "raw html",
+			t:    contents,
+			key:  "&amp;",
+			want: []vocab.IRI{"https://example.com/1"},
+		},
+		{
+			name: "plain text",
+			t:    contents,
+			key:  "tom & jerry",
+			want: []vocab.IRI{"https://example.com/1"},
+		},
+		{
+			name: "data URIs are verified, not pruned",
+			t:    contents,
+			key:  "base64",
+			want: []vocab.IRI{"https://example.com/2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.t.get(tt.key)
+			if got.GetCardinality() != uint64(len(tt.want)) {
+				t.Errorf("get(%q) = %v, want %v", tt.key, got.ToArray(), tt.want)
+			}
+			for _, iri := range tt.want {
+				if !got.Contains(HashFn(iri)) {
+					t.Errorf("get(%q) doesn't contain %s", tt.key, iri)
+				}
+			}
+		})
+	}
+}
+
+func TestSubstringSearch(t *testing.T) {
+	names := NewTrigramIndex(ExtractNameValues)
+	for _, ob := range trigramObjects {
+		names.Add(ob)
+	}
+
+	t.Run("not a trigram index", func(t *testing.T) {
+		if got := SubstringSearch(NewTokenIndex(ExtractName), "doe", nil); got != nil {
+			t.Errorf("SubstringSearch() = %v, want nil", got)
+		}
+	})
+	t.Run("candidates without verification", func(t *testing.T) {
+		got := SubstringSearch(names, "doe creme", nil)
+		if got.GetCardinality() != 0 {
+			t.Errorf("SubstringSearch() = %v, want empty", got.ToArray())
+		}
+		got = SubstringSearch(names, "creme", nil)
+		if !got.Contains(HashFn(vocab.IRI("https://example.com/1"))) || got.GetCardinality() != 1 {
+			t.Errorf("SubstringSearch() = %v, want %d", got.ToArray(), HashFn(vocab.IRI("https://example.com/1")))
+		}
+	})
+	t.Run("verified", func(t *testing.T) {
+		got := SubstringSearch(names, "", func(values []vocab.NaturalLanguageValues) bool {
+			return len(values) == 2
+		})
+		if !got.Contains(HashFn(vocab.IRI("https://example.com/~jdoe"))) || got.GetCardinality() != 1 {
+			t.Errorf("SubstringSearch() = %v, want %d", got.ToArray(), HashFn(vocab.IRI("https://example.com/~jdoe")))
+		}
+	})
+}
+
+func Test_trigramIndex_MarshalBinary(t *testing.T) {
+	ti := NewTrigramIndex(ExtractNameValues).(*trigramIndex)
+	for _, ob := range trigramObjects {
+		ti.Add(ob)
+	}
+	data, err := ti.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	got := trigramIndex{}
+	if err = got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	for _, key := range []string{"creme", "jdoe", "oe", "missing"} {
+		if !got.get(key).Equals(ti.get(key)) {
+			t.Errorf("get(%q) after UnmarshalBinary() = %v, want %v", key, got.get(key).ToArray(), ti.get(key).ToArray())
+		}
+	}
+}
+
+func TestExtractAttributedToValues(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  vocab.LinkOrIRI
+		want []vocab.NaturalLanguageValues
+	}{
+		{
+			name: "empty",
+			arg:  nil,
+			want: nil,
+		},
+		{
+			name: "iri",
+			arg:  vocab.IRI("https://example.com"),
+			want: []vocab.NaturalLanguageValues{},
+		},
+		{
+			name: "no attributedTo",
+			arg:  &vocab.Object{ID: "https://example.com/1"},
+			want: []vocab.NaturalLanguageValues{},
+		},
+		{
+			name: "attributedTo",
+			arg: &vocab.Object{
+				ID:           "https://example.com/1",
+				AttributedTo: vocab.ItemCollection{vocab.IRI("https://example.com/~jdoe"), vocab.IRI("https://example.com/~alice")},
+			},
+			want: []vocab.NaturalLanguageValues{
+				vocab.DefaultNaturalLanguage("https://example.com/~jdoe"),
+				vocab.DefaultNaturalLanguage("https://example.com/~alice"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractAttributedToValues(tt.arg)
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("ExtractAttributedToValues() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestExtractIDValues(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  vocab.LinkOrIRI
+		want []vocab.NaturalLanguageValues
+	}{
+		{
+			name: "empty",
+			arg:  nil,
+			want: nil,
+		},
+		{
+			name: "iri",
+			arg:  vocab.IRI("https://example.com"),
+			want: []vocab.NaturalLanguageValues{vocab.DefaultNaturalLanguage("https://example.com")},
+		},
+		{
+			name: "object",
+			arg:  &vocab.Object{ID: "https://example.com/1", InReplyTo: vocab.IRI("https://example.com/2")},
+			want: []vocab.NaturalLanguageValues{vocab.DefaultNaturalLanguage("https://example.com/1")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractIDValues(tt.arg)
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("ExtractIDValues() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func Test_trigramIndex_Remove(t *testing.T) {
+	names := NewTrigramIndex(ExtractNameValues).(*trigramIndex)
+	for _, ob := range trigramObjects {
+		names.Add(ob)
+	}
+	removed := HashFn(trigramObjects[0])
+	names.Remove(removed)
+
+	if _, ok := names.values[removed]; ok {
+		t.Errorf("Remove() kept the values of the removed document")
+	}
+	for tri, b := range names.m {
+		if b.Contains(removed) {
+			t.Errorf("Remove() kept the removed document in the bitmap of %q", tri)
+		}
+	}
+	if !names.get("doe").Contains(HashFn(trigramObjects[1])) {
+		t.Errorf("Remove() removed the other documents")
+	}
+
+	contents := NewTrigramIndex(ExtractContentValues).(*trigramIndex)
+	for _, ob := range trigramObjects {
+		contents.Add(ob)
+	}
+	removed = HashFn(trigramObjects[2])
+	contents.Remove(removed)
+	if contents.unpruned.Contains(removed) {
+		t.Errorf("Remove() kept the removed document in the unpruned bitmap")
+	}
+}
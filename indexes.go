@@ -1,147 +1,651 @@
 package filters
 
 import (
+	"net/url"
+	"slices"
+	"strconv"
+
 	"github.com/RoaringBitmap/roaring/roaring64"
 	vocab "github.com/go-ap/activitypub"
 	"github.com/go-ap/filters/index"
+	"golang.org/x/text/language"
 )
 
-var hFn = index.HashFn
+// hFn returns the reference of the IRI, using the current index.LookupFn or index.HashFn, which can be replaced.
+func hFn(li vocab.LinkOrIRI) uint64 {
+	return index.LookupRef(li)
+}
 
-func extractBitmapsForSubprop(checks Checks, indexes map[index.Type]index.Indexable, typ index.Type) []*roaring64.Bitmap {
-	found := roaring64.FastAnd(extractBitmaps(checks, indexes)...)
-	if found.GetCardinality() == 0 {
-		return nil
+const (
+	ByID                  = index.ByID
+	ByType                = index.ByType
+	ByName                = index.ByName
+	ByPreferredUsername   = index.ByPreferredUsername
+	BySummary             = index.BySummary
+	ByContent             = index.ByContent
+	ByActor               = index.ByActor
+	ByObject              = index.ByObject
+	ByRecipients          = index.ByRecipients
+	ByAttributedTo        = index.ByAttributedTo
+	ByInReplyTo           = index.ByInReplyTo
+	ByText                = index.ByText
+	ByNameTrigram         = index.ByNameTrigram
+	ByContentTrigram      = index.ByContentTrigram
+	ByHashtag             = index.ByHashtag
+	ByMention             = index.ByMention
+	ByTag                 = index.ByTag
+	ByContext             = index.ByContext
+	ByURL                 = index.ByURL
+	ByTarget              = index.ByTarget
+	ByAudience            = index.ByAudience
+	ByIDTrigram           = index.ByIDTrigram
+	ByAttributedToTrigram = index.ByAttributedToTrigram
+	ByInReplyToTrigram    = index.ByInReplyToTrigram
+)
+
+// Scored represents a search result together with its relevance score.
+type Scored[T any] = index.Scored[T]
+
+// analyzer contains the Tokenizer, and its Collation, used by an index for the tokens of the summary and content
+// properties, which are used for tokenizing the values of the checks the same way.
+type analyzer struct {
+	tok  index.Tokenizer
+	coll Collation
+}
+
+// defaultAnalyzer corresponds to the indexes created using the index.DefaultTokenizer.
+var defaultAnalyzer = analyzer{tok: index.DefaultTokenizer, coll: index.DefaultCollation}
+
+// analyzerOf returns the analyzer of the summary and content tokens of the i index.
+func analyzerOf(i *index.Index) analyzer {
+	return analyzer{tok: i.Tokenizer(), coll: i.Collation()}
+}
+
+// resolveCheck returns the bitmap of the documents matching the check, using the indexes, and if the bitmap
+// contains exactly the matching documents. When it's not exact, the bitmap is a superset of the matching
+// documents, and they need to be verified using the in-memory check.
+// It returns a nil bitmap if the check can't be resolved using the indexes, so any document can match it.
+func resolveCheck(check Check, indexes map[index.Type]index.Indexable, an analyzer) (*roaring64.Bitmap, bool) {
+	switch fil := check.(type) {
+	case notCrit:
+		// NOTE(marius): the complement of a superset would be a subset of the matching documents,
+		// so we can resolve only the negations of the exact bitmaps.
+		toExclude, exact := resolveAll(Checks(fil), indexes, an)
+		if toExclude == nil || !exact {
+			return nil, false
+		}
+		return complement(toExclude, indexes)
+	case idEquals:
+		return roaring64.BitmapOf(hFn(vocab.IRI(fil))), true
+	case iriEquals:
+		return roaring64.BitmapOf(hFn(vocab.IRI(fil))), true
+	case idNil, iriNil:
+		// NOTE(marius): the documents without an ID are indexed under the hash of the empty IRI.
+		return lookup[uint64](indexes, ByID, hFn(vocab.EmptyIRI), hFn(vocab.NilIRI))
+	case itemNil:
+		if indexes[ByID] == nil {
+			return nil, false
+		}
+		return roaring64.New(), true
+	case idLike:
+		return likeBitmap(indexes, ByIDTrigram, string(fil), ExactCollation)
+	case iriLike:
+		return likeBitmap(indexes, ByIDTrigram, string(fil), ExactCollation)
+	case attributedToLike:
+		return likeBitmap(indexes, ByAttributedToTrigram, string(fil), ExactCollation)
+	case inReplyToLike:
+		return likeBitmap(indexes, ByInReplyToTrigram, string(fil), ExactCollation)
+	case collatedLike:
+		switch like := fil.like.(type) {
+		case idLike:
+			return likeBitmap(indexes, ByIDTrigram, string(like), fil.collation)
+		case iriLike:
+			return likeBitmap(indexes, ByIDTrigram, string(like), fil.collation)
+		case attributedToLike:
+			return likeBitmap(indexes, ByAttributedToTrigram, string(like), fil.collation)
+		case inReplyToLike:
+			return likeBitmap(indexes, ByInReplyToTrigram, string(like), fil.collation)
+		}
+	case attributedToNil:
+		return absent(indexes, ByAttributedTo)
+	case inReplyToNil:
+		return absent(indexes, ByInReplyTo)
+	case contextNil:
+		return absent(indexes, ByContext)
+	case checkAny:
+		if len(fil) == 0 {
+			return nil, false
+		}
+		ors := make([]*roaring64.Bitmap, 0, len(fil))
+		exact := true
+		for _, c := range fil {
+			// NOTE(marius): if any of the alternatives can't be resolved, any document can match.
+			bmp, ex := resolveCheck(c, indexes, an)
+			if bmp == nil {
+				return nil, false
+			}
+			ors = append(ors, bmp)
+			exact = exact && ex
+		}
+		return roaring64.FastOr(ors...), exact
+	case checkAll:
+		return resolveAll(Checks(fil), indexes, an)
+	case naturalLanguageValCheck:
+		return resolveNaturalLanguageVal(fil, indexes, an)
+	case withTypes:
+		if len(fil) == 0 {
+			return nil, false
+		}
+		types := make([]string, 0, len(fil))
+		for _, tf := range fil {
+			types = append(types, string(tf))
+		}
+		return lookup[string](indexes, ByType, types...)
+	case actorChecks:
+		return resolveSubprop(Checks(fil), indexes, an, ByActor)
+	case objectChecks:
+		return resolveSubprop(Checks(fil), indexes, an, ByObject)
+	case targetChecks:
+		return resolveSubprop(Checks(fil), indexes, an, ByTarget)
+	case tagChecks:
+		if len(fil) == 0 {
+			return nil, false
+		}
+		return resolveSubprop(Checks(fil), indexes, an, ByTag)
+	case propertyChecks:
+		// NOTE(marius): we can resolve only the IRI comparisons of the properties that have an index.
+		typ, ok := propertyIndexTypes[fil.path]
+		if !ok || !onlyIRIEquals(fil.fns) {
+			return nil, false
+		}
+		return resolveSubprop(fil.fns, indexes, an, typ)
+	case contextEquals:
+		return lookup[uint64](indexes, ByContext, hFn(vocab.IRI(fil)))
+	case urlEquals:
+		return lookup[uint64](indexes, ByURL, hFn(vocab.IRI(fil)))
+	case attributedToEquals:
+		return lookup[uint64](indexes, ByAttributedTo, hFn(vocab.IRI(fil)))
+	case inReplyToEquals:
+		return lookup[uint64](indexes, ByInReplyTo, hFn(vocab.IRI(fil)))
+	case public:
+		return lookup[uint64](indexes, ByRecipients, hFn(vocab.PublicNS))
+	case authorized:
+		return resolveAuthorized(vocab.IRI(fil), indexes)
+	case recipients:
+		return lookup[uint64](indexes, ByRecipients, hFn(vocab.IRI(fil)))
+	case hashtagCheck:
+		return lookup[string](indexes, ByHashtag, string(fil))
+	case mentionCheck:
+		return lookup[uint64](indexes, ByMention, hFn(vocab.IRI(fil)))
 	}
+	return resolveRegistered(check, indexes)
+}
 
-	iter := found.Iterator()
-	if iter == nil {
-		return nil
+// resolveAll returns the AND-ed bitmaps of the checks that can be resolved using the indexes,
+// and if the result is exact, which happens when all the checks were resolved exactly.
+// It returns a nil bitmap if none of the checks can be resolved.
+func resolveAll(checks Checks, indexes map[index.Type]index.Indexable, an analyzer) (*roaring64.Bitmap, bool) {
+	ands := make([]*roaring64.Bitmap, 0, len(checks))
+	exact := true
+	for _, c := range checks {
+		bmp, ex := resolveCheck(c, indexes, an)
+		if bmp == nil {
+			exact = false
+			continue
+		}
+		ands = append(ands, bmp)
+		exact = exact && ex
+	}
+	if len(ands) == 0 {
+		return nil, false
+	}
+	return roaring64.FastAnd(ands...), exact
+}
+
+// resolveSubprop resolves the checks applying on the items of a property, like the actor or the object
+// of the activities: it finds the items matching the checks, and returns the documents that reference them
+// in the typ index.
+// The result is exact only when the checks compare the IRIs of the items.
+func resolveSubprop(checks Checks, indexes map[index.Type]index.Indexable, an analyzer, typ index.Type) (*roaring64.Bitmap, bool) {
+	found, exact := resolveAll(checks, indexes, an)
+	if found == nil {
+		return nil, false
 	}
+	bmp, ok := lookup[uint64](indexes, typ, found.ToArray()...)
+	if !ok {
+		return nil, false
+	}
+	// NOTE(marius): only the items indexed as documents of their own can match the other checks, so for
+	// the embedded items, like the tags, or the actors that were not added to the index, the result would
+	// be missing the documents referencing them. It needs to be verified in memory, and it can't be negated.
+	return bmp, exact && onlyIRIEquals(checks)
+}
 
-	refs := make([]uint64, 0, found.GetCardinality())
-	for x := iter.PeekNext(); iter.HasNext(); x = iter.Next() {
-		refs = append(refs, x)
+// lookup returns the OR-ed bitmaps of the tokens in the typ index.
+// It returns a nil bitmap if the indexes don't contain the typ index.
+func lookup[T index.Tokenizable](indexes map[index.Type]index.Indexable, typ index.Type, tokens ...T) (*roaring64.Bitmap, bool) {
+	in, ok := indexes[typ]
+	if !ok || in == nil {
+		return nil, false
 	}
+	return roaring64.FastOr(index.GetBitmaps[T](in, tokens...)...), true
+}
 
-	return index.GetBitmaps[uint64](indexes[typ], refs...)
+// complement returns the documents of the ByID index which are not in bmp.
+func complement(bmp *roaring64.Bitmap, indexes map[index.Type]index.Indexable) (*roaring64.Bitmap, bool) {
+	all := index.Indexed(indexes[ByID])
+	if all == nil {
+		return nil, false
+	}
+	all.AndNot(bmp)
+	return all, true
 }
 
-const (
-	ByID                = index.ByID
-	ByType              = index.ByType
-	ByName              = index.ByName
-	ByPreferredUsername = index.ByPreferredUsername
-	BySummary           = index.BySummary
-	ByContent           = index.ByContent
-	ByActor             = index.ByActor
-	ByObject            = index.ByObject
-	ByRecipients        = index.ByRecipients
-	ByAttributedTo      = index.ByAttributedTo
-	ByInReplyTo         = index.ByInReplyTo
-)
+// absent returns the documents for which the property corresponding to the typ indexes is missing,
+// which is the complement of the documents present in any of them.
+func absent(indexes map[index.Type]index.Indexable, types ...index.Type) (*roaring64.Bitmap, bool) {
+	present := roaring64.New()
+	for _, typ := range types {
+		bmp := index.Indexed(indexes[typ])
+		if bmp == nil {
+			return nil, false
+		}
+		present.Or(bmp)
+	}
+	return complement(present, indexes)
+}
 
-func extractBitmaps(checks Checks, indexes map[index.Type]index.Indexable) []*roaring64.Bitmap {
-	result := make([]*roaring64.Bitmap, 0)
-	for _, check := range checks {
-		switch fil := check.(type) {
-		case notCrit:
-			toExclude := roaring64.FastOr(extractBitmaps(Checks(fil), indexes)...)
-			if toExclude.GetCardinality() == 0 {
-				continue
-			}
-			all := roaring64.FastOr(index.GetBitmaps[uint64](indexes[ByID])...)
-			if all.GetCardinality() > 0 {
-				all.AndNot(toExclude)
-				result = append(result, all)
-			}
-		case idEquals:
-			result = append(result, roaring64.BitmapOf(hFn(vocab.IRI(fil))))
-		case iriEquals:
-			result = append(result, roaring64.BitmapOf(hFn(vocab.IRI(fil))))
-		case checkAny:
-			anys := extractBitmaps(Checks(fil), indexes)
-			result = append(result, roaring64.FastOr(anys...))
-		case checkAll:
-			alls := extractBitmaps(Checks(fil), indexes)
-			result = append(result, roaring64.FastAnd(alls...))
-		case naturalLanguageValCheck:
-			switch fil.typ {
-			case byName:
-				// NOTE(marius): the naturalLanguageValChecks have this idiosyncrasy of doing name searches for
-				// both Name and PreferredUsername fields, so until we split them, we should use the same logic here.
-				ors := make([]*roaring64.Bitmap, 0)
-				ors = append(ors, index.GetBitmaps[string](indexes[ByName], fil.checkValue)...)
-				ors = append(ors, index.GetBitmaps[string](indexes[ByPreferredUsername], fil.checkValue)...)
-				if len(ors) > 0 {
-					result = append(result, roaring64.FastOr(ors...))
+// likeBitmap resolves the Like checks on the IRI properties using the typ trigram index.
+// The candidates are verified against the indexed IRIs, so the result is exact.
+func likeBitmap(indexes map[index.Type]index.Indexable, typ index.Type, frag string, coll Collation) (*roaring64.Bitmap, bool) {
+	fragment, _ := url.QueryUnescape(frag)
+	if coll&^index.DefaultCollation != 0 {
+		// NOTE(marius): the trigrams are folded using the index.DefaultCollation, so for collations
+		// that ignore more differences we can't use them for selecting the candidates.
+		fragment = ""
+	}
+	bmp := index.SubstringSearch(indexes[typ], fragment, func(values []vocab.NaturalLanguageValues) bool {
+		for _, nlv := range values {
+			for _, v := range nlv {
+				if collatedContains(v.String(), frag, coll) {
+					return true
 				}
-			case bySummary:
-				result = append(result, index.GetBitmaps[string](indexes[BySummary], fil.checkValue)...)
-			case byContent:
-				result = append(result, index.GetBitmaps[string](indexes[ByContent], fil.checkValue)...)
-			default:
 			}
-		case withTypes:
-			ors := make([]*roaring64.Bitmap, 0)
-			for _, tf := range fil {
-				ors = append(ors, index.GetBitmaps[string](indexes[ByType], string(tf))...)
+		}
+		return false
+	})
+	return bmp, bmp != nil
+}
+
+// resolveNaturalLanguageVal resolves the natural language values checks using the token and trigram indexes.
+func resolveNaturalLanguageVal(fil naturalLanguageValCheck, indexes map[index.Type]index.Indexable, an analyzer) (*roaring64.Bitmap, bool) {
+	switch fil.op {
+	case nlvLike:
+		bmp := substringBitmap(fil, indexes)
+		return bmp, bmp != nil
+	case nlvEmpty:
+		if fil.lang != language.Und {
+			return nil, false
+		}
+		switch fil.typ {
+		case byName:
+			// NOTE(marius): the naturalLanguageValChecks have this idiosyncrasy of doing name searches for
+			// both Name and PreferredUsername fields, so until we split them, we should use the same logic here.
+			return absent(indexes, ByName, ByPreferredUsername)
+		case byPreferredUsername:
+			return absent(indexes, ByPreferredUsername)
+		case byContent:
+			// NOTE(marius): the content tokens skip the values without words, so we use the trigram
+			// index, which keeps all the values.
+			return absent(indexes, ByContentTrigram)
+		}
+		return nil, false
+	}
+
+	val, _ := url.QueryUnescape(fil.checkValue)
+	switch fil.typ {
+	case byName, byPreferredUsername:
+		if fil.text != 0 || fil.collation&^index.DefaultCollation != 0 {
+			// NOTE(marius): the name tokens are folded using the index.DefaultCollation, so for collations
+			// that ignore more differences, the lookup would miss some of the matching documents.
+			return nil, false
+		}
+		// NOTE(marius): the index tokens are folded using the index.DefaultCollation,
+		// so we need to look up the folded value.
+		token := index.Fold(val, index.DefaultCollation)
+		types := []index.Type{ByPreferredUsername}
+		if fil.typ == byName {
+			types = append(types, ByName)
+		}
+		ors := make([]*roaring64.Bitmap, 0, len(types))
+		for _, typ := range types {
+			bmp, ok := lookup[string](indexes, typ, token)
+			if !ok {
+				return nil, false
 			}
-			if len(ors) > 0 {
-				result = append(result, roaring64.FastOr(ors...))
+			ors = append(ors, bmp)
+		}
+		exact := fil.lang == language.Und && fil.collation == index.DefaultCollation
+		return roaring64.FastOr(ors...), exact
+	case bySummary, byContent:
+		typ := BySummary
+		if fil.typ == byContent {
+			typ = ByContent
+		}
+		if fil.collation&^an.coll != 0 {
+			// NOTE(marius): the words are folded using the collation of the index tokenizer, so for
+			// collations that ignore more differences, the lookup would miss some of the matching documents.
+			return nil, false
+		}
+		// NOTE(marius): the summary and content are indexed as words, so we can only select the documents
+		// that contain all the words of the value, tokenized the same way, which need to be verified in memory.
+		words := an.tok.Tokenize(val, fil.lang)
+		if len(words) == 0 {
+			return nil, false
+		}
+		ands := make([]*roaring64.Bitmap, 0, len(words))
+		for _, w := range words {
+			bmp, ok := lookup[string](indexes, typ, w)
+			if !ok {
+				return nil, false
 			}
-		case actorChecks:
-			actorRefs := extractBitmapsForSubprop(Checks(fil), indexes, ByActor)
-			result = append(result, roaring64.FastOr(actorRefs...))
-		case objectChecks:
-			objectRefs := extractBitmapsForSubprop(Checks(fil), indexes, ByObject)
-			result = append(result, roaring64.FastOr(objectRefs...))
-		case attributedToEquals:
-			result = append(result, index.GetBitmaps[uint64](indexes[ByAttributedTo], hFn(vocab.IRI(fil)))...)
-		case inReplyToEquals:
-			result = append(result, index.GetBitmaps[uint64](indexes[ByInReplyTo], hFn(vocab.IRI(fil)))...)
-		case public:
-			result = append(result, index.GetBitmaps[uint64](indexes[ByRecipients], hFn(vocab.PublicNS))...)
-		case authorized:
-			bmps := index.GetBitmaps[uint64](indexes[ByRecipients], hFn(vocab.PublicNS), hFn(vocab.IRI(fil)))
-			result = append(result, roaring64.FastOr(bmps...))
-		case recipients:
-			result = append(result, index.GetBitmaps[uint64](indexes[ByRecipients], hFn(vocab.IRI(fil)))...)
-		}
-	}
-	return result
+			ands = append(ands, bmp)
+		}
+		return roaring64.FastAnd(ands...), false
+	}
+	return nil, false
 }
 
-func (ff Checks) IndexMatch(indexes map[index.Type]index.Indexable) *roaring64.Bitmap {
+// resolveAuthorized returns the documents that are authorized for the iri actor, the same as the authorized check:
+// the ones where it's a recipient, the attributedTo, or the actor, together with the public ones, and the activities
+// having it as object, except for the Block ones, for which it's the other way around.
+// The documents that don't have recipients, like the IRIs, are public for the in-memory check, and they are
+// indexed under the [vocab.PublicNS] IRI, see [index.ExtractRecipients], so they are returned too.
+func resolveAuthorized(iri vocab.IRI, indexes map[index.Type]index.Indexable) (*roaring64.Bitmap, bool) {
+	ors := make([]*roaring64.Bitmap, 0, 4)
+	for _, typ := range []index.Type{ByRecipients, ByAttributedTo, ByActor} {
+		toks := []uint64{hFn(iri)}
+		if typ == ByRecipients {
+			toks = append(toks, hFn(vocab.PublicNS))
+		}
+		bmp, ok := lookup[uint64](indexes, typ, toks...)
+		if !ok {
+			return nil, false
+		}
+		ors = append(ors, bmp)
+	}
+	objects, ok := lookup[uint64](indexes, ByObject, hFn(iri))
+	if !ok {
+		return nil, false
+	}
+	blocks, ok := lookup[string](indexes, ByType, string(vocab.BlockType))
+	if !ok {
+		return nil, false
+	}
+	// NOTE(marius): the Block activities are authorized when their object is not the actor, including when
+	// they don't have one, and the other activities when it is, which is the symmetric difference of the two.
+	ors = append(ors, roaring64.Xor(objects, blocks))
+	return roaring64.FastOr(ors...), true
+}
+
+// propertyIndexTypes contains the index types for the property checks paths that can be resolved using the indexes.
+var propertyIndexTypes = map[string]index.Type{
+	keyAttributedTo: ByAttributedTo,
+	keyInReplyTo:    ByInReplyTo,
+	keyContext:      ByContext,
+	keyURL:          ByURL,
+	keyTag:          ByTag,
+	keyTarget:       ByTarget,
+	"audience":      ByAudience,
+}
+
+// onlyIRIEquals returns if the checks are IRI or ID equality checks, or Any aggregates of them.
+func onlyIRIEquals(fns []Check) bool {
+	if len(fns) == 0 {
+		return false
+	}
+	for _, fn := range fns {
+		switch c := fn.(type) {
+		case iriEquals, idEquals:
+		case checkAny:
+			if !onlyIRIEquals(c) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// substringBitmap resolves the "like" natural language value checks using the trigram indexes:
+// the trigrams of the fragment select the candidates, which are then verified using the in-memory check
+// on the values stored by the index.
+// It returns nil if the check is not a "like" check, or if the indexes don't contain the corresponding trigram index.
+func substringBitmap(fil naturalLanguageValCheck, indexes map[index.Type]index.Indexable) *roaring64.Bitmap {
+	if fil.op != nlvLike {
+		return nil
+	}
+	var typ index.Type
+	switch fil.typ {
+	case byName:
+		typ = ByNameTrigram
+	case byContent:
+		typ = ByContentTrigram
+	default:
+		return nil
+	}
+	fragment, _ := url.QueryUnescape(fil.checkValue)
+	if fil.text != TextRaw && fil.text != index.DefaultTextOptions {
+		// NOTE(marius): the trigrams are extracted only from the raw values and from their default text
+		// conversion, so for other text options we can't use them for selecting the candidates.
+		fragment = ""
+	}
+	return index.SubstringSearch(indexes[typ], fragment, fil.matchValues)
+}
+
+// IndexResolve resolves the checks using the indexes, and returns the bitmap of the documents that can match them,
+// together with the residual checks, which couldn't be resolved exactly using the indexes,
+// and need to be verified in memory on the documents of the bitmap.
+// When there are no residual checks, the bitmap contains exactly the matching documents.
+//
+// The checks that can't be resolved don't restrict the bitmap, so when none of them can be resolved,
+// it contains all the documents of the ByID index.
+//
+// The values of the summary and content checks are tokenized using the [index.DefaultTokenizer], so for
+// the indexes created with a different Tokenizer, the functions receiving the [index.Index], like SearchIndex,
+// need to be used instead.
+func (ff Checks) IndexResolve(indexes map[index.Type]index.Indexable) (*roaring64.Bitmap, Checks) {
+	return ff.indexResolve(indexes, defaultAnalyzer)
+}
+
+// indexResolve resolves the checks like IndexResolve, tokenizing the natural language values of the
+// checks using the an analyzer.
+func (ff Checks) indexResolve(indexes map[index.Type]index.Indexable, an analyzer) (*roaring64.Bitmap, Checks) {
 	if len(ff) == 0 {
-		return roaring64.New()
+		return roaring64.New(), nil
 	}
 
 	// NOTE(marius): A normal list of Check functions in this package corresponds
 	// to a filter equivalent of All(Checks...).
 	// We can therefore use an AND operator for the bitmaps.
-	ands := extractBitmaps(ff, indexes)
-	return roaring64.FastAnd(ands...)
+	ands := make([]*roaring64.Bitmap, 0, len(ff))
+	residual := make(Checks, 0)
+	for _, c := range ff {
+		bmp, exact := resolveCheck(c, indexes, an)
+		if bmp != nil {
+			ands = append(ands, bmp)
+		}
+		if !exact {
+			residual = append(residual, c)
+		}
+	}
+	if len(ands) == 0 {
+		all := index.Indexed(indexes[ByID])
+		if all == nil {
+			all = roaring64.New()
+		}
+		return all, residual
+	}
+	return roaring64.FastAnd(ands...), residual
+}
+
+// IndexMatch returns the bitmap of the documents that can match the checks, using the indexes.
+// The bitmap can be a superset of the matching documents, see IndexResolve for finding out
+// which checks need to be verified in memory.
+// When the index can be modified concurrently, the indexes must be accessed through [index.Index.View].
+func (ff Checks) IndexMatch(indexes map[index.Type]index.Indexable) *roaring64.Bitmap {
+	bmp, _ := ff.IndexResolve(indexes)
+	return bmp
 }
 
-// SearchIndex does a fast index search for the received filters.
+// EstimateCardinality returns the number of documents of the i index that can match the check, using
+// the cardinalities of the index bitmaps, and whether the number is exact.
+// When the check contains conditions that can't be resolved from the indexes, see IndexResolve,
+// the number is an upper bound of the matching documents.
+//
+// Comparing it with the number of documents returned by [index.Index.Stats] gives the selectivity of the check,
+// which can be used for deciding between an index lookup and a full scan of the documents.
+func EstimateCardinality(i *index.Index, check Check) (uint64, bool) {
+	var (
+		bmp      *roaring64.Bitmap
+		residual Checks
+	)
+	i.View(func(indexes map[index.Type]index.Indexable) {
+		bmp, residual = Checks{check}.indexResolve(indexes, analyzerOf(i))
+	})
+	return bmp.GetCardinality(), len(residual) == 0
+}
+
+// SearchIndex does a fast index search for the received filters, and returns the IRIs of the matching documents,
+// the most recently updated, or published, first, like PaginateCollection orders the items.
+// It honours the WithMaxCount, After and Before pagination checks, see SearchIndexPage for the cursors of
+// the neighbouring pages.
+// When some of the filters can't be resolved from the indexes, the IRIs of all the documents that can match them
+// are returned, see SearchIndexPage for the residual checks the callers need to verify.
+// If the references of multiple documents collide, all of their IRIs are returned, see [index.Index.IRIs].
+// It is safe to call while other goroutines add documents to i.
 func SearchIndex(i *index.Index, ff ...Check) ([]vocab.IRI, error) {
-	bmp := Checks(ff).IndexMatch(i.Indexes)
+	iris, _, _, _, err := SearchIndexPage(i, ff...)
+	return iris, err
+}
+
+// SearchIndexPage returns the same IRIs as SearchIndex, together with the residual checks, see [Checks.IndexResolve],
+// and the cursors of the previous and the next pages, which contain the "maxItems", "before" and "after" values,
+// like the ones of PaginateCollection. The cursors are nil when there is no previous, or next page.
+//
+// The pages are computed from the documents that can match the filters, so when the residual checks are not empty,
+// the callers need to verify them on the loaded documents, and the pages can contain fewer matching documents
+// than the WithMaxCount check allows. The pagination checks are never part of the residual checks.
+//
+// The times used for ordering the documents come from the ByUpdated and ByPublished indexes, see
+// [index.SortByTime], and the After and Before checks are verified against the IRIs of the documents.
+func SearchIndexPage(i *index.Index, ff ...Check) ([]vocab.IRI, Checks, url.Values, url.Values, error) {
+	var refs []uint64
+	var residual Checks
+	i.View(func(indexes map[index.Type]index.Indexable) {
+		var bmp *roaring64.Bitmap
+		bmp, residual = Checks(ff).indexResolve(indexes, analyzerOf(i))
+		refs = bmp.ToArray()
+		index.SortByTime(indexes, refs)
+	})
+	residual = FilterChecks(residual...)
+	if len(refs) == 0 {
+		return nil, residual, nil, nil, nil
+	}
+
+	result := make([]vocab.IRI, 0, len(refs))
+	for _, ref := range refs {
+		// NOTE(marius): when the references of multiple documents collide, we return all of them.
+		result = append(result, i.IRIs(ref)...)
+	}
+	page, prev, next := paginateIRIs(result, ff...)
+	return page, residual, prev, next, nil
+}
+
+// paginateIRIs returns the page of the iris corresponding to the pagination checks, and the cursors of
+// the previous and next pages.
+// Unlike filterCollection, when paginating with a Before check, the page contains the IRIs right before it.
+func paginateIRIs(iris []vocab.IRI, ff ...Check) ([]vocab.IRI, url.Values, url.Values) {
+	maxItems := MaxCount(ff...)
+	if maxItems == 0 {
+		return []vocab.IRI{}, nil, nil
+	}
 
-	if bmp.GetCardinality() == 0 {
-		return nil, nil
+	cursor := func(fns Checks) int {
+		match := checkFn(fns)
+		return slices.IndexFunc(iris, func(iri vocab.IRI) bool { return match(iri) })
 	}
+	after, before := AfterChecks(ff...), BeforeChecks(ff...)
+
+	start, end := 0, len(iris)
+	if len(after) > 0 {
+		// NOTE(marius): the same as for After, when the cursor is missing no IRI is after it.
+		start = len(iris)
+		if j := cursor(after); j >= 0 {
+			start = j + 1
+		}
+	}
+	if len(before) > 0 {
+		if j := cursor(before); j >= 0 {
+			end = j
+		}
+	}
+	start = min(start, end)
+	if maxItems > 0 && end-start > maxItems {
+		if len(before) > 0 && len(after) == 0 {
+			start = end - maxItems
+		} else {
+			end = start + maxItems
+		}
+	}
+
+	page := iris[start:end]
+	if len(page) == 0 {
+		return page, nil, nil
+	}
+	cursorValues := func(key string, iri vocab.IRI) url.Values {
+		q := url.Values{key: []string{iri.String()}}
+		if maxItems > 0 {
+			q.Set(keyMaxItems, strconv.Itoa(maxItems))
+		}
+		return q
+	}
+	var prev, next url.Values
+	if start > 0 {
+		prev = cursorValues(keyBefore, page[0])
+	}
+	if end < len(iris) {
+		next = cursorValues(keyAfter, page[len(page)-1])
+	}
+	return page, prev, next
+}
 
-	it := bmp.Iterator()
-	if it == nil {
-		return nil, nil
+// Search does a full-text search of the query in the ByText index of i, and returns the IRIs of the matching
+// objects ordered by relevance. See [index.Index.Search] for the query syntax.
+// If any filters are received, only the results that can match them, using the indexes, are returned, together
+// with the residual checks, see [Checks.IndexResolve].
+// Like SearchIndexPage, Search doesn't load the documents, so it doesn't apply the residual checks, and the callers
+// must verify them on the loaded documents, eg: using All(residual...).Match(it), before returning the results.
+// When the residual checks are empty, the results match exactly all the filters.
+// The pagination checks are not applied, and they are never part of the residual checks.
+func Search(i *index.Index, query string, ff ...Check) ([]Scored[vocab.IRI], Checks, error) {
+	result := i.Search(query)
+	if len(ff) == 0 || len(result) == 0 {
+		return result, nil, nil
 	}
 
-	result := make([]vocab.IRI, 0, bmp.GetCardinality())
-	for x := it.PeekNext(); it.HasNext(); x = it.Next() {
-		if iri, ok := i.Ref[x]; ok {
-			result = append(result, iri)
+	var bmp *roaring64.Bitmap
+	var residual Checks
+	i.View(func(indexes map[index.Type]index.Indexable) {
+		bmp, residual = Checks(ff).indexResolve(indexes, analyzerOf(i))
+	})
+	residual = FilterChecks(residual...)
+	filtered := make([]Scored[vocab.IRI], 0, len(result))
+	for _, r := range result {
+		if bmp.Contains(hFn(r.Value)) {
+			filtered = append(filtered, r)
 		}
 	}
-	return result, nil
+	return filtered, residual, nil
 }
@@ -2,12 +2,18 @@ package filters
 
 import (
 	"fmt"
-	"reflect"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/RoaringBitmap/roaring/roaring64"
 	vocab "github.com/go-ap/activitypub"
 	"github.com/go-ap/filters/index"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/text/language"
 )
 
 func ExampleSearchIndex() {
@@ -67,6 +73,9 @@ func ExampleSearchIndex() {
 	fmt.Printf("Error: %v\n", err)
 	fmt.Printf("IRIs: %#v\n", iris)
 
+	// NOTE(marius): the Full index doesn't contain the ByAttributedToTrigram index, so the AttributedToLike check
+	// can't be resolved, and the IRIs are the candidates that still need to be checked against it,
+	// see SearchIndexPage for getting the checks that weren't resolved.
 	findBlock := Checks{
 		HasType(vocab.FlagType),
 		AttributedToLike("https://federated.local/~jdoe"),
@@ -85,6 +94,46 @@ func ExampleSearchIndex() {
 	// IRIs: []activitypub.IRI{https://federated.local/4}
 }
 
+func ExampleSearchIndexPage() {
+	flag := &vocab.Activity{
+		ID:     "https://federated.local/4",
+		Type:   vocab.FlagType,
+		To:     vocab.ItemCollection{vocab.IRI("https://federated.local/~jdoe")},
+		Actor:  vocab.IRI("https://federated.local/~jdoe"),
+		Object: vocab.IRI("https://federated.local/objects/1"),
+	}
+
+	in := index.Partial(ByID, ByType, ByIDTrigram)
+	in.Add(flag)
+
+	// The IDLike check is resolved using the ByIDTrigram index, so the IRIs match all the checks.
+	iris, residual, _, _, err := SearchIndexPage(in, HasType(vocab.FlagType), IDLike("https://federated.local/"))
+	fmt.Printf("Find Flag by ID:\n")
+	fmt.Printf("Error: %v\n", err)
+	fmt.Printf("IRIs: %#v\n", iris)
+	fmt.Printf("Residual checks: %d\n", len(residual))
+
+	// The AttributedToLike check can't be resolved without the ByAttributedToTrigram index, so it is returned
+	// for being applied on the loaded items.
+	iris, residual, _, _, err = SearchIndexPage(in, HasType(vocab.FlagType), AttributedToLike("https://federated.local/~jdoe"))
+	fmt.Printf("Find Flag by attributedTo:\n")
+	fmt.Printf("Error: %v\n", err)
+	fmt.Printf("IRIs: %#v\n", iris)
+	fmt.Printf("Residual checks: %d\n", len(residual))
+	fmt.Printf("Matches the loaded item: %t\n", All(residual...).Match(flag))
+
+	// Output:
+	// Find Flag by ID:
+	// Error: <nil>
+	// IRIs: []activitypub.IRI{https://federated.local/4}
+	// Residual checks: 0
+	// Find Flag by attributedTo:
+	// Error: <nil>
+	// IRIs: []activitypub.IRI{https://federated.local/4}
+	// Residual checks: 1
+	// Matches the loaded item: false
+}
+
 var indexableActivities = []vocab.LinkOrIRI{
 	&vocab.Actor{
 		ID:                "https://federated.local/~jdoe",
@@ -149,8 +198,68 @@ var indexableActivities = []vocab.LinkOrIRI{
 	},
 }
 
+func ExampleSearch() {
+	objects := []vocab.LinkOrIRI{
+		&vocab.Object{
+			ID:      "https://federated.local/objects/1",
+			Type:    vocab.NoteType,
+			Content: vocab.NaturalLanguageValues{vocab.DefaultLang: vocab.Content("<p>Free software licenses</p>")},
+		},
+		&vocab.Object{
+			ID:      "https://federated.local/objects/2",
+			Type:    vocab.ArticleType,
+			Name:    vocab.NaturalLanguageValues{vocab.DefaultLang: vocab.Content("Software licensing")},
+			Content: vocab.NaturalLanguageValues{vocab.DefaultLang: vocab.Content("Which license is free enough for your software?")},
+		},
+		&vocab.Object{
+			ID:      "https://federated.local/objects/3",
+			Type:    vocab.NoteType,
+			Content: vocab.NaturalLanguageValues{vocab.DefaultLang: vocab.Content("Free beer")},
+		},
+	}
+
+	in := index.Partial(ByID, ByType, ByText)
+	in.Add(objects...)
+
+	results, _, err := Search(in, "free software")
+	fmt.Printf("Search:\n")
+	fmt.Printf("Error: %v\n", err)
+	for _, r := range results {
+		fmt.Printf("IRI: %s\n", r.Value)
+	}
+
+	results, residual, err := Search(in, `"free software"`, HasType(vocab.NoteType))
+	fmt.Printf("Search phrase in notes:\n")
+	fmt.Printf("Error: %v\n", err)
+	fmt.Printf("Residual checks: %d\n", len(residual))
+	for _, r := range results {
+		fmt.Printf("IRI: %s\n", r.Value)
+	}
+
+	// Output:
+	// Search:
+	// Error: <nil>
+	// IRI: https://federated.local/objects/2
+	// IRI: https://federated.local/objects/1
+	// IRI: https://federated.local/objects/3
+	// Search phrase in notes:
+	// Error: <nil>
+	// Residual checks: 0
+	// IRI: https://federated.local/objects/1
+}
+
+// extendedIndex returns an index containing all the index types, including the ones that are not part of index.Full.
+func extendedIndex() *index.Index {
+	return index.Partial(
+		ByID, ByType, ByName, ByPreferredUsername, BySummary, ByContent, ByActor, ByObject,
+		ByRecipients, ByAttributedTo, ByInReplyTo, index.ByPublished, index.ByUpdated,
+		ByText, ByNameTrigram, ByContentTrigram, ByHashtag, ByMention, ByTag, ByContext, ByURL, ByTarget, ByAudience,
+		ByIDTrigram, ByAttributedToTrigram, ByInReplyToTrigram,
+	)
+}
+
 func buildIndex() map[index.Type]index.Indexable {
-	f := index.Full()
+	f := extendedIndex()
 	f.Add(indexableActivities...)
 	return f.Indexes
 }
@@ -166,6 +275,37 @@ func wantedBmp[T ~string](x ...T) *roaring64.Bitmap {
 func TestChecks_IndexMatch(t *testing.T) {
 	idx := buildIndex()
 
+	tagged := extendedIndex()
+	tagged.Add(
+		&vocab.Activity{
+			ID:     "https://federated.local/6",
+			Type:   vocab.AddType,
+			Actor:  vocab.IRI("https://federated.local/~jdoe"),
+			Object: vocab.IRI("https://federated.local/objects/2"),
+			Target: vocab.IRI("https://federated.local/~jdoe/featured"),
+		},
+		&vocab.Object{
+			ID:       "https://federated.local/objects/2",
+			Type:     vocab.NoteType,
+			Context:  vocab.IRI("https://federated.local/threads/1"),
+			URL:      vocab.IRI("https://federated.local/@jdoe/2"),
+			Audience: vocab.ItemCollection{vocab.IRI("https://federated.local/groups/1")},
+			Tag: vocab.ItemCollection{
+				vocab.IRI("https://federated.local/tags/golang"),
+				&vocab.Link{Type: index.HashtagType, Name: vocab.DefaultNaturalLanguage("#GoLang")},
+				&vocab.Mention{Type: index.MentionType, Href: "https://federated.local/~jdoe"},
+			},
+		},
+		&vocab.Object{
+			ID:      "https://federated.local/objects/3",
+			Type:    vocab.NoteType,
+			Context: vocab.IRI("https://federated.local/threads/1"),
+			Tag: vocab.ItemCollection{
+				&vocab.Link{Type: index.HashtagType, Name: vocab.DefaultNaturalLanguage("#rust")},
+			},
+		},
+	)
+
 	tests := []struct {
 		name    string
 		ff      Checks
@@ -383,14 +523,814 @@ func TestChecks_IndexMatch(t *testing.T) {
 			indexes: idx,
 			want:    wantedBmp("https://federated.local/1"),
 		},
+		{
+			name:    "name like substring",
+			ff:      Checks{NameLike("nderla")},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/~alice"),
+		},
+		{
+			name:    "name like preferred username, folded",
+			ff:      Checks{WithCollation(DefaultCollation, NameLike("DOE"))},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/~jdoe"),
+		},
+		{
+			name:    "name like preferred username, exact by default",
+			ff:      Checks{NameLike("DOE")},
+			indexes: idx,
+			want:    roaring64.New(),
+		},
+		{
+			name:    "name like shorter than a trigram",
+			ff:      Checks{NameLike("ic")},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/~alice"),
+		},
+		{
+			name:    "name like across words",
+			ff:      Checks{NameLike("to example")},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/objects/1"),
+		},
+		{
+			name:    "name like not found",
+			ff:      Checks{NameLike("bob")},
+			indexes: idx,
+			want:    roaring64.New(),
+		},
+		{
+			name:    "name like, exact collation",
+			ff:      Checks{WithCollation(ExactCollation, NameLike("ALICE"))},
+			indexes: idx,
+			want:    roaring64.New(),
+		},
+		{
+			name:    "content like substring",
+			ff:      Checks{ContentLike("ged obj")},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/5"),
+		},
+		{
+			name:    "type:Flag,content like substring",
+			ff:      Checks{HasType(vocab.FlagType), ContentLike("lagg")},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/5"),
+		},
+		{
+			name:    "hashtag",
+			ff:      Checks{HasHashtag("#golang")},
+			indexes: tagged.Indexes,
+			want:    wantedBmp("https://federated.local/objects/2"),
+		},
+		{
+			name:    "any hashtag",
+			ff:      Checks{Any(HasHashtag("golang"), HasHashtag("RUST"))},
+			indexes: tagged.Indexes,
+			want:    wantedBmp("https://federated.local/objects/2", "https://federated.local/objects/3"),
+		},
+		{
+			name:    "mention",
+			ff:      Checks{Mentions("https://federated.local/~jdoe")},
+			indexes: tagged.Indexes,
+			want:    wantedBmp("https://federated.local/objects/2"),
+		},
+		{
+			name:    "hashtag and mention",
+			ff:      Checks{HasHashtag("rust"), Mentions("https://federated.local/~jdoe")},
+			indexes: tagged.Indexes,
+			want:    roaring64.New(),
+		},
+		{
+			name:    "context",
+			ff:      Checks{SameContext("https://federated.local/threads/1")},
+			indexes: tagged.Indexes,
+			want:    wantedBmp("https://federated.local/objects/2", "https://federated.local/objects/3"),
+		},
+		{
+			name:    "url",
+			ff:      Checks{SameURL("https://federated.local/@jdoe/2")},
+			indexes: tagged.Indexes,
+			want:    wantedBmp("https://federated.local/objects/2"),
+		},
+		{
+			name:    "tag IRI",
+			ff:      Checks{Tag(SameIRI("https://federated.local/tags/golang"))},
+			indexes: tagged.Indexes,
+			want:    wantedBmp("https://federated.local/objects/2"),
+		},
+		{
+			name:    "target",
+			ff:      Checks{HasType(vocab.AddType), Target(SameID("https://federated.local/~jdoe/featured"))},
+			indexes: tagged.Indexes,
+			want:    wantedBmp("https://federated.local/6"),
+		},
+		{
+			name:    "audience property",
+			ff:      Checks{Property("audience", SameIRI("https://federated.local/groups/1"))},
+			indexes: tagged.Indexes,
+			want:    wantedBmp("https://federated.local/objects/2"),
+		},
+		{
+			name:    "context and hashtag",
+			ff:      Checks{SameContext("https://federated.local/threads/1"), HasHashtag("rust")},
+			indexes: tagged.Indexes,
+			want:    wantedBmp("https://federated.local/objects/3"),
+		},
+		{
+			name:    "nil attributedTo",
+			ff:      Checks{NilAttributedTo},
+			indexes: idx,
+			want: wantedBmp(
+				"https://federated.local/1",
+				"https://federated.local/2",
+				"https://federated.local/3",
+				"https://federated.local/4",
+				"https://federated.local/5",
+			),
+		},
+		{
+			name:    "not nil attributedTo",
+			ff:      Checks{Not(NilAttributedTo)},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/~jdoe", "https://federated.local/~alice", "https://federated.local/objects/1"),
+		},
+		{
+			name:    "nil inReplyTo",
+			ff:      Checks{HasType(vocab.PersonType), NilInReplyTo},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/~jdoe", "https://federated.local/~alice"),
+		},
+		{
+			name:    "nil ID",
+			ff:      Checks{NilID},
+			indexes: idx,
+			want:    wantedBmp[string](),
+		},
+		{
+			name:    "name empty",
+			ff:      Checks{NameEmpty, Not(HasType(vocab.FlagType))},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/1", "https://federated.local/2", "https://federated.local/3"),
+		},
+		{
+			name:    "content empty",
+			ff:      Checks{HasType(vocab.FlagType), ContentEmpty},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/4"),
+		},
+		{
+			name:    "attributedTo like",
+			ff:      Checks{AttributedToLike("~ali")},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/objects/1"),
+		},
+		{
+			name:    "id like",
+			ff:      Checks{IDLike("/objects/")},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/objects/1"),
+		},
+		{
+			name:    "id like with collation",
+			ff:      Checks{WithCollation(CaseFold, IDLike("/OBJECTS/"))},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/objects/1"),
+		},
+		{
+			name:    "not iri like",
+			ff:      Checks{HasType(vocab.PersonType), Not(IRILike("~j"))},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/~alice"),
+		},
+		{
+			name:    "any with unresolved check",
+			ff:      Checks{HasType(vocab.PersonType), Any(SameID("https://federated.local/~jdoe"), URLLike("example"))},
+			indexes: idx,
+			want:    wantedBmp("https://federated.local/~jdoe", "https://federated.local/~alice"),
+		},
+		{
+			name:    "only unresolved checks",
+			ff:      Checks{URLLike("example")},
+			indexes: tagged.Indexes,
+			want: wantedBmp(
+				"https://federated.local/6",
+				"https://federated.local/objects/2",
+				"https://federated.local/objects/3",
+			),
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			got := tt.ff.IndexMatch(tt.indexes)
-			if !reflect.DeepEqual(got, tt.want) {
+			if !got.Equals(tt.want) {
 				t.Errorf("IndexMatch() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestChecks_IndexResolve(t *testing.T) {
+	idx := buildIndex()
+
+	tests := []struct {
+		name         string
+		ff           Checks
+		indexes      map[index.Type]index.Indexable
+		want         *roaring64.Bitmap
+		wantResidual Checks
+	}{
+		{
+			name: "empty",
+			want: &roaring64.Bitmap{},
+		},
+		{
+			name:         "exact",
+			ff:           Checks{HasType(vocab.FlagType), Not(SameAttributedTo("https://federated.local/~alice"))},
+			indexes:      idx,
+			want:         wantedBmp("https://federated.local/4", "https://federated.local/5"),
+			wantResidual: Checks{},
+		},
+		{
+			name:         "unresolved check",
+			ff:           Checks{HasType(vocab.PageType), URLLike("example")},
+			indexes:      idx,
+			want:         wantedBmp("https://federated.local/objects/1"),
+			wantResidual: Checks{URLLike("example")},
+		},
+		{
+			name:         "superset of words",
+			ff:           Checks{SummaryIs("example"), HasType(vocab.PageType)},
+			indexes:      idx,
+			want:         wantedBmp("https://federated.local/objects/1"),
+			wantResidual: Checks{SummaryIs("example")},
+		},
+		{
+			name:         "not of unresolved check",
+			ff:           Checks{HasType(vocab.PersonType), Not(SummaryIs("dude"))},
+			indexes:      idx,
+			want:         wantedBmp("https://federated.local/~jdoe", "https://federated.local/~alice"),
+			wantResidual: Checks{Not(SummaryIs("dude"))},
+		},
+		{
+			name:         "nested IRI check",
+			ff:           Checks{HasType(vocab.FlagType), Actor(SameID("https://federated.local/~jdoe"))},
+			indexes:      idx,
+			want:         wantedBmp("https://federated.local/4"),
+			wantResidual: Checks{},
+		},
+		{
+			name:         "nested name check",
+			ff:           Checks{HasType(vocab.FlagType), Actor(NameIs("jDoe"))},
+			indexes:      idx,
+			want:         wantedBmp("https://federated.local/4"),
+			wantResidual: Checks{Actor(NameIs("jDoe"))},
+		},
+		{
+			name:         "not of nested name check",
+			ff:           Checks{HasType(vocab.FlagType), Not(Actor(NameIs("jDoe")))},
+			indexes:      idx,
+			want:         wantedBmp("https://federated.local/4", "https://federated.local/5"),
+			wantResidual: Checks{Not(Actor(NameIs("jDoe")))},
+		},
+		{
+			name:         "missing index",
+			ff:           Checks{IDLike("/objects/")},
+			indexes:      index.Partial(ByID, ByType).Indexes,
+			want:         &roaring64.Bitmap{},
+			wantResidual: Checks{IDLike("/objects/")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, residual := tt.ff.IndexResolve(tt.indexes)
+			if !got.Equals(tt.want) {
+				t.Errorf("IndexResolve() = %v, want %v", got, tt.want)
+			}
+			if !cmp.Equal(residual, tt.wantResidual, cmp.Comparer(ChecksComparer)) {
+				t.Errorf("IndexResolve() residual = %s", cmp.Diff(tt.wantResidual, residual, cmp.Comparer(ChecksComparer)))
+			}
+		})
+	}
+}
+
+func TestSearch_residual(t *testing.T) {
+	in := index.Partial(ByID, ByType, ByText)
+	in.Add(
+		&vocab.Object{
+			ID:      "https://federated.local/objects/1",
+			Type:    vocab.NoteType,
+			Name:    vocab.NaturalLanguageValues{vocab.DefaultLang: vocab.Content("Licenses")},
+			Content: vocab.NaturalLanguageValues{vocab.DefaultLang: vocab.Content("Free software")},
+		},
+		&vocab.Object{
+			ID:      "https://federated.local/objects/2",
+			Type:    vocab.ArticleType,
+			Content: vocab.NaturalLanguageValues{vocab.DefaultLang: vocab.Content("Free beer")},
+		},
+	)
+
+	nameMatches := MustNameMatches("^Lic")
+	results, residual, err := Search(in, "free", HasType(vocab.NoteType), nameMatches, WithMaxCount(10))
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Value != "https://federated.local/objects/1" {
+		t.Errorf("Search() = %v, want only https://federated.local/objects/1", results)
+	}
+	if want := (Checks{nameMatches}); !cmp.Equal(residual, want, cmp.Comparer(ChecksComparer)) {
+		t.Errorf("Search() residual = %s", cmp.Diff(want, residual, cmp.Comparer(ChecksComparer)))
+	}
+}
+
+func TestSearchIndex_collisions(t *testing.T) {
+	defer func(fn index.HashFnType) { index.HashFn = fn }(index.HashFn)
+	// NOTE(marius): all the IRIs with the same length share the same reference.
+	index.HashFn = func(li vocab.LinkOrIRI) uint64 {
+		if li == nil {
+			return 0
+		}
+		return uint64(len(li.GetLink()))
+	}
+
+	in := index.Full()
+	in.Add(indexableActivities...)
+
+	got, err := SearchIndex(in, SameID("https://federated.local/4"))
+	if err != nil {
+		t.Fatalf("SearchIndex() error = %v", err)
+	}
+	want := []vocab.IRI{
+		"https://federated.local/1",
+		"https://federated.local/2",
+		"https://federated.local/3",
+		"https://federated.local/4",
+		"https://federated.local/5",
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("SearchIndex() = %s", cmp.Diff(want, got))
+	}
+}
+
+func TestSearchIndex_sequentialRefs(t *testing.T) {
+	defer func(fn index.HashFnType) { index.HashFn = fn }(index.HashFn)
+	defer func(fn index.HashFnType) { index.LookupFn = fn }(index.LookupFn)
+	refs := index.NewSequentialRefs()
+	index.HashFn, index.LookupFn = refs.Hash, refs.Lookup
+
+	in := index.Full()
+	in.Add(indexableActivities...)
+
+	got, err := SearchIndex(in, SameID("https://federated.local/missing"))
+	if err != nil {
+		t.Fatalf("SearchIndex() error = %v", err)
+	}
+	if len(got) > 0 {
+		t.Errorf("SearchIndex() = %v, want none", got)
+	}
+	if ref := refs.Lookup(vocab.IRI("https://federated.local/missing")); ref != 0 {
+		t.Errorf("SearchIndex() allocated the reference %d for a missing IRI", ref)
+	}
+}
+
+func TestSearchIndex_segment(t *testing.T) {
+	in := index.Full()
+	in.Add(indexableActivities...)
+
+	path := filepath.Join(t.TempDir(), "index.seg")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("unable to create segment: %v", err)
+	}
+	if _, err = in.WriteSegment(f); err != nil {
+		t.Fatalf("WriteSegment() error = %v", err)
+	}
+	_ = f.Close()
+
+	seg, err := index.OpenSegment(path)
+	if err != nil {
+		t.Fatalf("OpenSegment() error = %v", err)
+	}
+	defer seg.Close()
+
+	tests := map[string]Checks{
+		"type":            {HasType(vocab.FlagType, vocab.CreateType)},
+		"not type":        {Not(HasType(vocab.PersonType))},
+		"id":              {SameID("https://federated.local/~alice")},
+		"id like":         {IDLike("federated.local/objects")},
+		"name like":       {NameLike("wonderland")},
+		"summary like":    {SummaryLike("example")},
+		"actor":           {Actor(SameID("https://federated.local/~alice"))},
+		"recipients":      {Recipients(vocab.PublicNS)},
+		"attributed like": {AttributedToLike("~alice")},
+		"authorized":      {Authorized("https://federated.local/~alice")},
+		"any":             {Any(NameIs("alice"), Object(SameID("https://federated.local/objects/1")))},
+	}
+	for name, ff := range tests {
+		t.Run(name, func(t *testing.T) {
+			want, err := SearchIndex(in, ff...)
+			if err != nil {
+				t.Fatalf("SearchIndex() error = %v", err)
+			}
+			got, err := SearchIndex(seg.Index, ff...)
+			if err != nil {
+				t.Fatalf("SearchIndex() on segment error = %v", err)
+			}
+			if !cmp.Equal(got, want) {
+				t.Errorf("SearchIndex() on segment = %s", cmp.Diff(want, got))
+			}
+		})
+	}
+}
+
+func TestSearchIndex_concurrent(t *testing.T) {
+	in := index.Full()
+	in.Add(indexableActivities...)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := range 100 {
+			in.Add(&vocab.Activity{
+				ID:     vocab.IRI(fmt.Sprintf("https://federated.local/like/%d", j)),
+				Type:   vocab.LikeType,
+				To:     vocab.ItemCollection{vocab.PublicNS},
+				Actor:  vocab.IRI("https://federated.local/~alice"),
+				Object: vocab.IRI("https://federated.local/objects/1"),
+			})
+		}
+	}()
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if _, err := SearchIndex(in, HasType(vocab.LikeType), Not(Actor(SameID("https://federated.local/~jdoe")))); err != nil {
+					t.Errorf("SearchIndex() error = %v", err)
+				}
+				if _, _, err := Search(in, "flagged", Authorized("https://federated.local/~alice")); err != nil {
+					t.Errorf("Search() error = %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var got *roaring64.Bitmap
+	in.View(func(indexes map[index.Type]index.Indexable) {
+		got = Checks{HasType(vocab.LikeType), Actor(SameID("https://federated.local/~alice"))}.IndexMatch(indexes)
+	})
+	if got.GetCardinality() != 100 {
+		t.Errorf("IndexMatch() after concurrent Add() = %d documents, want 100", got.GetCardinality())
+	}
+}
+
+func TestSearchIndex_snapshot(t *testing.T) {
+	in := index.Full()
+	in.Add(indexableActivities...)
+
+	snap := in.Snapshot()
+	want, err := SearchIndex(snap, HasType(vocab.LikeType))
+	if err != nil {
+		t.Fatalf("SearchIndex() error = %v", err)
+	}
+
+	in.Add(&vocab.Activity{
+		ID:     "https://federated.local/6",
+		Type:   vocab.LikeType,
+		Actor:  vocab.IRI("https://federated.local/~alice"),
+		Object: vocab.IRI("https://federated.local/objects/1"),
+	})
+	in.Remove("https://federated.local/2")
+
+	got, err := SearchIndex(snap, HasType(vocab.LikeType))
+	if err != nil {
+		t.Fatalf("SearchIndex() error = %v", err)
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("SearchIndex() on the snapshot = %s", cmp.Diff(want, got))
+	}
+	if snap.Generation() == in.Generation() {
+		t.Errorf("Generation() = %d, want it different from the one of the index", in.Generation())
+	}
+
+	got, err = SearchIndex(in, HasType(vocab.LikeType))
+	if err != nil {
+		t.Fatalf("SearchIndex() error = %v", err)
+	}
+	if cmp.Equal(got, want) {
+		t.Errorf("SearchIndex() on the index = %v, want it to contain the changes", got)
+	}
+}
+
+func TestEstimateCardinality(t *testing.T) {
+	in := index.Full()
+	in.Add(indexableActivities...)
+
+	tests := []struct {
+		name      string
+		check     Check
+		want      uint64
+		wantExact bool
+	}{
+		{
+			name:      "type",
+			check:     HasType(vocab.FlagType),
+			want:      2,
+			wantExact: true,
+		},
+		{
+			name:      "missing type",
+			check:     HasType(vocab.UndoType),
+			want:      0,
+			wantExact: true,
+		},
+		{
+			name:      "not type",
+			check:     Not(HasType(vocab.PersonType)),
+			want:      6,
+			wantExact: true,
+		},
+		{
+			name:      "actor",
+			check:     Actor(SameID("https://federated.local/~jdoe")),
+			want:      4,
+			wantExact: true,
+		},
+		{
+			name:      "authorized",
+			check:     Authorized("https://federated.local/~alice"),
+			want:      3,
+			wantExact: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, exact := EstimateCardinality(in, tt.check)
+			if got != tt.want || exact != tt.wantExact {
+				t.Errorf("EstimateCardinality() = %d, %t, want %d, %t", got, exact, tt.want, tt.wantExact)
+			}
+		})
+	}
+}
+
+func TestSearchIndexPage(t *testing.T) {
+	published := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	in := index.Full()
+	for j := 1; j <= 5; j++ {
+		in.Add(&vocab.Object{
+			ID:        vocab.IRI(fmt.Sprintf("https://federated.local/objects/%d", j)),
+			Type:      vocab.NoteType,
+			Published: published.Add(time.Duration(j) * time.Hour),
+		})
+	}
+	in.Add(&vocab.Object{ID: "https://federated.local/objects/6", Type: vocab.ArticleType, Published: published})
+
+	cursor := func(key, iri string, maxItems string) url.Values {
+		return url.Values{key: []string{iri}, keyMaxItems: []string{maxItems}}
+	}
+
+	nameMatches := MustNameMatches("^Note")
+
+	tests := []struct {
+		name         string
+		checks       Checks
+		want         []vocab.IRI
+		wantResidual Checks
+		wantPrev     url.Values
+		wantNext     url.Values
+	}{
+		{
+			name:   "all",
+			checks: Checks{HasType(vocab.NoteType)},
+			want: []vocab.IRI{
+				"https://federated.local/objects/5", "https://federated.local/objects/4", "https://federated.local/objects/3",
+				"https://federated.local/objects/2", "https://federated.local/objects/1",
+			},
+		},
+		{
+			name:     "first page",
+			checks:   Checks{HasType(vocab.NoteType), WithMaxCount(2)},
+			want:     []vocab.IRI{"https://federated.local/objects/5", "https://federated.local/objects/4"},
+			wantNext: cursor(keyAfter, "https://federated.local/objects/4", "2"),
+		},
+		{
+			name:     "after",
+			checks:   Checks{HasType(vocab.NoteType), WithMaxCount(2), After(SameID("https://federated.local/objects/4"))},
+			want:     []vocab.IRI{"https://federated.local/objects/3", "https://federated.local/objects/2"},
+			wantPrev: cursor(keyBefore, "https://federated.local/objects/3", "2"),
+			wantNext: cursor(keyAfter, "https://federated.local/objects/2", "2"),
+		},
+		{
+			name:     "last page",
+			checks:   Checks{HasType(vocab.NoteType), WithMaxCount(2), After(SameID("https://federated.local/objects/2"))},
+			want:     []vocab.IRI{"https://federated.local/objects/1"},
+			wantPrev: cursor(keyBefore, "https://federated.local/objects/1", "2"),
+		},
+		{
+			name:     "before",
+			checks:   Checks{HasType(vocab.NoteType), WithMaxCount(2), Before(SameID("https://federated.local/objects/2"))},
+			want:     []vocab.IRI{"https://federated.local/objects/4", "https://federated.local/objects/3"},
+			wantPrev: cursor(keyBefore, "https://federated.local/objects/4", "2"),
+			wantNext: cursor(keyAfter, "https://federated.local/objects/3", "2"),
+		},
+		{
+			name:     "before first page",
+			checks:   Checks{HasType(vocab.NoteType), WithMaxCount(2), Before(SameID("https://federated.local/objects/4"))},
+			want:     []vocab.IRI{"https://federated.local/objects/5"},
+			wantNext: cursor(keyAfter, "https://federated.local/objects/5", "2"),
+		},
+		{
+			name:   "missing cursor",
+			checks: Checks{HasType(vocab.NoteType), After(SameID("https://federated.local/objects/6"))},
+			want:   []vocab.IRI{},
+		},
+		{
+			name:   "no items",
+			checks: Checks{HasType(vocab.NoteType), WithMaxCount(0)},
+			want:   []vocab.IRI{},
+		},
+		{
+			name:         "residual",
+			checks:       Checks{HasType(vocab.NoteType), nameMatches, WithMaxCount(2)},
+			want:         []vocab.IRI{"https://federated.local/objects/5", "https://federated.local/objects/4"},
+			wantResidual: Checks{nameMatches},
+			wantNext:     cursor(keyAfter, "https://federated.local/objects/4", "2"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, residual, prev, next, err := SearchIndexPage(in, tt.checks...)
+			if err != nil {
+				t.Fatalf("SearchIndexPage() error = %v", err)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("SearchIndexPage() = %s", cmp.Diff(tt.want, got))
+			}
+			if len(residual) > 0 || len(tt.wantResidual) > 0 {
+				if !cmp.Equal(residual, tt.wantResidual, cmp.Comparer(ChecksComparer)) {
+					t.Errorf("SearchIndexPage() residual = %s", cmp.Diff(tt.wantResidual, residual, cmp.Comparer(ChecksComparer)))
+				}
+			}
+			if !cmp.Equal(prev, tt.wantPrev) {
+				t.Errorf("SearchIndexPage() prev = %s", cmp.Diff(tt.wantPrev, prev))
+			}
+			if !cmp.Equal(next, tt.wantNext) {
+				t.Errorf("SearchIndexPage() next = %s", cmp.Diff(tt.wantNext, next))
+			}
+		})
+	}
+	t.Run("follow cursors", func(t *testing.T) {
+		all, err := SearchIndex(in, HasType(vocab.NoteType))
+		if err != nil {
+			t.Fatalf("SearchIndex() error = %v", err)
+		}
+		var got []vocab.IRI
+		page, _, _, next, _ := SearchIndexPage(in, HasType(vocab.NoteType), WithMaxCount(2))
+		for got = append(got, page...); next != nil; got = append(got, page...) {
+			page, _, _, next, _ = SearchIndexPage(in, append(Checks{HasType(vocab.NoteType)}, FromValues(next)...)...)
+		}
+		if !cmp.Equal(got, all) {
+			t.Errorf("SearchIndexPage() pages = %s", cmp.Diff(all, got))
+		}
+	})
+}
+
+// authorizedObjects contains the documents for the differential tests of the Authorized check,
+// with the Block activities operated against the actors, every property the check looks at, and a bare IRI,
+// which doesn't have recipients.
+var authorizedObjects = []vocab.LinkOrIRI{
+	&vocab.Object{ID: "https://federated.local/objects/public", Type: vocab.NoteType, To: vocab.ItemCollection{vocab.PublicNS}},
+	&vocab.Object{ID: "https://federated.local/objects/private", Type: vocab.NoteType},
+	&vocab.Object{ID: "https://federated.local/objects/alice", Type: vocab.NoteType, AttributedTo: vocab.IRI("https://federated.local/~alice")},
+	&vocab.Object{ID: "https://federated.local/objects/to-bob", Type: vocab.NoteType, To: vocab.ItemCollection{vocab.IRI("https://federated.local/~bob")}},
+	&vocab.Object{ID: "https://federated.local/objects/bcc-bob", Type: vocab.NoteType, BCC: vocab.ItemCollection{vocab.IRI("https://federated.local/~bob")}},
+	&vocab.Object{ID: "https://federated.local/objects/audience-alice", Type: vocab.NoteType, Audience: vocab.ItemCollection{vocab.IRI("https://federated.local/~alice")}},
+	&vocab.Activity{
+		ID:     "https://federated.local/follow-bob",
+		Type:   vocab.FollowType,
+		Actor:  vocab.IRI("https://federated.local/~alice"),
+		Object: vocab.IRI("https://federated.local/~bob"),
+	},
+	&vocab.Activity{
+		ID:     "https://federated.local/like-embedded",
+		Type:   vocab.LikeType,
+		Actor:  &vocab.Actor{ID: "https://federated.local/~carol", Type: vocab.PersonType},
+		Object: &vocab.Object{ID: "https://federated.local/~alice", Type: vocab.PersonType},
+	},
+	&vocab.Activity{
+		ID:     "https://federated.local/block-bob",
+		Type:   vocab.BlockType,
+		Actor:  vocab.IRI("https://federated.local/~alice"),
+		Object: vocab.IRI("https://federated.local/~bob"),
+	},
+	&vocab.Activity{
+		ID:     "https://federated.local/block-public",
+		Type:   vocab.BlockType,
+		To:     vocab.ItemCollection{vocab.PublicNS},
+		Actor:  vocab.IRI("https://federated.local/~carol"),
+		Object: vocab.IRI("https://federated.local/~bob"),
+	},
+	&vocab.Activity{
+		ID:     "https://federated.local/block-to-bob",
+		Type:   vocab.BlockType,
+		To:     vocab.ItemCollection{vocab.IRI("https://federated.local/~bob")},
+		Actor:  vocab.IRI("https://federated.local/~carol"),
+		Object: vocab.IRI("https://federated.local/~bob"),
+	},
+	&vocab.Activity{
+		ID:     "https://federated.local/block-many",
+		Type:   vocab.BlockType,
+		Actor:  vocab.IRI("https://federated.local/~carol"),
+		Object: vocab.ItemCollection{vocab.IRI("https://federated.local/~alice"), vocab.IRI("https://federated.local/~bob")},
+	},
+	&vocab.Activity{
+		ID:    "https://federated.local/block-nothing",
+		Type:  vocab.BlockType,
+		Actor: vocab.IRI("https://federated.local/~carol"),
+	},
+	&vocab.IntransitiveActivity{
+		ID:    "https://federated.local/arrive",
+		Type:  vocab.ArriveType,
+		Actor: vocab.IRI("https://federated.local/~bob"),
+	},
+	vocab.IRI("https://federated.local/objects/bare"),
+}
+
+func TestSearchIndex_tokenizer(t *testing.T) {
+	// NOTE(marius): the tokenizer indexes each value as a single token.
+	tok := index.TokenizerFunc(func(text string, _ language.Tag) []string {
+		return []string{index.Fold(index.PlainText(text, index.DefaultTextOptions), index.DefaultCollation)}
+	})
+	in := index.PartialWithTokenizer(tok, ByID, ByType, ByContent)
+	in.Add(
+		&vocab.Object{
+			ID:      "https://federated.local/objects/1",
+			Type:    vocab.NoteType,
+			Content: vocab.NaturalLanguageValues{vocab.DefaultLang: vocab.Content("<p>Free Software</p>")},
+		},
+		&vocab.Object{
+			ID:      "https://federated.local/objects/2",
+			Type:    vocab.NoteType,
+			Content: vocab.NaturalLanguageValues{vocab.DefaultLang: vocab.Content("Software, free")},
+		},
+	)
+
+	check := ContentIs("free software")
+	want := vocab.IRIs{"https://federated.local/objects/1"}
+	got, err := SearchIndex(in, check)
+	if err != nil {
+		t.Fatalf("SearchIndex() error = %v", err)
+	}
+	if !cmp.Equal(vocab.IRIs(got), want) {
+		t.Errorf("SearchIndex() = %s", cmp.Diff(want, vocab.IRIs(got)))
+	}
+	if n, exact := EstimateCardinality(in, check); n != 1 || exact {
+		t.Errorf("EstimateCardinality() = %d, %t, want 1, false", n, exact)
+	}
+}
+
+func TestSearchIndex_authorized(t *testing.T) {
+	in := index.Full()
+	in.Add(authorizedObjects...)
+
+	viewers := []vocab.IRI{
+		"https://federated.local/~alice",
+		"https://federated.local/~bob",
+		"https://federated.local/~carol",
+		"https://federated.local/~dave",
+		vocab.PublicNS,
+	}
+	for _, viewer := range viewers {
+		for _, ff := range []Checks{
+			{Authorized(viewer)},
+			{Not(Authorized(viewer))},
+			{HasType(vocab.BlockType), Authorized(viewer)},
+			{Any(Authorized(viewer), HasType(vocab.NoteType))},
+		} {
+			t.Run(fmt.Sprintf("%s %#v", viewer, ff), func(t *testing.T) {
+				want := roaring64.New()
+				for _, ob := range authorizedObjects {
+					if All(ff...).Match(ob.(vocab.Item)) {
+						want.Add(index.HashFn(ob))
+					}
+				}
+
+				var got *roaring64.Bitmap
+				var residual Checks
+				in.View(func(indexes map[index.Type]index.Indexable) {
+					got, residual = ff.IndexResolve(indexes)
+				})
+				if len(residual) > 0 {
+					t.Errorf("IndexResolve() = %d residual checks, want the indexes to resolve them", len(residual))
+				}
+				if !got.Equals(want) {
+					t.Errorf("IndexResolve() = %v, want the same documents as Match() %v", got.ToArray(), want.ToArray())
+				}
+			})
+		}
+	}
+}
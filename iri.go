@@ -1,12 +1,6 @@
 package filters
 
-import (
-	"net/url"
-	"strings"
-
-	vocab "github.com/go-ap/activitypub"
-	"golang.org/x/text/unicode/norm"
-)
+import vocab "github.com/go-ap/activitypub"
 
 type iriEquals vocab.IRI
 
@@ -17,6 +11,10 @@ func (i iriEquals) Match(it vocab.Item) bool {
 	return it.GetLink().Equal(vocab.IRI(i))
 }
 
+func (i iriEquals) Leaf() (Field, Operator, Value) {
+	return FieldIRI, OperatorEquals, vocab.IRI(i)
+}
+
 func (i iriEquals) GoString() string {
 	return `iri=` + string(i)
 }
@@ -29,6 +27,10 @@ func SameIRI(iri vocab.IRI) Check {
 type iriLike string
 
 func (frag iriLike) Match(it vocab.Item) bool {
+	return frag.matchCollated(it, ExactCollation)
+}
+
+func (frag iriLike) matchCollated(it vocab.Item, coll Collation) bool {
 	if vocab.IsNil(it) {
 		return false
 	}
@@ -36,9 +38,11 @@ func (frag iriLike) Match(it vocab.Item) bool {
 	if iri == "" {
 		return false
 	}
-	nfc := norm.NFC.String
-	fragStr, _ := url.QueryUnescape(string(frag))
-	return strings.Contains(nfc(string(iri)), nfc(fragStr))
+	return collatedContains(string(iri), string(frag), coll)
+}
+
+func (frag iriLike) Leaf() (Field, Operator, Value) {
+	return FieldIRI, OperatorLike, string(frag)
 }
 
 func (frag iriLike) GoString() string {
@@ -71,6 +75,10 @@ func (n iriNil) Match(it vocab.Item) bool {
 	return Any(SameIRI(vocab.NilIRI), SameIRI(vocab.EmptyIRI)).Match(it.GetLink())
 }
 
+func (n iriNil) Leaf() (Field, Operator, Value) {
+	return FieldIRI, OperatorNil, nil
+}
+
 // NotNilIRI checks if the activitypub.Object's URL property matches any of the two magic values
 // that denote an empty value: activitypub.NilID = "-", or activitypub.EmptyID = ""
 var NotNilIRI = Not(iriNil{})
@@ -92,6 +100,11 @@ func (n itemNil) Match(it vocab.Item) bool {
 	return false
 }
 
+// Leaf returns the FieldNone property, as the check applies on the item itself.
+func (n itemNil) Leaf() (Field, Operator, Value) {
+	return FieldNone, OperatorNil, nil
+}
+
 // NilItem checks if the activitypub.Item is nil
 var NilItem = itemNil{}
 
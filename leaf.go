@@ -0,0 +1,140 @@
+package filters
+
+import "golang.org/x/text/language"
+
+// Field represents the ActivityPub property that a Check applies on.
+// The values are the same as the ones used for the URL query parameters.
+type Field string
+
+const (
+	// FieldNone is used by the checks that don't apply on a specific property of the item,
+	// like the aggregators, or the pagination checks.
+	FieldNone Field = ""
+
+	FieldID   Field = keyID
+	FieldIRI  Field = keyIRI
+	FieldType Field = keyType
+
+	FieldName              Field = keyName
+	FieldSummary           Field = keySummary
+	FieldContent           Field = keyContent
+	FieldPreferredUsername Field = keyPreferredUsername
+
+	FieldURL          Field = keyURL
+	FieldAttributedTo Field = keyAttributedTo
+	FieldInReplyTo    Field = keyInReplyTo
+	FieldContext      Field = keyContext
+
+	FieldActor  Field = keyActor
+	FieldObject Field = keyObject
+	FieldTarget Field = keyTarget
+
+	FieldTag Field = keyTag
+
+	// FieldHashtag represents the names of the Hashtag items of the tag property.
+	FieldHashtag Field = keyHashtag
+	// FieldMention represents the hrefs of the Mention items of the tag property.
+	FieldMention Field = keyMention
+	// FieldEmoji represents the names of the Emoji items of the tag property.
+	FieldEmoji Field = keyEmoji
+
+	// FieldRecipients represents the aggregated to, bto, cc, bcc and audience properties.
+	FieldRecipients Field = "recipients"
+)
+
+// Operator represents the comparison, or the logical operation, that a Check applies.
+type Operator string
+
+const (
+	// OperatorEquals matches if the property is equal to the value.
+	// For properties that can have multiple values, it matches if any of them is equal to it.
+	OperatorEquals Operator = "eq"
+	// OperatorLike matches if the property contains the value.
+	OperatorLike Operator = "like"
+	// OperatorMatches matches if the property matches the RE2 regular expression in the value.
+	OperatorMatches Operator = "matches"
+	// OperatorGlob matches if the property matches the glob pattern in the value.
+	OperatorGlob Operator = "glob"
+	// OperatorNil matches if the property is empty.
+	OperatorNil Operator = "nil"
+	// OperatorIn matches if the property is equal to any of the values.
+	OperatorIn Operator = "in"
+	// OperatorAuthorized matches if the actor IRI in the value has access to the item.
+	// See the Authorized function for the exact rules.
+	OperatorAuthorized Operator = "authorized"
+	// OperatorLanguage matches if the item has natural language values in the language of the value.
+	OperatorLanguage Operator = "lang"
+	// OperatorGt matches if the numeric property is greater than the value.
+	OperatorGt Operator = "gt"
+	// OperatorGte matches if the numeric property is greater than or equal to the value.
+	OperatorGte Operator = "gte"
+	// OperatorLt matches if the numeric property is less than the value.
+	OperatorLt Operator = "lt"
+	// OperatorLte matches if the numeric property is less than or equal to the value.
+	OperatorLte Operator = "lte"
+	// OperatorBetween matches if the numeric property is inside the closed interval in the value.
+	OperatorBetween Operator = "between"
+	// OperatorMaxItems limits the number of matched items to the value.
+	OperatorMaxItems Operator = "maxItems"
+
+	// OperatorAll matches if all the children checks match.
+	OperatorAll Operator = "all"
+	// OperatorAny matches if any of the children checks match.
+	OperatorAny Operator = "any"
+	// OperatorNot matches if the child check does not match.
+	OperatorNot Operator = "not"
+	// OperatorAfter matches the items after the one matched by the children checks.
+	OperatorAfter Operator = "after"
+	// OperatorBefore matches the items before the one matched by the children checks.
+	OperatorBefore Operator = "before"
+)
+
+// Value represents the value that a leaf Check compares against.
+// Its concrete type depends on the Operator:
+//   - [vocab.IRI] for the OperatorEquals checks on IRI properties, and for OperatorAuthorized,
+//   - string for the OperatorEquals checks on natural language values, and for OperatorLike,
+//     OperatorMatches and OperatorGlob,
+//   - [vocab.ActivityVocabularyTypes] for OperatorIn,
+//   - float64 for OperatorGt, OperatorGte, OperatorLt and OperatorLte, and [2]float64 for OperatorBetween,
+//   - [language.Tag] for OperatorLanguage,
+//   - int for OperatorMaxItems,
+//   - nil for OperatorNil.
+type Value any
+
+// Leaf is implemented by the checks that don't contain other checks.
+//
+// It allows packages outside this one to translate checks to the query language of their storage backends.
+type Leaf interface {
+	Check
+	// Leaf returns the property the check applies on, the comparison operator and the value compared against.
+	Leaf() (Field, Operator, Value)
+}
+
+// Localized is implemented by the natural language value checks, like NameIs or ContentLike,
+// which can be restricted to the values in a specific language.
+type Localized interface {
+	Leaf
+	// Language returns the language the check is restricted to, or [language.Und] if it applies to all of them.
+	Language() language.Tag
+}
+
+// Collated is implemented by the checks that compare the values using a Collation, which can be
+// configured with WithCollation: the natural language values checks, and the Like checks on IRI properties.
+type Collated interface {
+	Leaf
+	// Collation returns the differences between the values that are ignored when comparing them.
+	Collation() Collation
+}
+
+// Composite is implemented by the checks that contain other checks.
+//
+// For the Any, All and Not aggregators and the After and Before pagination checks the Field is FieldNone.
+// For the Actor, Object, Target and Tag checks the Field represents the property of the item that
+// the children checks get applied on, and the Operator is OperatorAll.
+type Composite interface {
+	Check
+	// Combinator returns the property the children checks are applied on, and how their results get combined.
+	Combinator() (Field, Operator)
+	// Children returns the checks contained by the composite.
+	Children() []Check
+}
@@ -0,0 +1,382 @@
+package filters
+
+import (
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/text/language"
+)
+
+func TestLeaf_Leaf(t *testing.T) {
+	tests := []struct {
+		name      string
+		check     Check
+		wantField Field
+		wantOp    Operator
+		wantValue Value
+	}{
+		{
+			name:      "SameID",
+			check:     SameID("https://example.com"),
+			wantField: FieldID,
+			wantOp:    OperatorEquals,
+			wantValue: vocab.IRI("https://example.com"),
+		},
+		{
+			name:      "IDLike",
+			check:     IDLike("example"),
+			wantField: FieldID,
+			wantOp:    OperatorLike,
+			wantValue: "example",
+		},
+		{
+			name:      "NilID",
+			check:     NilID,
+			wantField: FieldID,
+			wantOp:    OperatorNil,
+		},
+		{
+			name:      "SameIRI",
+			check:     SameIRI("https://example.com"),
+			wantField: FieldIRI,
+			wantOp:    OperatorEquals,
+			wantValue: vocab.IRI("https://example.com"),
+		},
+		{
+			name:      "IRILike",
+			check:     IRILike("example"),
+			wantField: FieldIRI,
+			wantOp:    OperatorLike,
+			wantValue: "example",
+		},
+		{
+			name:      "NilIRI",
+			check:     NilIRI,
+			wantField: FieldIRI,
+			wantOp:    OperatorNil,
+		},
+		{
+			name:      "NilItem",
+			check:     NilItem,
+			wantField: FieldNone,
+			wantOp:    OperatorNil,
+		},
+		{
+			name:      "SameURL",
+			check:     SameURL("https://example.com"),
+			wantField: FieldURL,
+			wantOp:    OperatorEquals,
+			wantValue: vocab.IRI("https://example.com"),
+		},
+		{
+			name:      "URLLike",
+			check:     URLLike("example"),
+			wantField: FieldURL,
+			wantOp:    OperatorLike,
+			wantValue: "example",
+		},
+		{
+			name:      "NilURL",
+			check:     NilURL,
+			wantField: FieldURL,
+			wantOp:    OperatorNil,
+		},
+		{
+			name:      "SameContext",
+			check:     SameContext("https://example.com"),
+			wantField: FieldContext,
+			wantOp:    OperatorEquals,
+			wantValue: vocab.IRI("https://example.com"),
+		},
+		{
+			name:      "ContextLike",
+			check:     ContextLike("example"),
+			wantField: FieldContext,
+			wantOp:    OperatorLike,
+			wantValue: "example",
+		},
+		{
+			name:      "NilContext",
+			check:     NilContext,
+			wantField: FieldContext,
+			wantOp:    OperatorNil,
+		},
+		{
+			name:      "SameAttributedTo",
+			check:     SameAttributedTo("https://example.com"),
+			wantField: FieldAttributedTo,
+			wantOp:    OperatorEquals,
+			wantValue: vocab.IRI("https://example.com"),
+		},
+		{
+			name:      "AttributedToLike",
+			check:     AttributedToLike("example"),
+			wantField: FieldAttributedTo,
+			wantOp:    OperatorLike,
+			wantValue: "example",
+		},
+		{
+			name:      "NilAttributedTo",
+			check:     NilAttributedTo,
+			wantField: FieldAttributedTo,
+			wantOp:    OperatorNil,
+		},
+		{
+			name:      "SameInReplyTo",
+			check:     SameInReplyTo("https://example.com"),
+			wantField: FieldInReplyTo,
+			wantOp:    OperatorEquals,
+			wantValue: vocab.IRI("https://example.com"),
+		},
+		{
+			name:      "InReplyToLike",
+			check:     InReplyToLike("example"),
+			wantField: FieldInReplyTo,
+			wantOp:    OperatorLike,
+			wantValue: "example",
+		},
+		{
+			name:      "NilInReplyTo",
+			check:     NilInReplyTo,
+			wantField: FieldInReplyTo,
+			wantOp:    OperatorNil,
+		},
+		{
+			name:      "NameIs",
+			check:     NameIs("jdoe"),
+			wantField: FieldName,
+			wantOp:    OperatorEquals,
+			wantValue: "jdoe",
+		},
+		{
+			name:      "PreferredUsernameLike",
+			check:     PreferredUsernameLike("jdoe"),
+			wantField: FieldPreferredUsername,
+			wantOp:    OperatorLike,
+			wantValue: "jdoe",
+		},
+		{
+			name:      "SummaryEmpty",
+			check:     SummaryEmpty,
+			wantField: FieldSummary,
+			wantOp:    OperatorNil,
+		},
+		{
+			name:      "ContentLike",
+			check:     ContentLike("test"),
+			wantField: FieldContent,
+			wantOp:    OperatorLike,
+			wantValue: "test",
+		},
+		{
+			name:      "HasType",
+			check:     HasType(vocab.NoteType, vocab.ArticleType),
+			wantField: FieldType,
+			wantOp:    OperatorIn,
+			wantValue: vocab.ActivityVocabularyTypes{vocab.NoteType, vocab.ArticleType},
+		},
+		{
+			name:      "Recipients",
+			check:     Recipients("https://example.com/~jdoe"),
+			wantField: FieldRecipients,
+			wantOp:    OperatorEquals,
+			wantValue: vocab.IRI("https://example.com/~jdoe"),
+		},
+		{
+			name:      "IsPublic",
+			check:     IsPublic(),
+			wantField: FieldRecipients,
+			wantOp:    OperatorEquals,
+			wantValue: vocab.PublicNS,
+		},
+		{
+			name:      "Authorized",
+			check:     Authorized("https://example.com/~jdoe"),
+			wantField: FieldNone,
+			wantOp:    OperatorAuthorized,
+			wantValue: vocab.IRI("https://example.com/~jdoe"),
+		},
+		{
+			name:      "WithMaxCount",
+			check:     WithMaxCount(10),
+			wantField: FieldNone,
+			wantOp:    OperatorMaxItems,
+			wantValue: 10,
+		},
+		{
+			name:      "Gt",
+			check:     Gt("replies.totalItems", 10),
+			wantField: Field("replies.totalItems"),
+			wantOp:    OperatorGt,
+			wantValue: 10.0,
+		},
+		{
+			name:      "Between",
+			check:     Between("width", 10, 20),
+			wantField: Field("width"),
+			wantOp:    OperatorBetween,
+			wantValue: [2]float64{10, 20},
+		},
+		{
+			name:      "HasLanguage",
+			check:     HasLanguage(language.French),
+			wantField: FieldNone,
+			wantOp:    OperatorLanguage,
+			wantValue: language.French,
+		},
+		{
+			name:      "IDMatches",
+			check:     MustIDMatches(`^https://`),
+			wantField: FieldID,
+			wantOp:    OperatorMatches,
+			wantValue: `^https://`,
+		},
+		{
+			name:      "NameGlob",
+			check:     MustNameGlob("j*"),
+			wantField: FieldName,
+			wantOp:    OperatorGlob,
+			wantValue: "j*",
+		},
+		{
+			name:      "HasHashtag",
+			check:     HasHashtag("#GoLang"),
+			wantField: FieldHashtag,
+			wantOp:    OperatorEquals,
+			wantValue: "golang",
+		},
+		{
+			name:      "Mentions",
+			check:     Mentions("https://example.com/~jdoe"),
+			wantField: FieldMention,
+			wantOp:    OperatorEquals,
+			wantValue: vocab.IRI("https://example.com/~jdoe"),
+		},
+		{
+			name:      "HasEmoji",
+			check:     HasEmoji(":blob:"),
+			wantField: FieldEmoji,
+			wantOp:    OperatorEquals,
+			wantValue: "blob",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaf, ok := tt.check.(Leaf)
+			if !ok {
+				t.Fatalf("%T does not implement Leaf", tt.check)
+			}
+			field, op, value := leaf.Leaf()
+			if field != tt.wantField {
+				t.Errorf("Leaf() field = %q, want %q", field, tt.wantField)
+			}
+			if op != tt.wantOp {
+				t.Errorf("Leaf() operator = %q, want %q", op, tt.wantOp)
+			}
+			tagComparer := cmp.Comparer(func(a, b language.Tag) bool { return a == b })
+			if !cmp.Equal(value, tt.wantValue, tagComparer) {
+				t.Errorf("Leaf() value = %s", cmp.Diff(tt.wantValue, value, tagComparer))
+			}
+			if _, ok := tt.check.(Composite); ok {
+				t.Errorf("%T implements both Leaf and Composite", tt.check)
+			}
+		})
+	}
+}
+
+func TestComposite_Children(t *testing.T) {
+	children := []Check{SameID("https://example.com"), NameIs("jdoe")}
+	tests := []struct {
+		name      string
+		check     Check
+		wantField Field
+		wantOp    Operator
+		want      []Check
+	}{
+		{
+			name:      "All",
+			check:     All(children...),
+			wantField: FieldNone,
+			wantOp:    OperatorAll,
+			want:      children,
+		},
+		{
+			name:      "Any",
+			check:     Any(children...),
+			wantField: FieldNone,
+			wantOp:    OperatorAny,
+			want:      children,
+		},
+		{
+			name:      "Not",
+			check:     Not(children[0]),
+			wantField: FieldNone,
+			wantOp:    OperatorNot,
+			want:      children[:1],
+		},
+		{
+			name:      "Actor",
+			check:     Actor(children...),
+			wantField: FieldActor,
+			wantOp:    OperatorAll,
+			want:      children,
+		},
+		{
+			name:      "Object",
+			check:     Object(children...),
+			wantField: FieldObject,
+			wantOp:    OperatorAll,
+			want:      children,
+		},
+		{
+			name:      "Target",
+			check:     Target(children...),
+			wantField: FieldTarget,
+			wantOp:    OperatorAll,
+			want:      children,
+		},
+		{
+			name:      "Tag",
+			check:     Tag(children...),
+			wantField: FieldTag,
+			wantOp:    OperatorAll,
+			want:      children,
+		},
+		{
+			name:      "After",
+			check:     After(children...),
+			wantField: FieldNone,
+			wantOp:    OperatorAfter,
+			want:      children,
+		},
+		{
+			name:      "Before",
+			check:     Before(children...),
+			wantField: FieldNone,
+			wantOp:    OperatorBefore,
+			want:      children,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comp, ok := tt.check.(Composite)
+			if !ok {
+				t.Fatalf("%T does not implement Composite", tt.check)
+			}
+			field, op := comp.Combinator()
+			if field != tt.wantField {
+				t.Errorf("Combinator() field = %q, want %q", field, tt.wantField)
+			}
+			if op != tt.wantOp {
+				t.Errorf("Combinator() operator = %q, want %q", op, tt.wantOp)
+			}
+			if got := comp.Children(); !Equal(checkAll(got), checkAll(tt.want)) {
+				t.Errorf("Children() = %s", cmp.Diff(canonicalString(checkAll(tt.want)), canonicalString(checkAll(got))))
+			}
+			if _, ok := tt.check.(Leaf); ok {
+				t.Errorf("%T implements both Leaf and Composite", tt.check)
+			}
+		})
+	}
+}
@@ -1,11 +1,12 @@
 package filters
 
 import (
-	"bytes"
 	"net/url"
+	"strings"
 
 	vocab "github.com/go-ap/activitypub"
-	"golang.org/x/text/unicode/norm"
+	"github.com/go-ap/filters/index"
+	"golang.org/x/text/language"
 )
 
 type nlvType uint8
@@ -17,30 +18,123 @@ const (
 	byContent
 )
 
+// nlvOp represents the comparison operator that a naturalLanguageValCheck applies.
+// We store it as a plain value, instead of the comparison function, so the checks can be
+// compared using Equal, or Canonical, and translated to other query languages.
+// The checks can't be compared with ==, as they contain the language matcher.
+type nlvOp uint8
+
+const (
+	nlvEquals nlvOp = iota
+	nlvLike
+	nlvEmpty
+)
+
 type naturalLanguageValCheck struct {
 	checkValue string
-	checkFn    naturalLanguageValuesCheckFn
-	accumFn    func(vocab.Item) []vocab.NaturalLanguageValues
+	op         nlvOp
 	typ        nlvType
+	// lang restricts the check to the values in a specific language, when it's not [language.Und].
+	lang language.Tag
+	// matcher matches the languages of the values against lang, see newLanguageMatcher.
+	matcher language.Matcher
+	// text holds the options for converting the HTML values to plain text before checking them.
+	// If it's 0, the values are checked as they are.
+	text TextOptions
+	// collation holds the differences between the values that are ignored when comparing them.
+	collation Collation
+	// collated is set when the collation has been configured using WithCollation, and only then
+	// the SQL conditions fold the values, see collatedExpr.
+	collated bool
 }
 
 func (n naturalLanguageValCheck) Match(it vocab.Item) bool {
-	return n.checkFn(n.accumFn(it), n.checkValue)
+	return n.matchValues(n.accumFn()(it))
+}
+
+// matchValues checks the natural language values of a property, after restricting them to the language
+// of the check and converting them to text.
+func (n naturalLanguageValCheck) matchValues(toCheck []vocab.NaturalLanguageValues) bool {
+	if n.lang != language.Und {
+		toCheck = filterLanguage(toCheck, n.languageMatcher())
+	}
+	if n.text != 0 {
+		toCheck = plainTextValues(toCheck, n.text)
+	}
+	return n.checkFn()(toCheck, n.checkValue, n.collation)
+}
+
+// Language returns the language the check is restricted to, or [language.Und] if it applies to all of them.
+func (n naturalLanguageValCheck) Language() language.Tag {
+	return n.lang
+}
+
+// Collation returns the collation used for comparing the values.
+func (n naturalLanguageValCheck) Collation() Collation {
+	return n.collation
+}
+
+func (n naturalLanguageValCheck) checkFn() naturalLanguageValuesCheckFn {
+	switch n.op {
+	case nlvLike:
+		return naturalLanguageValuesLike
+	case nlvEmpty:
+		return naturalLanguageEmpty
+	default:
+		return naturalLanguageValuesEquals
+	}
+}
+
+func (n naturalLanguageValCheck) accumFn() func(vocab.Item) []vocab.NaturalLanguageValues {
+	switch n.typ {
+	case byPreferredUsername:
+		return loadPreferredUsername
+	case bySummary:
+		return loadSummary
+	case byContent:
+		return loadContent
+	default:
+		return loadName
+	}
+}
+
+func (n naturalLanguageValCheck) Leaf() (Field, Operator, Value) {
+	var field Field
+	switch n.typ {
+	case byPreferredUsername:
+		field = FieldPreferredUsername
+	case bySummary:
+		field = FieldSummary
+	case byContent:
+		field = FieldContent
+	default:
+		field = FieldName
+	}
+	switch n.op {
+	case nlvLike:
+		return field, OperatorLike, n.checkValue
+	case nlvEmpty:
+		return field, OperatorNil, nil
+	default:
+		return field, OperatorEquals, n.checkValue
+	}
 }
 
 // NameIs checks an [vocab.Object]'s Name, or, in the case of an [vocab.Actor]
 // also the PreferredUsername against the "name" value.
 // If any of the Language Ref map values match the value, the function returns true.
-func NameIs(name string) Check {
-	return nameCheck(name, naturalLanguageValuesEquals)
+// If languages are passed, only the values in those languages are checked.
+func NameIs(name string, lang ...language.Tag) Check {
+	return withLanguages(nameCheck(name, nlvEquals), lang)
 }
 
 // NameLike checks an [vocab.Object]'s Name, or, in the case of an [vocab.Actor]
 // // also the PreferredUsername against the "name" value.
 // If any of the Language Ref map values contains the value as a substring,
 // the function returns true.
-func NameLike(name string) Check {
-	return nameCheck(name, naturalLanguageValuesLike)
+// If languages are passed, only the values in those languages are checked.
+func NameLike(name string, lang ...language.Tag) Check {
+	return withLanguages(nameCheck(name, nlvLike), lang)
 }
 
 // NameEmpty checks an [vocab.Object]'s Name, *and*, in the case of an [vocab.Actor]
@@ -48,19 +142,25 @@ func NameLike(name string) Check {
 // If *all* of the values are empty, the function returns true.
 //
 // Please note that the logic of this check is different from NameIs and NameLike.
-var NameEmpty = nameCheck("", naturalLanguageEmpty)
+var NameEmpty Check = nameCheck("", nlvEmpty)
 
 // ContentIs checks an [vocab.Object]'s Content against the "cont" value.
 // If any of the Language Ref map values match the value, the function returns true.
-func ContentIs(cont string) Check {
-	return contentCheck(cont, naturalLanguageValuesEquals)
+// The HTML values are compared using their visible text, so the markup doesn't match, eg: "strong" doesn't match
+// "<strong>Go</strong>". Use WithTextOptions with TextRaw for comparing the raw values, including their markup.
+// If languages are passed, only the values in those languages are checked.
+func ContentIs(cont string, lang ...language.Tag) Check {
+	return withLanguages(contentCheck(cont, nlvEquals), lang)
 }
 
 // ContentLike checks an [vocab.Object]'s Content property against the "cont" value.
 // If any of the Language Ref map values contains the value as a substring,
 // the function returns true.
-func ContentLike(cont string) Check {
-	return contentCheck(cont, naturalLanguageValuesLike)
+// The HTML values are compared using their visible text, so the markup doesn't match, eg: "strong" doesn't match
+// "<strong>Go</strong>". Use WithTextOptions with TextRaw for comparing the raw values, including their markup.
+// If languages are passed, only the values in those languages are checked.
+func ContentLike(cont string, lang ...language.Tag) Check {
+	return withLanguages(contentCheck(cont, nlvLike), lang)
 }
 
 // ContentEmpty checks an [vocab.Object]'s Content, *and*, in the case of an [vocab.Actor]
@@ -68,19 +168,25 @@ func ContentLike(cont string) Check {
 // If *all* of the values are empty, the function returns true.
 //
 // Please note that the logic of this check is different from ContentIs and ContentLike.
-var ContentEmpty = contentCheck("", naturalLanguageEmpty)
+var ContentEmpty Check = contentCheck("", nlvEmpty)
 
 // SummaryIs checks an [vocab.Object]'s Summary against the "sum" value.
 // If any of the Language Ref map values match the value, the function returns true.
-func SummaryIs(sum string) Check {
-	return summaryCheck(sum, naturalLanguageValuesEquals)
+// The HTML values are compared using their visible text, so the markup doesn't match, eg: "strong" doesn't match
+// "<strong>Go</strong>". Use WithTextOptions with TextRaw for comparing the raw values, including their markup.
+// If languages are passed, only the values in those languages are checked.
+func SummaryIs(sum string, lang ...language.Tag) Check {
+	return withLanguages(summaryCheck(sum, nlvEquals), lang)
 }
 
 // SummaryLike checks an [vocab.Object]'s Summary property against the "sum" value.
 // If any of the Language Ref map values contains the value as a substring,
 // the function returns true.
-func SummaryLike(sum string) Check {
-	return summaryCheck(sum, naturalLanguageValuesLike)
+// The HTML values are compared using their visible text, so the markup doesn't match, eg: "strong" doesn't match
+// "<strong>Go</strong>". Use WithTextOptions with TextRaw for comparing the raw values, including their markup.
+// If languages are passed, only the values in those languages are checked.
+func SummaryLike(sum string, lang ...language.Tag) Check {
+	return withLanguages(summaryCheck(sum, nlvLike), lang)
 }
 
 // SummaryEmpty checks an [vocab.Object]'s Summary, *and*, in the case of an [vocab.Actor]
@@ -88,15 +194,14 @@ func SummaryLike(sum string) Check {
 // If *all* of the values are empty, the function returns true.
 //
 // Please note that the logic of this check is different from SummaryIs and SummaryLike.
-var SummaryEmpty = summaryCheck("", naturalLanguageEmpty)
-
-func naturalLanguageValuesEquals(check []vocab.NaturalLanguageValues, val string) bool {
-	nfc := norm.NFC.Bytes
+var SummaryEmpty Check = summaryCheck("", nlvEmpty)
 
+func naturalLanguageValuesEquals(check []vocab.NaturalLanguageValues, val string, coll Collation) bool {
 	val, _ = url.QueryUnescape(val)
+	val = index.Fold(val, coll)
 	for _, nlv := range check {
 		for _, c := range nlv {
-			if c != nil && bytes.EqualFold(nfc(c), nfc([]byte(val))) {
+			if c != nil && index.Fold(string(c), coll) == val {
 				return true
 			}
 		}
@@ -104,7 +209,7 @@ func naturalLanguageValuesEquals(check []vocab.NaturalLanguageValues, val string
 	return false
 }
 
-func naturalLanguageEmpty(check []vocab.NaturalLanguageValues, _ string) bool {
+func naturalLanguageEmpty(check []vocab.NaturalLanguageValues, _ string, _ Collation) bool {
 	cnt := 0
 	for _, nlv := range check {
 		cnt += len(nlv)
@@ -112,13 +217,12 @@ func naturalLanguageEmpty(check []vocab.NaturalLanguageValues, _ string) bool {
 	return cnt == 0
 }
 
-func naturalLanguageValuesLike(check []vocab.NaturalLanguageValues, val string) bool {
-	nfc := norm.NFC.Bytes
-
+func naturalLanguageValuesLike(check []vocab.NaturalLanguageValues, val string, coll Collation) bool {
 	val, _ = url.QueryUnescape(val)
+	val = index.Fold(val, coll)
 	for _, nlv := range check {
 		for _, c := range nlv {
-			if c != nil && bytes.Contains(nfc(c), nfc([]byte(val))) {
+			if c != nil && strings.Contains(index.Fold(string(c), coll), val) {
 				return true
 			}
 		}
@@ -126,14 +230,171 @@ func naturalLanguageValuesLike(check []vocab.NaturalLanguageValues, val string)
 	return false
 }
 
-type naturalLanguageValuesCheckFn func([]vocab.NaturalLanguageValues, string) bool
+// TextOptions configures how the HTML of the natural language values is converted to plain text.
+// See [index.TextOptions] for the available options.
+type TextOptions = index.TextOptions
+
+const (
+	TextVisibleOnly    = index.TextVisibleOnly
+	TextIgnoreHashtags = index.TextIgnoreHashtags
+	TextIgnoreMentions = index.TextIgnoreMentions
+	TextDecodeEntities = index.TextDecodeEntities
+	// TextRaw disables the conversion, and the values get checked including their markup.
+	TextRaw TextOptions = 0
+)
+
+// WithTextOptions returns a copy of the c Check where the content and summary checks, including the regular
+// expression and glob ones, convert the HTML values to plain text using the opts options, eg:
+//
+//	WithTextOptions(TextVisibleOnly|TextIgnoreHashtags, ContentLike("golang"))
+//
+// matches only the items that mention "golang" outside their hashtags.
+func WithTextOptions(opts TextOptions, c Check) Check {
+	return Rewrite(c, func(c Check) (Check, bool) {
+		switch n := c.(type) {
+		case naturalLanguageValCheck:
+			if n.typ == byContent || n.typ == bySummary {
+				n.text = opts
+				return n, false
+			}
+		case patternCheck:
+			if n.field == FieldContent || n.field == FieldSummary {
+				n.text = opts
+				return n, false
+			}
+		}
+		return c, true
+	})
+}
+
+// nlvCollation returns the default Collation for the op operator, which is the same as the comparisons
+// of the natural language values used before the collations were configurable: the values are compared
+// ignoring their case, and they are searched for exactly.
+func nlvCollation(op nlvOp) Collation {
+	if op == nlvEquals {
+		return CaseFold
+	}
+	return ExactCollation
+}
+
+// nlvTextOptions returns the default TextOptions for the typ property.
+// Only "content" and "summary" can contain HTML.
+func nlvTextOptions(typ nlvType) TextOptions {
+	if typ == byContent || typ == bySummary {
+		return index.DefaultTextOptions
+	}
+	return TextRaw
+}
+
+// plainTextValues returns copies of the nlv values converted to plain text using the opts options.
+func plainTextValues(nlv []vocab.NaturalLanguageValues, opts TextOptions) []vocab.NaturalLanguageValues {
+	result := make([]vocab.NaturalLanguageValues, 0, len(nlv))
+	for _, vv := range nlv {
+		if len(vv) == 0 {
+			continue
+		}
+		txt := make(vocab.NaturalLanguageValues, len(vv))
+		for lang, v := range vv {
+			txt[lang] = vocab.Content(index.PlainText(string(v), opts))
+		}
+		result = append(result, txt)
+	}
+	return result
+}
+
+// withLanguages restricts the c check to the lang languages.
+// For multiple languages it returns an Any aggregate of the checks for each of them.
+func withLanguages(c naturalLanguageValCheck, lang []language.Tag) Check {
+	if len(lang) == 0 {
+		return c
+	}
+	fns := make([]Check, 0, len(lang))
+	for _, l := range lang {
+		c.lang = l
+		c.matcher = newLanguageMatcher(l)
+		fns = append(fns, c)
+	}
+	return Any(fns...)
+}
+
+// newLanguageMatcher returns the matcher for the values in the lang language, with fallbacks between
+// the regional variants, so "fr" matches "fr-CA", and "en-US" matches "en".
+func newLanguageMatcher(lang language.Tag) language.Matcher {
+	return language.NewMatcher([]language.Tag{lang})
+}
+
+// languageMatcher returns the matcher for the language of the check, which is built when creating the check.
+func (n naturalLanguageValCheck) languageMatcher() language.Matcher {
+	if n.matcher == nil {
+		return newLanguageMatcher(n.lang)
+	}
+	return n.matcher
+}
+
+// languageMatches checks if the "have" language tag of a value satisfies the language of the m matcher.
+func languageMatches(m language.Matcher, have language.Tag) bool {
+	_, _, conf := m.Match(have)
+	return conf >= language.High
+}
+
+// filterLanguage returns only the natural language values in the language of the m matcher.
+func filterLanguage(check []vocab.NaturalLanguageValues, m language.Matcher) []vocab.NaturalLanguageValues {
+	result := make([]vocab.NaturalLanguageValues, 0, len(check))
+	for _, nlv := range check {
+		filtered := make(vocab.NaturalLanguageValues)
+		for ref, c := range nlv {
+			if languageMatches(m, language.Tag(ref)) {
+				filtered[ref] = c
+			}
+		}
+		if len(filtered) > 0 {
+			result = append(result, filtered)
+		}
+	}
+	return result
+}
+
+// HasLanguage creates a filter that checks if any of the name, summary or content natural language values
+// of an item are in the lang language. It uses the same fallbacks between the regional variants as
+// the language restricted NameIs, ContentLike, etc. checks, so HasLanguage(language.French) matches "fr-CA" values.
+func HasLanguage(lang language.Tag) Check {
+	return languageCheck{lang: lang, matcher: newLanguageMatcher(lang)}
+}
+
+type languageCheck struct {
+	lang    language.Tag
+	matcher language.Matcher
+}
+
+func (l languageCheck) Match(it vocab.Item) bool {
+	if vocab.IsNil(it) {
+		return false
+	}
+	for _, load := range []func(vocab.Item) []vocab.NaturalLanguageValues{loadName, loadSummary, loadContent} {
+		if len(filterLanguage(load(it), l.matcher)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Leaf returns the FieldNone property, as the check applies on all the natural language values of the item.
+func (l languageCheck) Leaf() (Field, Operator, Value) {
+	return FieldNone, OperatorLanguage, l.lang
+}
+
+func (l languageCheck) GoString() string {
+	return "lang=" + l.lang.String()
+}
+
+type naturalLanguageValuesCheckFn func([]vocab.NaturalLanguageValues, string, Collation) bool
 
-func nameCheck(name string, checkFn naturalLanguageValuesCheckFn) Check {
+func nameCheck(name string, op nlvOp) naturalLanguageValCheck {
 	return naturalLanguageValCheck{
 		checkValue: name,
-		checkFn:    checkFn,
-		accumFn:    loadName,
+		op:         op,
 		typ:        byName,
+		collation:  nlvCollation(op),
 	}
 }
 
@@ -159,29 +420,31 @@ func loadName(it vocab.Item) []vocab.NaturalLanguageValues {
 
 // PreferredUsernameIs checks an [vocab.Actor]'s PreferredUsername against the "name" value.
 // If any of the Language Ref map values match the value, the function returns true.
-func PreferredUsernameIs(name string) Check {
-	return preferredUsername(name, naturalLanguageValuesEquals)
+// If languages are passed, only the values in those languages are checked.
+func PreferredUsernameIs(name string, lang ...language.Tag) Check {
+	return withLanguages(preferredUsername(name, nlvEquals), lang)
 }
 
 // PreferredUsernameLike checks an [vocab.Actor]'s PreferredUsername against the "name" value.
 // If any of the Language Ref map values contains the value as a substring,
 // the function returns true.
-func PreferredUsernameLike(name string) Check {
-	return preferredUsername(name, naturalLanguageValuesLike)
+// If languages are passed, only the values in those languages are checked.
+func PreferredUsernameLike(name string, lang ...language.Tag) Check {
+	return withLanguages(preferredUsername(name, nlvLike), lang)
 }
 
 // PreferredUsernameEmpty checks an [vocab.Actors]'s PreferredUsername to be empty.
 // If *all* of the values are empty, the function returns true.
 //
 // Please note that the logic of this check is different from PreferredUsernameIs and PreferredUsernameLike.
-var PreferredUsernameEmpty = preferredUsername("", naturalLanguageEmpty)
+var PreferredUsernameEmpty Check = preferredUsername("", nlvEmpty)
 
-func preferredUsername(name string, checkFn naturalLanguageValuesCheckFn) Check {
+func preferredUsername(name string, op nlvOp) naturalLanguageValCheck {
 	return naturalLanguageValCheck{
 		checkValue: name,
-		checkFn:    checkFn,
-		accumFn:    loadPreferredUsername,
+		op:         op,
 		typ:        byPreferredUsername,
+		collation:  nlvCollation(op),
 	}
 }
 
@@ -199,12 +462,13 @@ func loadPreferredUsername(it vocab.Item) []vocab.NaturalLanguageValues {
 	return toCheck
 }
 
-func contentCheck(content string, checkFn naturalLanguageValuesCheckFn) Check {
+func contentCheck(content string, op nlvOp) naturalLanguageValCheck {
 	return naturalLanguageValCheck{
 		checkValue: content,
-		checkFn:    checkFn,
-		accumFn:    loadContent,
+		op:         op,
 		typ:        byContent,
+		text:       nlvTextOptions(byContent),
+		collation:  nlvCollation(op),
 	}
 }
 
@@ -220,12 +484,13 @@ func loadContent(it vocab.Item) []vocab.NaturalLanguageValues {
 	return toCheck
 }
 
-func summaryCheck(summary string, checkFn naturalLanguageValuesCheckFn) Check {
+func summaryCheck(summary string, op nlvOp) naturalLanguageValCheck {
 	return naturalLanguageValCheck{
 		checkValue: summary,
-		checkFn:    checkFn,
-		accumFn:    loadSummary,
+		op:         op,
 		typ:        bySummary,
+		text:       nlvTextOptions(bySummary),
+		collation:  nlvCollation(op),
 	}
 }
 
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	vocab "github.com/go-ap/activitypub"
+	"golang.org/x/text/language"
 )
 
 func dnl(v ...string) vocab.NaturalLanguageValues {
@@ -580,3 +581,233 @@ func TestSummaryEmpty(t *testing.T) {
 		})
 	}
 }
+
+func lnl(kv ...string) vocab.NaturalLanguageValues {
+	nlv := make(vocab.NaturalLanguageValues)
+	for i := 0; i+1 < len(kv); i += 2 {
+		nlv[language.MustParse(kv[i])] = vocab.Content(kv[i+1])
+	}
+	return nlv
+}
+
+func TestNaturalLanguageValues_withLanguage(t *testing.T) {
+	content := lnl("en", "The cat", "fr-CA", "Le chat", "und", "chat")
+	tests := []struct {
+		name  string
+		check Check
+		item  vocab.Item
+		want  bool
+	}{
+		{
+			name:  "content like in language",
+			check: ContentLike("chat", language.French),
+			item:  &vocab.Object{Content: content},
+			want:  true,
+		},
+		{
+			name:  "content like in other language",
+			check: ContentLike("chat", language.English),
+			item:  &vocab.Object{Content: content},
+			want:  false,
+		},
+		{
+			name:  "content like in any of the languages",
+			check: ContentLike("chat", language.English, language.French),
+			item:  &vocab.Object{Content: content},
+			want:  true,
+		},
+		{
+			name:  "content like without language",
+			check: ContentLike("chat"),
+			item:  &vocab.Object{Content: content},
+			want:  true,
+		},
+		{
+			name:  "content is falls back to the regional variant",
+			check: ContentIs("Le chat", language.French),
+			item:  &vocab.Object{Content: content},
+			want:  true,
+		},
+		{
+			name:  "content is in a regional variant matches the base language",
+			check: ContentIs("The cat", language.AmericanEnglish),
+			item:  &vocab.Object{Content: content},
+			want:  true,
+		},
+		{
+			name:  "content is in language does not match undetermined values",
+			check: ContentIs("chat", language.French),
+			item:  &vocab.Object{Content: lnl("und", "chat")},
+			want:  false,
+		},
+		{
+			name:  "name is in language",
+			check: NameIs("Le chat", language.French),
+			item:  &vocab.Object{Name: content},
+			want:  true,
+		},
+		{
+			name:  "summary like in language",
+			check: SummaryLike("cat", language.German),
+			item:  &vocab.Object{Summary: content},
+			want:  false,
+		},
+		{
+			name:  "preferredUsername like in language",
+			check: PreferredUsernameLike("chat", language.French),
+			item:  &vocab.Actor{Type: vocab.PersonType, PreferredUsername: content},
+			want:  true,
+		},
+		{
+			name:  "empty in language",
+			check: nlvFilters(byContent, language.German).nilFn,
+			item:  &vocab.Object{Content: content},
+			want:  true,
+		},
+		{
+			name:  "not empty in language",
+			check: nlvFilters(byContent, language.English).nilFn,
+			item:  &vocab.Object{Content: content},
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check.Match(tt.item); got != tt.want {
+				t.Errorf("Match() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+	t.Run("matcher built with the check", func(t *testing.T) {
+		for _, check := range []Check{
+			ContentLike("chat", language.French),
+			nlvFilters(byContent, language.French).likeFn("chat"),
+			nlvFilters(byContent, language.French).nilFn,
+		} {
+			if c, ok := check.(naturalLanguageValCheck); !ok || c.matcher == nil {
+				t.Errorf("%#v has no language matcher", check)
+			}
+		}
+		if c, ok := HasLanguage(language.French).(languageCheck); !ok || c.matcher == nil {
+			t.Errorf("HasLanguage() has no language matcher")
+		}
+	})
+}
+
+func TestHasLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		lang language.Tag
+		item vocab.Item
+		want bool
+	}{
+		{
+			name: "nil item",
+			lang: language.French,
+			want: false,
+		},
+		{
+			name: "no values",
+			lang: language.French,
+			item: &vocab.Object{},
+			want: false,
+		},
+		{
+			name: "content in language",
+			lang: language.French,
+			item: &vocab.Object{Content: lnl("fr", "chat")},
+			want: true,
+		},
+		{
+			name: "summary in regional variant",
+			lang: language.French,
+			item: &vocab.Object{Summary: lnl("fr-CA", "chat")},
+			want: true,
+		},
+		{
+			name: "name in other language",
+			lang: language.French,
+			item: &vocab.Object{Name: lnl("en", "cat")},
+			want: false,
+		},
+		{
+			name: "undetermined language",
+			lang: language.French,
+			item: &vocab.Object{Content: lnl("und", "chat")},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasLanguage(tt.lang).Match(tt.item); got != tt.want {
+				t.Errorf("HasLanguage(%s).Match() = %t, want %t", tt.lang, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNaturalLanguageValues_html(t *testing.T) {
+	post := &vocab.Object{
+		Name:    lnl("und", "fish &amp; chips"),
+		Content: lnl("und", `<p>foo <strong>bar</strong> <a href="https://example.com/tags/baz" class="mention hashtag" rel="tag">#<span>baz</span></a></p>`),
+		Summary: lnl("und", "fish &amp; chips"),
+	}
+	tests := []struct {
+		name  string
+		check Check
+		want  bool
+	}{
+		{
+			name:  "markup does not match",
+			check: ContentLike("strong"),
+			want:  false,
+		},
+		{
+			name:  "text split across elements",
+			check: ContentLike("foo bar"),
+			want:  true,
+		},
+		{
+			name:  "whole text",
+			check: ContentIs("foo bar #baz"),
+			want:  true,
+		},
+		{
+			name:  "entities are decoded",
+			check: SummaryIs("fish & chips"),
+			want:  true,
+		},
+		{
+			name:  "raw markup",
+			check: WithTextOptions(TextRaw, ContentLike("strong")),
+			want:  true,
+		},
+		{
+			name:  "hashtag",
+			check: ContentLike("#baz"),
+			want:  true,
+		},
+		{
+			name:  "ignored hashtag",
+			check: WithTextOptions(TextVisibleOnly|TextIgnoreHashtags, ContentLike("baz")),
+			want:  false,
+		},
+		{
+			name:  "ignored hashtag in aggregate",
+			check: WithTextOptions(TextIgnoreHashtags, Any(ContentLike("baz"), SummaryLike("baz"))),
+			want:  false,
+		},
+		{
+			name:  "name is not converted",
+			check: WithTextOptions(TextDecodeEntities, NameIs("fish &amp; chips")),
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check.Match(post); got != tt.want {
+				t.Errorf("Match() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
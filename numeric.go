@@ -0,0 +1,183 @@
+package filters
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	vocab "github.com/go-ap/activitypub"
+)
+
+// numericCheck compares the numeric values of the property found at the dotted path against a value,
+// or, for the Between check, against a closed interval.
+type numericCheck struct {
+	path  string
+	op    Operator
+	value float64
+	max   float64
+}
+
+// Gt creates a filter that matches if the numeric value of the property found at the dotted path
+// of the item it gets applied on is greater than v, eg: Gt("totalItems", 10) or Gt("replies.totalItems", 10).
+// The numeric properties are: totalItems, startIndex, width, height and duration, the last one in seconds.
+// If a property has multiple values, the filter matches if any of them satisfies the comparison.
+func Gt(path string, v float64) Check {
+	return numericCheck{path: path, op: OperatorGt, value: v}
+}
+
+// Gte creates a filter that matches if the numeric value of the property found at the dotted path
+// is greater than or equal to v.
+func Gte(path string, v float64) Check {
+	return numericCheck{path: path, op: OperatorGte, value: v}
+}
+
+// Lt creates a filter that matches if the numeric value of the property found at the dotted path
+// is less than v.
+func Lt(path string, v float64) Check {
+	return numericCheck{path: path, op: OperatorLt, value: v}
+}
+
+// Lte creates a filter that matches if the numeric value of the property found at the dotted path
+// is less than or equal to v.
+func Lte(path string, v float64) Check {
+	return numericCheck{path: path, op: OperatorLte, value: v}
+}
+
+// Between creates a filter that matches if the numeric value of the property found at the dotted path
+// is greater than or equal to min and less than or equal to max.
+func Between(path string, min, max float64) Check {
+	return numericCheck{path: path, op: OperatorBetween, value: min, max: max}
+}
+
+func (n numericCheck) Match(it vocab.Item) bool {
+	if vocab.IsNil(it) {
+		return false
+	}
+	for _, v := range resolvePath(it, n.path) {
+		if f, ok := numericValue(v); ok && n.compare(f) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n numericCheck) compare(f float64) bool {
+	switch n.op {
+	case OperatorGt:
+		return f > n.value
+	case OperatorGte:
+		return f >= n.value
+	case OperatorLt:
+		return f < n.value
+	case OperatorLte:
+		return f <= n.value
+	case OperatorBetween:
+		return f >= n.value && f <= n.max
+	}
+	return false
+}
+
+// Leaf returns the path of the property, the comparison operator and the value as a float64,
+// or, for OperatorBetween, the interval as a [2]float64.
+func (n numericCheck) Leaf() (Field, Operator, Value) {
+	if n.op == OperatorBetween {
+		return Field(n.path), n.op, [2]float64{n.value, n.max}
+	}
+	return Field(n.path), n.op, n.value
+}
+
+func (n numericCheck) GoString() string {
+	return n.path + "=" + n.urlValue()
+}
+
+// urlValue returns the value of the check in the URL query parameter format.
+func (n numericCheck) urlValue() string {
+	format := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	switch n.op {
+	case OperatorGt:
+		return opGt + format(n.value)
+	case OperatorGte:
+		return opGte + format(n.value)
+	case OperatorLt:
+		return opLt + format(n.value)
+	case OperatorLte:
+		return opLte + format(n.value)
+	case OperatorBetween:
+		return format(n.value) + opBetween + format(n.max)
+	}
+	return ""
+}
+
+func numericValue(v any) (float64, bool) {
+	switch vv := v.(type) {
+	case uint:
+		return float64(vv), true
+	case time.Duration:
+		return vv.Seconds(), true
+	}
+	return 0, false
+}
+
+const (
+	opGt      = ">"
+	opGte     = ">="
+	opLt      = "<"
+	opLte     = "<="
+	opBetween = ".."
+)
+
+// parseNumericValue parses the numeric comparison URL values: ">10", ">=10", "<10", "<=10" and "10..100".
+func parseNumericValue(path, v string) (Check, bool) {
+	parse := func(s string) (float64, bool) {
+		f, err := strconv.ParseFloat(s, 64)
+		return f, err == nil
+	}
+	for _, op := range []string{opGte, opLte, opGt, opLt} {
+		if !strings.HasPrefix(v, op) {
+			continue
+		}
+		f, ok := parse(v[len(op):])
+		if !ok {
+			return nil, false
+		}
+		switch op {
+		case opGt:
+			return Gt(path, f), true
+		case opGte:
+			return Gte(path, f), true
+		case opLt:
+			return Lt(path, f), true
+		case opLte:
+			return Lte(path, f), true
+		}
+	}
+	if mn, mx, ok := strings.Cut(v, opBetween); ok {
+		fmin, okMin := parse(mn)
+		fmax, okMax := parse(mx)
+		if okMin && okMax {
+			return Between(path, fmin, fmax), true
+		}
+	}
+	return nil, false
+}
+
+// numericFilters builds the checks for the numeric comparison values of the property found at the dotted path.
+// It returns nil if any of the values is not a numeric comparison.
+// Unlike the rest of the URL values, multiple comparisons on the same property need to match all,
+// so ?totalItems=>10&totalItems=<100 represents an interval.
+func numericFilters(path string, vv ...string) Check {
+	f := make(Checks, 0, len(vv))
+	for _, v := range vv {
+		c, ok := parseNumericValue(path, v)
+		if !ok {
+			return nil
+		}
+		f = append(f, c)
+	}
+	if len(f) == 0 {
+		return nil
+	}
+	return All(f...)
+}
@@ -0,0 +1,167 @@
+package filters
+
+import (
+	"testing"
+	"time"
+
+	vocab "github.com/go-ap/activitypub"
+)
+
+func TestNumericChecks(t *testing.T) {
+	collection := &vocab.OrderedCollection{Type: vocab.OrderedCollectionType, TotalItems: 10}
+	tests := []struct {
+		name  string
+		check Check
+		item  vocab.Item
+		want  bool
+	}{
+		{
+			name:  "nil item",
+			check: Gt("totalItems", 1),
+			want:  false,
+		},
+		{
+			name:  "totalItems greater than",
+			check: Gt("totalItems", 9),
+			item:  collection,
+			want:  true,
+		},
+		{
+			name:  "totalItems not greater than",
+			check: Gt("totalItems", 10),
+			item:  collection,
+			want:  false,
+		},
+		{
+			name:  "totalItems greater than or equal",
+			check: Gte("totalItems", 10),
+			item:  collection,
+			want:  true,
+		},
+		{
+			name:  "totalItems less than",
+			check: Lt("totalItems", 11),
+			item:  collection,
+			want:  true,
+		},
+		{
+			name:  "totalItems not less than",
+			check: Lt("totalItems", 10),
+			item:  collection,
+			want:  false,
+		},
+		{
+			name:  "totalItems less than or equal",
+			check: Lte("totalItems", 10),
+			item:  collection,
+			want:  true,
+		},
+		{
+			name:  "totalItems between",
+			check: Between("totalItems", 10, 20),
+			item:  collection,
+			want:  true,
+		},
+		{
+			name:  "totalItems not between",
+			check: Between("totalItems", 11, 20),
+			item:  collection,
+			want:  false,
+		},
+		{
+			name:  "empty collection",
+			check: Lt("totalItems", 1),
+			item:  &vocab.Collection{Type: vocab.CollectionType},
+			want:  true,
+		},
+		{
+			name:  "link width",
+			check: Gte("width", 640),
+			item:  &vocab.Link{Type: vocab.LinkType, Width: 800, Height: 600},
+			want:  true,
+		},
+		{
+			name:  "link height",
+			check: Gte("height", 640),
+			item:  &vocab.Link{Type: vocab.LinkType, Width: 800, Height: 600},
+			want:  false,
+		},
+		{
+			name:  "missing width",
+			check: Lt("width", 640),
+			item:  &vocab.Link{Type: vocab.LinkType},
+			want:  false,
+		},
+		{
+			name:  "duration in seconds",
+			check: Between("duration", 60, 120),
+			item:  &vocab.Object{Type: vocab.VideoType, Duration: 90 * time.Second},
+			want:  true,
+		},
+		{
+			name:  "replies.totalItems",
+			check: Gt("replies.totalItems", 2),
+			item: &vocab.Object{
+				Type:    vocab.NoteType,
+				Replies: &vocab.Collection{Type: vocab.CollectionType, TotalItems: 3},
+			},
+			want: true,
+		},
+		{
+			name:  "question option votes",
+			check: Gte("oneOf.replies.totalItems", 5),
+			item: &vocab.Question{
+				Type: vocab.QuestionType,
+				OneOf: vocab.ItemCollection{
+					&vocab.Object{Type: vocab.NoteType, Replies: &vocab.Collection{Type: vocab.CollectionType, TotalItems: 1}},
+					&vocab.Object{Type: vocab.NoteType, Replies: &vocab.Collection{Type: vocab.CollectionType, TotalItems: 7}},
+				},
+			},
+			want: true,
+		},
+		{
+			name:  "not numeric property",
+			check: Gt("mediaType", 0),
+			item:  &vocab.Object{MediaType: "image/png"},
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check.Match(tt.item); got != tt.want {
+				t.Errorf("Match() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseNumericValue(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   Check
+		wantOk bool
+	}{
+		{value: ">10", want: Gt("totalItems", 10), wantOk: true},
+		{value: ">=10", want: Gte("totalItems", 10), wantOk: true},
+		{value: "<10.5", want: Lt("totalItems", 10.5), wantOk: true},
+		{value: "<=10", want: Lte("totalItems", 10), wantOk: true},
+		{value: "1..10", want: Between("totalItems", 1, 10), wantOk: true},
+		{value: "", wantOk: false},
+		{value: "10", wantOk: false},
+		{value: ">", wantOk: false},
+		{value: ">ten", wantOk: false},
+		{value: "1..", wantOk: false},
+		{value: "~10", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseNumericValue("totalItems", tt.value)
+			if ok != tt.wantOk {
+				t.Fatalf("parseNumericValue(%q) ok = %t, want %t", tt.value, ok, tt.wantOk)
+			}
+			if !Equal(got, tt.want) {
+				t.Errorf("parseNumericValue(%q) = %s, want %s", tt.value, canonicalString(got), canonicalString(tt.want))
+			}
+		})
+	}
+}
@@ -1,12 +1,6 @@
 package filters
 
-import (
-	"net/url"
-	"strings"
-
-	vocab "github.com/go-ap/activitypub"
-	"golang.org/x/text/unicode/norm"
-)
+import vocab "github.com/go-ap/activitypub"
 
 // NilID checks if the [vocab.Object]'s ID property matches any of the two magic values
 // that denote an empty value: [vocab.NilID] = "-", or [vocab.EmptyID] = ""
@@ -21,6 +15,10 @@ func (n idNil) Match(it vocab.Item) bool {
 	return vocab.IsNil(it) || Any(SameIRI(vocab.NilIRI), SameIRI(vocab.EmptyIRI)).Match(it.GetID())
 }
 
+func (n idNil) Leaf() (Field, Operator, Value) {
+	return FieldID, OperatorNil, nil
+}
+
 // SameID checks a [vocab.Object]'s ID property against the received iri.
 func SameID(i vocab.IRI) Check {
 	return idEquals(i)
@@ -43,6 +41,10 @@ func (i idEquals) Match(item vocab.Item) bool {
 	return item.GetID().Equals(vocab.IRI(i), false)
 }
 
+func (i idEquals) Leaf() (Field, Operator, Value) {
+	return FieldID, OperatorEquals, vocab.IRI(i)
+}
+
 func (i idEquals) GoString() string {
 	return `id=` + string(i)
 }
@@ -55,12 +57,18 @@ func IDLike(frag string) Check {
 type idLike iriLike
 
 func (l idLike) Match(item vocab.Item) bool {
+	return l.matchCollated(item, ExactCollation)
+}
+
+func (l idLike) matchCollated(item vocab.Item, coll Collation) bool {
 	if vocab.IsNil(item) {
 		return false
 	}
-	nfc := norm.NFC.String
-	fragStr, _ := url.QueryUnescape(string(l))
-	return strings.Contains(nfc(item.GetID().String()), nfc(fragStr))
+	return collatedContains(item.GetID().String(), string(l), coll)
+}
+
+func (l idLike) Leaf() (Field, Operator, Value) {
+	return FieldID, OperatorLike, string(l)
 }
 
 func (l idLike) GoString() string {
@@ -98,22 +106,32 @@ func (i urlEquals) Match(it vocab.Item) bool {
 	return accumURLs(it).Contains(vocab.IRI(i))
 }
 
+func (i urlEquals) Leaf() (Field, Operator, Value) {
+	return FieldURL, OperatorEquals, vocab.IRI(i)
+}
+
 type urlLike iriLike
 
 func (frag urlLike) Match(it vocab.Item) bool {
+	return frag.matchCollated(it, ExactCollation)
+}
+
+func (frag urlLike) matchCollated(it vocab.Item, coll Collation) bool {
 	if vocab.IsNil(it) {
 		return len(frag) == 0
 	}
-	nfc := norm.NFC.String
-	fragStr, _ := url.QueryUnescape(string(frag))
 	for _, u := range accumURLs(it) {
-		if strings.Contains(nfc(u.String()), nfc(fragStr)) {
+		if collatedContains(u.String(), string(frag), coll) {
 			return true
 		}
 	}
 	return false
 }
 
+func (frag urlLike) Leaf() (Field, Operator, Value) {
+	return FieldURL, OperatorLike, string(frag)
+}
+
 func URLLike(frag string) Check {
 	return urlLike(frag)
 }
@@ -138,6 +156,10 @@ func (frag urlNil) Match(it vocab.Item) bool {
 	return len(accumURLs(it)) > 0
 }
 
+func (frag urlNil) Leaf() (Field, Operator, Value) {
+	return FieldURL, OperatorNil, nil
+}
+
 func SameContext(iri vocab.IRI) Check {
 	return contextEquals(iri)
 }
@@ -152,6 +174,10 @@ func (c contextEquals) Match(it vocab.Item) bool {
 	return accumContexts(it).Contains(vocab.IRI(c))
 }
 
+func (c contextEquals) Leaf() (Field, Operator, Value) {
+	return FieldContext, OperatorEquals, vocab.IRI(c)
+}
+
 func ContextLike(frag string) Check {
 	return contextLike(frag)
 }
@@ -159,20 +185,25 @@ func ContextLike(frag string) Check {
 type contextLike iriLike
 
 func (c contextLike) Match(it vocab.Item) bool {
+	return c.matchCollated(it, ExactCollation)
+}
+
+func (c contextLike) matchCollated(it vocab.Item, coll Collation) bool {
 	if vocab.IsNil(it) {
 		return len(c) == 0
 	}
-
-	nfc := norm.NFC.String
-	fragStr, _ := url.QueryUnescape(string(c))
 	for _, u := range accumContexts(it) {
-		if strings.Contains(nfc(u.String()), nfc(fragStr)) {
+		if collatedContains(u.String(), string(c), coll) {
 			return true
 		}
 	}
 	return false
 }
 
+func (c contextLike) Leaf() (Field, Operator, Value) {
+	return FieldContext, OperatorLike, string(c)
+}
+
 var NilContext = contextNil{}
 
 type contextNil iriNil
@@ -184,6 +215,10 @@ func (c contextNil) Match(it vocab.Item) bool {
 	return len(accumContexts(it)) == 0
 }
 
+func (c contextNil) Leaf() (Field, Operator, Value) {
+	return FieldContext, OperatorNil, nil
+}
+
 func accumAttributedTos(item vocab.Item) vocab.IRIs {
 	var items vocab.ItemCollection
 	_ = vocab.OnObject(item, func(ob *vocab.Object) error {
@@ -209,6 +244,10 @@ func (a attributedToEquals) Match(it vocab.Item) bool {
 	return accumAttributedTos(it).Contains(vocab.IRI(a))
 }
 
+func (a attributedToEquals) Leaf() (Field, Operator, Value) {
+	return FieldAttributedTo, OperatorEquals, vocab.IRI(a)
+}
+
 // AttributedToLike creates a filter that checks the [vocab.IRI] against the attributedTo property of the item
 // it gets applied on using a similarity match.
 func AttributedToLike(frag string) Check {
@@ -218,19 +257,25 @@ func AttributedToLike(frag string) Check {
 type attributedToLike iriLike
 
 func (a attributedToLike) Match(it vocab.Item) bool {
+	return a.matchCollated(it, ExactCollation)
+}
+
+func (a attributedToLike) matchCollated(it vocab.Item, coll Collation) bool {
 	if vocab.IsNil(it) {
 		return len(a) == 0
 	}
-	nfc := norm.NFC.String
-	fragStr, _ := url.QueryUnescape(string(a))
 	for _, u := range accumAttributedTos(it) {
-		if strings.Contains(nfc(u.String()), nfc(fragStr)) {
+		if collatedContains(u.String(), string(a), coll) {
 			return true
 		}
 	}
 	return false
 }
 
+func (a attributedToLike) Leaf() (Field, Operator, Value) {
+	return FieldAttributedTo, OperatorLike, string(a)
+}
+
 var NilAttributedTo = attributedToNil{}
 
 type attributedToNil iriNil
@@ -242,6 +287,10 @@ func (a attributedToNil) Match(it vocab.Item) bool {
 	return len(accumAttributedTos(it)) == 0
 }
 
+func (a attributedToNil) Leaf() (Field, Operator, Value) {
+	return FieldAttributedTo, OperatorNil, nil
+}
+
 func accumInReplyTos(item vocab.Item) vocab.IRIs {
 	var iris vocab.ItemCollection
 	_ = vocab.OnObject(item, func(ob *vocab.Object) error {
@@ -262,6 +311,10 @@ func (c inReplyToNil) Match(it vocab.Item) bool {
 	return len(accumInReplyTos(it)) == 0
 }
 
+func (c inReplyToNil) Leaf() (Field, Operator, Value) {
+	return FieldInReplyTo, OperatorNil, nil
+}
+
 // InReplyToLike filters objects having the inReplyTo pattern match the fragment
 func InReplyToLike(frag string) Check {
 	return inReplyToLike(frag)
@@ -270,19 +323,25 @@ func InReplyToLike(frag string) Check {
 type inReplyToLike iriLike
 
 func (a inReplyToLike) Match(it vocab.Item) bool {
+	return a.matchCollated(it, ExactCollation)
+}
+
+func (a inReplyToLike) matchCollated(it vocab.Item, coll Collation) bool {
 	if vocab.IsNil(it) {
 		return len(a) == 0
 	}
-	nfc := norm.NFC.String
-	fragStr, _ := url.QueryUnescape(string(a))
 	for _, u := range accumInReplyTos(it) {
-		if strings.Contains(nfc(u.String()), nfc(fragStr)) {
+		if collatedContains(u.String(), string(a), coll) {
 			return true
 		}
 	}
 	return false
 }
 
+func (a inReplyToLike) Leaf() (Field, Operator, Value) {
+	return FieldInReplyTo, OperatorLike, string(a)
+}
+
 // SameInReplyTo checks an activitypub.Object's InReplyTo
 func SameInReplyTo(iri vocab.IRI) Check {
 	return inReplyToEquals(iri)
@@ -296,3 +355,7 @@ func (i inReplyToEquals) Match(it vocab.Item) bool {
 	}
 	return accumInReplyTos(it).Contains(vocab.IRI(i))
 }
+
+func (i inReplyToEquals) Leaf() (Field, Operator, Value) {
+	return FieldInReplyTo, OperatorEquals, vocab.IRI(i)
+}
@@ -0,0 +1,311 @@
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/filters/index"
+)
+
+const (
+	// maxPatternLength is the maximum length of the regular expressions and glob patterns.
+	maxPatternLength = 1024
+	// maxPatternInstructions limits the size of the compiled regular expressions, which for patterns
+	// with nested repetitions, like "((a{100}){100}){100}", can be much larger than their length.
+	maxPatternInstructions = 10_000
+)
+
+var (
+	// ErrPatternTooComplex is returned for the patterns that exceed the length or the size limits,
+	// as they can come from hostile input.
+	ErrPatternTooComplex = errors.New("pattern is too complex")
+	// ErrPatternNotSupported is returned by the URL values parsing for the patterns of the properties
+	// that can't be matched against them, eg: the natural language values restricted to a language.
+	ErrPatternNotSupported = errors.New("pattern is not supported for the property")
+)
+
+// compilePattern compiles the RE2 expr regular expression, rejecting the ones that exceed
+// the maxPatternLength and maxPatternInstructions limits, as they can come from hostile input.
+func compilePattern(expr string) (*regexp.Regexp, error) {
+	if len(expr) > maxPatternLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", ErrPatternTooComplex, maxPatternLength)
+	}
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	if len(prog.Inst) > maxPatternInstructions {
+		return nil, fmt.Errorf("%w: more than %d instructions", ErrPatternTooComplex, maxPatternInstructions)
+	}
+	return regexp.Compile(expr)
+}
+
+// globToRegexp returns the anchored regular expression corresponding to the glob pattern,
+// where "*" matches any sequence of characters, and everything else matches literally.
+func globToRegexp(glob string) string {
+	parts := strings.Split(glob, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return `^` + strings.Join(parts, `.*`) + `$`
+}
+
+// patternCheck matches the values of a property against a regular expression, or a glob pattern.
+// The expression is compiled when the check is created, and the resulting [regexp.Regexp] is safe for concurrent use.
+type patternCheck struct {
+	field Field
+	expr  string
+	glob  bool
+	// re is nil if expr is not a valid pattern, in which case the check doesn't match anything.
+	// The constructors don't return such checks, only FromValues and FromURL build them, so
+	// the invalid patterns in the URLs don't result in checks matching everything.
+	re *regexp.Regexp
+	// text holds the options for converting the HTML of the summary and content values to plain text,
+	// see WithTextOptions. If it's 0, the values are checked as they are.
+	text TextOptions
+}
+
+// newPatternCheck returns the check of the field property against expr, and the error of compiling it.
+// On error, the check is still returned, with a nil re, so it doesn't match anything.
+func newPatternCheck(field Field, expr string, glob bool) (patternCheck, error) {
+	c := patternCheck{field: field, expr: expr, glob: glob, text: fieldTextOptions(field)}
+	var err error
+	if glob {
+		c.re, err = compilePattern(globToRegexp(expr))
+	} else {
+		c.re, err = compilePattern(expr)
+	}
+	if err != nil {
+		return c, fmt.Errorf("invalid pattern %q for %s: %w", expr, field, err)
+	}
+	return c, nil
+}
+
+// patternCheckFn returns the result of newPatternCheck as a Check, which is nil on error.
+func patternCheckFn(field Field, expr string, glob bool) (Check, error) {
+	c, err := newPatternCheck(field, expr, glob)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// mustPatternCheck is like patternCheckFn, but it panics if the pattern is not valid.
+func mustPatternCheck(field Field, expr string, glob bool) Check {
+	c, err := patternCheckFn(field, expr, glob)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (p patternCheck) Match(it vocab.Item) bool {
+	if p.re == nil || vocab.IsNil(it) {
+		return false
+	}
+	for _, v := range fieldValues(it, p.field, p.text) {
+		if p.re.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Leaf returns the property, the OperatorMatches or OperatorGlob operator, and the pattern as a string.
+func (p patternCheck) Leaf() (Field, Operator, Value) {
+	if p.glob {
+		return p.field, OperatorGlob, p.expr
+	}
+	return p.field, OperatorMatches, p.expr
+}
+
+func (p patternCheck) GoString() string {
+	return string(p.field) + "=" + p.urlValue()
+}
+
+// urlValue returns the value of the check in the URL query parameter format: "=~expr" for the regular
+// expressions, and "*~glob" for the glob patterns.
+func (p patternCheck) urlValue() string {
+	if p.glob {
+		return opGlob + p.expr
+	}
+	return opRegexp + p.expr
+}
+
+// fieldTextOptions returns the default TextOptions for the values of the field property.
+// Only "content" and "summary" can contain HTML.
+func fieldTextOptions(field Field) TextOptions {
+	if field == FieldSummary || field == FieldContent {
+		return index.DefaultTextOptions
+	}
+	return TextRaw
+}
+
+// fieldValues returns the string values of the field property of the it item.
+// The HTML of the summary and content values gets converted to text using the text options, unless they are TextRaw.
+func fieldValues(it vocab.Item, field Field, text TextOptions) []string {
+	var iris vocab.IRIs
+	var nlvs []vocab.NaturalLanguageValues
+	switch field {
+	case FieldID:
+		iris = vocab.IRIs{it.GetID()}
+	case FieldIRI:
+		iris = vocab.IRIs{it.GetLink()}
+	case FieldURL:
+		iris = accumURLs(it)
+	case FieldAttributedTo:
+		iris = accumAttributedTos(it)
+	case FieldInReplyTo:
+		iris = accumInReplyTos(it)
+	case FieldContext:
+		iris = accumContexts(it)
+	case FieldName:
+		nlvs = loadName(it)
+	case FieldPreferredUsername:
+		nlvs = loadPreferredUsername(it)
+	case FieldSummary:
+		nlvs = loadSummary(it)
+	case FieldContent:
+		nlvs = loadContent(it)
+	}
+	if text != TextRaw {
+		nlvs = plainTextValues(nlvs, text)
+	}
+	result := make([]string, 0, len(iris))
+	for _, iri := range iris {
+		if iri != "" {
+			result = append(result, iri.String())
+		}
+	}
+	for _, nlv := range nlvs {
+		for _, v := range nlv {
+			result = append(result, v.String())
+		}
+	}
+	return result
+}
+
+// IDMatches creates a filter that checks the ID of the item against the expr RE2 regular expression,
+// eg: IDMatches(`^https://example\.com/objects/[0-9]+$`).
+// Like [regexp.Regexp.MatchString], the expression is not anchored, so it matches if any part of the ID matches.
+// Expressions that are not valid, or are too complex, result in an error, which wraps ErrPatternTooComplex
+// for the latter.
+func IDMatches(expr string) (Check, error) {
+	return patternCheckFn(FieldID, expr, false)
+}
+
+// MustIDMatches is like IDMatches, but it panics if the pattern is not valid.
+func MustIDMatches(expr string) Check {
+	return mustPatternCheck(FieldID, expr, false)
+}
+
+// IRIMatches creates a filter that checks the IRI of the item against the expr RE2 regular expression.
+// See IDMatches for details.
+func IRIMatches(expr string) (Check, error) {
+	return patternCheckFn(FieldIRI, expr, false)
+}
+
+// MustIRIMatches is like IRIMatches, but it panics if the pattern is not valid.
+func MustIRIMatches(expr string) Check {
+	return mustPatternCheck(FieldIRI, expr, false)
+}
+
+// URLMatches creates a filter that checks the URL property of the item against the expr RE2 regular expression.
+// See IDMatches for details.
+func URLMatches(expr string) (Check, error) {
+	return patternCheckFn(FieldURL, expr, false)
+}
+
+// MustURLMatches is like URLMatches, but it panics if the pattern is not valid.
+func MustURLMatches(expr string) Check {
+	return mustPatternCheck(FieldURL, expr, false)
+}
+
+// NameMatches creates a filter that checks the Name values of the item against the expr RE2 regular expression.
+// Like NameIs and NameLike, it also checks the PreferredUsername of actors.
+// See IDMatches for details.
+func NameMatches(expr string) (Check, error) {
+	return patternCheckFn(FieldName, expr, false)
+}
+
+// MustNameMatches is like NameMatches, but it panics if the pattern is not valid.
+func MustNameMatches(expr string) Check {
+	return mustPatternCheck(FieldName, expr, false)
+}
+
+// SummaryMatches creates a filter that checks the text of the Summary values of the item
+// against the expr RE2 regular expression. See IDMatches for details.
+// The markup of the HTML values is not matched, see WithTextOptions for matching the raw values.
+func SummaryMatches(expr string) (Check, error) {
+	return patternCheckFn(FieldSummary, expr, false)
+}
+
+// MustSummaryMatches is like SummaryMatches, but it panics if the pattern is not valid.
+func MustSummaryMatches(expr string) Check {
+	return mustPatternCheck(FieldSummary, expr, false)
+}
+
+// ContentMatches creates a filter that checks the text of the Content values of the item
+// against the expr RE2 regular expression. See IDMatches for details.
+// The markup of the HTML values is not matched, see WithTextOptions for matching the raw values.
+func ContentMatches(expr string) (Check, error) {
+	return patternCheckFn(FieldContent, expr, false)
+}
+
+// MustContentMatches is like ContentMatches, but it panics if the pattern is not valid.
+func MustContentMatches(expr string) Check {
+	return mustPatternCheck(FieldContent, expr, false)
+}
+
+// IDGlob creates a filter that checks the ID of the item against the glob pattern,
+// where "*" matches any sequence of characters, eg: IDGlob("https://*.example.com/actors/*").
+// Unlike the regular expressions, the pattern needs to match the whole ID.
+// Patterns that are too long result in an error wrapping ErrPatternTooComplex.
+func IDGlob(glob string) (Check, error) {
+	return patternCheckFn(FieldID, glob, true)
+}
+
+// MustIDGlob is like IDGlob, but it panics if the pattern is not valid.
+func MustIDGlob(glob string) Check {
+	return mustPatternCheck(FieldID, glob, true)
+}
+
+// IRIGlob creates a filter that checks the IRI of the item against the glob pattern. See IDGlob for details.
+func IRIGlob(glob string) (Check, error) {
+	return patternCheckFn(FieldIRI, glob, true)
+}
+
+// MustIRIGlob is like IRIGlob, but it panics if the pattern is not valid.
+func MustIRIGlob(glob string) Check {
+	return mustPatternCheck(FieldIRI, glob, true)
+}
+
+// URLGlob creates a filter that checks the URL property of the item against the glob pattern.
+// See IDGlob for details.
+func URLGlob(glob string) (Check, error) {
+	return patternCheckFn(FieldURL, glob, true)
+}
+
+// MustURLGlob is like URLGlob, but it panics if the pattern is not valid.
+func MustURLGlob(glob string) Check {
+	return mustPatternCheck(FieldURL, glob, true)
+}
+
+// NameGlob creates a filter that checks the Name values, and the PreferredUsername of actors, against the glob pattern.
+// See IDGlob for details.
+func NameGlob(glob string) (Check, error) {
+	return patternCheckFn(FieldName, glob, true)
+}
+
+// MustNameGlob is like NameGlob, but it panics if the pattern is not valid.
+func MustNameGlob(glob string) Check {
+	return mustPatternCheck(FieldName, glob, true)
+}
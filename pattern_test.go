@@ -0,0 +1,280 @@
+package filters
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+)
+
+func TestPatternChecks(t *testing.T) {
+	ob := &vocab.Object{
+		ID:      "https://social.example.com/actors/jdoe/objects/42",
+		Type:    vocab.NoteType,
+		Name:    lnl("en", "Lorem ipsum", "fr", "Le chat"),
+		Content: lnl("und", "<p>foo <b>bar</b> 2024</p>"),
+		URL:     vocab.IRI("https://example.com/@jdoe/42"),
+	}
+	tests := []struct {
+		name  string
+		check Check
+		item  vocab.Item
+		want  bool
+	}{
+		{
+			name:  "nil item",
+			check: MustIDMatches(`.*`),
+			want:  false,
+		},
+		{
+			name:  "id matches",
+			check: MustIDMatches(`/objects/[0-9]+$`),
+			item:  ob,
+			want:  true,
+		},
+		{
+			name:  "id does not match",
+			check: MustIDMatches(`^https://example\.com/`),
+			item:  ob,
+			want:  false,
+		},
+		{
+			name:  "iri matches",
+			check: MustIRIMatches(`^https://[a-z]+\.example\.com`),
+			item:  vocab.IRI("https://social.example.com/actors/jdoe"),
+			want:  true,
+		},
+		{
+			name:  "url matches",
+			check: MustURLMatches(`/@[a-z]+/`),
+			item:  ob,
+			want:  true,
+		},
+		{
+			name:  "name matches in any language",
+			check: MustNameMatches(`^Le ch`),
+			item:  ob,
+			want:  true,
+		},
+		{
+			name:  "name matches is case sensitive",
+			check: MustNameMatches(`^le ch`),
+			item:  ob,
+			want:  false,
+		},
+		{
+			name:  "name matches with case insensitive flag",
+			check: MustNameMatches(`(?i)^le ch`),
+			item:  ob,
+			want:  true,
+		},
+		{
+			name:  "content matches the text",
+			check: MustContentMatches(`foo bar \d{4}`),
+			item:  ob,
+			want:  true,
+		},
+		{
+			name:  "content does not match the markup",
+			check: MustContentMatches(`<b>`),
+			item:  ob,
+			want:  false,
+		},
+		{
+			name:  "raw content matches the markup",
+			check: WithTextOptions(TextRaw, MustContentMatches(`<b>`)),
+			item:  ob,
+			want:  true,
+		},
+		{
+			name:  "summary matches missing value",
+			check: MustSummaryMatches(`.*`),
+			item:  ob,
+			want:  false,
+		},
+		{
+			name:  "id glob",
+			check: MustIDGlob("https://*.example.com/actors/*"),
+			item:  ob,
+			want:  true,
+		},
+		{
+			name:  "id glob needs to match everything",
+			check: MustIDGlob("https://*.example.com/actors"),
+			item:  ob,
+			want:  false,
+		},
+		{
+			name:  "id glob is literal",
+			check: MustIDGlob("https://social.example.com/actors/jdoe/objects/4."),
+			item:  ob,
+			want:  false,
+		},
+		{
+			name:  "iri glob",
+			check: MustIRIGlob("https://social.example.com/*"),
+			item:  vocab.IRI("https://social.example.com/actors/jdoe"),
+			want:  true,
+		},
+		{
+			name:  "url glob",
+			check: MustURLGlob("*/@jdoe/*"),
+			item:  ob,
+			want:  true,
+		},
+		{
+			name:  "name glob",
+			check: MustNameGlob("Lorem*"),
+			item:  ob,
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check.Match(tt.item); got != tt.want {
+				t.Errorf("Match() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternChecks_concurrentUse(t *testing.T) {
+	check := MustIDMatches(`/objects/[0-9]+$`)
+	wg := sync.WaitGroup{}
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ob := &vocab.Object{ID: vocab.IRI("https://example.com/objects/" + strings.Repeat("1", i+1))}
+			if !check.Match(ob) {
+				t.Errorf("Match(%s) = false, want true", ob.ID)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPatternChecks_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      func(string) (Check, error)
+		mustFn  func(string) Check
+		expr    string
+		wantErr error
+	}{
+		{
+			name:   "invalid expression",
+			fn:     IDMatches,
+			mustFn: MustIDMatches,
+			expr:   `(`,
+		},
+		{
+			name:    "expression too complex",
+			fn:      NameMatches,
+			mustFn:  MustNameMatches,
+			expr:    strings.Repeat(`[a-z]{1000}`, 11),
+			wantErr: ErrPatternTooComplex,
+		},
+		{
+			name:    "glob too long",
+			fn:      URLGlob,
+			mustFn:  MustURLGlob,
+			expr:    strings.Repeat("*", maxPatternLength),
+			wantErr: ErrPatternTooComplex,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := tt.fn(tt.expr)
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if c != nil {
+				t.Errorf("check = %#v, want nil", c)
+			}
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Must variant didn't panic")
+				}
+			}()
+			tt.mustFn(tt.expr)
+		})
+	}
+}
+
+func Test_compilePattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr error
+		invalid bool
+	}{
+		{
+			name: "simple",
+			expr: `^https://example\.com/[0-9]+$`,
+		},
+		{
+			name:    "invalid",
+			expr:    `(`,
+			invalid: true,
+		},
+		{
+			name:    "too long",
+			expr:    strings.Repeat("a", maxPatternLength+1),
+			wantErr: ErrPatternTooComplex,
+		},
+		{
+			name:    "nested repetitions",
+			expr:    `((a{100}){100}){100}`,
+			invalid: true,
+		},
+		{
+			name:    "too many instructions",
+			expr:    strings.Repeat(`[a-z]{1000}`, 11),
+			wantErr: ErrPatternTooComplex,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := compilePattern(tt.expr)
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("compilePattern() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.invalid && err == nil {
+				t.Fatalf("compilePattern() expected error, got none")
+			}
+			if tt.wantErr == nil && !tt.invalid {
+				if err != nil {
+					t.Fatalf("compilePattern() unexpected error = %v", err)
+				}
+				if re == nil {
+					t.Errorf("compilePattern() returned nil regexp")
+				}
+			}
+		})
+	}
+}
+
+func Test_globToRegexp(t *testing.T) {
+	tests := []struct {
+		glob string
+		want string
+	}{
+		{glob: "", want: `^$`},
+		{glob: "*", want: `^.*$`},
+		{glob: "https://*.example.com/*", want: `^https://.*\.example\.com/.*$`},
+		{glob: "a+b?", want: `^a\+b\?$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.glob, func(t *testing.T) {
+			if got := globToRegexp(tt.glob); got != tt.want {
+				t.Errorf("globToRegexp(%q) = %q, want %q", tt.glob, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,526 @@
+package filters
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	vocab "github.com/go-ap/activitypub"
+)
+
+// Property creates a filter that applies the check on the values of the property found at the dotted path
+// of the item it gets applied on, eg: "mediaType", "icon.url", "endpoints.sharedInbox" or "source.mediaType".
+// The path segments are the JSON-LD names of the ActivityStreams properties.
+//
+// The filter matches if any of the property values matches the check. If the property has no value,
+// the check gets applied on a nil item, so Property("generator", NilItem) matches items without a generator.
+//
+// Properties that are not Items themselves, like "mediaType", "published" or "totalItems",
+// get converted to [vocab.IRI] values, so they can be checked using SameIRI, IRILike and NilIRI.
+// The natural language values, like "name", get converted to one [vocab.IRI] for each of their languages.
+// The timestamps use the RFC3339 format.
+func Property(path string, check Check) Check {
+	return propertyChecks{path: path, fns: []Check{check}}
+}
+
+type propertyChecks struct {
+	path string
+	fns  []Check
+}
+
+func (p propertyChecks) Match(it vocab.Item) bool {
+	if vocab.IsNil(it) {
+		return false
+	}
+	check := All(p.fns...)
+	values := resolvePath(it, p.path)
+	if len(values) == 0 {
+		return check.Match(nil)
+	}
+	for _, v := range values {
+		for _, vit := range valueAsItems(v) {
+			if check.Match(vit) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p propertyChecks) Combinator() (Field, Operator) {
+	return Field(p.path), OperatorAll
+}
+
+func (p propertyChecks) Children() []Check {
+	return p.fns
+}
+
+func (p propertyChecks) GoString() string {
+	ss := strings.Builder{}
+	ss.WriteString(keyProperty + "." + p.path + "={")
+	for i, fn := range p.fns {
+		if sss, ok := fn.(fmt.GoStringer); ok {
+			ss.WriteString(sss.GoString())
+		}
+		if i < len(p.fns)-1 {
+			ss.WriteRune(',')
+		}
+	}
+	ss.WriteString("}")
+	return ss.String()
+}
+
+// validPropertyPath checks that the dotted path contains only non-empty segments made of letters, digits and underscores,
+// so it can be safely used in the JSON paths of the SQL queries and in the quamina patterns.
+func validPropertyPath(path string) bool {
+	if len(path) == 0 {
+		return false
+	}
+	for _, name := range strings.Split(path, ".") {
+		if len(name) == 0 {
+			return false
+		}
+		for _, r := range name {
+			if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// resolvePath returns the values found at the dotted path of the "it" item.
+// When a property contains an item collection, the rest of the path is resolved for each of its items.
+func resolvePath(it vocab.Item, path string) []any {
+	if len(path) == 0 {
+		return nil
+	}
+	values := []any{it}
+	for _, name := range strings.Split(path, ".") {
+		next := make([]any, 0)
+		for _, v := range values {
+			next = append(next, propertyValues(v, name)...)
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		values = next
+	}
+	return values
+}
+
+// propertyValues returns the values of the "name" property of v.
+// Besides Items, v can be one of the property values that are not Items themselves,
+// but have properties of their own: [vocab.Endpoints] and [vocab.Source].
+func propertyValues(v any, name string) []any {
+	switch vv := v.(type) {
+	case *vocab.Endpoints:
+		return endpointsValues(vv, name)
+	case vocab.Source:
+		return sourceValues(vv, name)
+	case vocab.Item:
+		return itemValues(vv, name)
+	}
+	return nil
+}
+
+func endpointsValues(e *vocab.Endpoints, name string) []any {
+	if e == nil {
+		return nil
+	}
+	var values propValues
+	switch name {
+	case "uploadMedia":
+		values.add(e.UploadMedia)
+	case "oauthAuthorizationEndpoint":
+		values.add(e.OauthAuthorizationEndpoint)
+	case "oauthTokenEndpoint":
+		values.add(e.OauthTokenEndpoint)
+	case "provideClientKey":
+		values.add(e.ProvideClientKey)
+	case "signClientKey":
+		values.add(e.SignClientKey)
+	case "sharedInbox":
+		values.add(e.SharedInbox)
+	}
+	return values
+}
+
+func sourceValues(s vocab.Source, name string) []any {
+	var values propValues
+	switch name {
+	case keyContent:
+		values.add(s.Content)
+	case "mediaType":
+		values.add(s.MediaType)
+	}
+	return values
+}
+
+func itemValues(it vocab.Item, name string) []any {
+	if vocab.IsNil(it) || vocab.IsIRI(it) {
+		// NOTE(marius): we can't resolve properties on IRIs without dereferencing them.
+		return nil
+	}
+	var values propValues
+	if vocab.IsItemCollection(it) {
+		_ = vocab.OnItem(it, func(it vocab.Item) error {
+			values = append(values, itemValues(it, name)...)
+			return nil
+		})
+		return values
+	}
+	switch name {
+	case keyID:
+		values.add(it.GetID())
+		return values
+	case keyType:
+		if typ := it.GetType(); typ != nil {
+			for _, t := range typ.AsTypes() {
+				values.add(string(t))
+			}
+		}
+		return values
+	}
+	if it.IsLink() {
+		_ = vocab.OnLink(it, func(l *vocab.Link) error {
+			values = linkValues(l, name)
+			return nil
+		})
+		return values
+	}
+	_ = vocab.OnObject(it, func(ob *vocab.Object) error {
+		values = objectValues(ob, name)
+		return nil
+	})
+	if len(values) > 0 {
+		return values
+	}
+	switch name {
+	case keyPreferredUsername, "inbox", "outbox", "following", "followers", "liked", "endpoints", "streams":
+		_ = vocab.OnActor(it, func(act *vocab.Actor) error {
+			values = actorValues(act, name)
+			return nil
+		})
+	case keyActor, keyTarget, "result", "origin", "instrument":
+		_ = vocab.OnIntransitiveActivity(it, func(act *vocab.IntransitiveActivity) error {
+			values = intransitiveActivityValues(act, name)
+			return nil
+		})
+	case keyObject:
+		_ = vocab.OnActivity(it, func(act *vocab.Activity) error {
+			values.add(act.Object)
+			return nil
+		})
+	case "oneOf", "anyOf", "closed":
+		_ = vocab.OnQuestion(it, func(q *vocab.Question) error {
+			values = questionValues(q, name)
+			return nil
+		})
+	case "totalItems", "current", "first", "last", "items", "orderedItems", "partOf", "next", "prev", "startIndex":
+		values = collectionValues(it, name)
+	}
+	return values
+}
+
+func linkValues(l *vocab.Link, name string) propValues {
+	var values propValues
+	switch name {
+	case keyName:
+		values.add(l.Name)
+	case "href":
+		values.add(l.Href)
+	case "rel":
+		values.add(l.Rel)
+	case "mediaType":
+		values.add(l.MediaType)
+	case "hreflang":
+		values.add(l.HrefLang.String())
+	case "height":
+		values.add(l.Height)
+	case "width":
+		values.add(l.Width)
+	case "preview":
+		values.add(l.Preview)
+	}
+	return values
+}
+
+func objectValues(ob *vocab.Object, name string) propValues {
+	var values propValues
+	switch name {
+	case keyName:
+		values.add(ob.Name)
+	case keySummary:
+		values.add(ob.Summary)
+	case keyContent:
+		values.add(ob.Content)
+	case "mediaType":
+		values.add(ob.MediaType)
+	case keyURL:
+		values.add(ob.URL)
+	case keyAttributedTo:
+		values.add(ob.AttributedTo)
+	case keyInReplyTo:
+		values.add(ob.InReplyTo)
+	case keyContext:
+		values.add(ob.Context)
+	case keyTag:
+		values.add(ob.Tag)
+	case "attachment":
+		values.add(ob.Attachment)
+	case "audience":
+		values.add(ob.Audience)
+	case "generator":
+		values.add(ob.Generator)
+	case "icon":
+		values.add(ob.Icon)
+	case "image":
+		values.add(ob.Image)
+	case "location":
+		values.add(ob.Location)
+	case "preview":
+		values.add(ob.Preview)
+	case "replies":
+		values.add(ob.Replies)
+	case "likes":
+		values.add(ob.Likes)
+	case "shares":
+		values.add(ob.Shares)
+	case "to":
+		values.add(ob.To)
+	case "bto":
+		values.add(ob.Bto)
+	case "cc":
+		values.add(ob.CC)
+	case "bcc":
+		values.add(ob.BCC)
+	case "published":
+		values.add(ob.Published)
+	case "updated":
+		values.add(ob.Updated)
+	case "startTime":
+		values.add(ob.StartTime)
+	case "endTime":
+		values.add(ob.EndTime)
+	case "duration":
+		values.add(ob.Duration)
+	case "source":
+		if len(ob.Source.Content) > 0 || len(ob.Source.MediaType) > 0 {
+			values = append(values, ob.Source)
+		}
+	}
+	return values
+}
+
+func actorValues(act *vocab.Actor, name string) propValues {
+	var values propValues
+	switch name {
+	case keyPreferredUsername:
+		values.add(act.PreferredUsername)
+	case "inbox":
+		values.add(act.Inbox)
+	case "outbox":
+		values.add(act.Outbox)
+	case "following":
+		values.add(act.Following)
+	case "followers":
+		values.add(act.Followers)
+	case "liked":
+		values.add(act.Liked)
+	case "streams":
+		values.add(act.Streams)
+	case "endpoints":
+		if act.Endpoints != nil {
+			values = append(values, act.Endpoints)
+		}
+	}
+	return values
+}
+
+func intransitiveActivityValues(act *vocab.IntransitiveActivity, name string) propValues {
+	var values propValues
+	switch name {
+	case keyActor:
+		values.add(act.Actor)
+	case keyTarget:
+		values.add(act.Target)
+	case "result":
+		values.add(act.Result)
+	case "origin":
+		values.add(act.Origin)
+	case "instrument":
+		values.add(act.Instrument)
+	}
+	return values
+}
+
+func questionValues(q *vocab.Question, name string) propValues {
+	var values propValues
+	switch name {
+	case "oneOf":
+		values.add(q.OneOf)
+	case "anyOf":
+		values.add(q.AnyOf)
+	case "closed":
+		values = append(values, q.Closed)
+	}
+	return values
+}
+
+func collectionValues(it vocab.Item, name string) propValues {
+	var values propValues
+	typ := it.GetType()
+	if typ == nil {
+		return nil
+	}
+	// NOTE(marius): the totalItems property is added even when it's 0,
+	// as, unlike the rest of the numeric properties, that is a meaningful value.
+	switch {
+	case vocab.OrderedCollectionPageType.Match(typ):
+		_ = vocab.OnOrderedCollectionPage(it, func(c *vocab.OrderedCollectionPage) error {
+			switch name {
+			case "totalItems":
+				values = append(values, c.TotalItems)
+			case "current":
+				values.add(c.Current)
+			case "first":
+				values.add(c.First)
+			case "last":
+				values.add(c.Last)
+			case "orderedItems":
+				values.add(c.OrderedItems)
+			case "partOf":
+				values.add(c.PartOf)
+			case "next":
+				values.add(c.Next)
+			case "prev":
+				values.add(c.Prev)
+			case "startIndex":
+				values = append(values, c.StartIndex)
+			}
+			return nil
+		})
+	case vocab.CollectionPageType.Match(typ):
+		_ = vocab.OnCollectionPage(it, func(c *vocab.CollectionPage) error {
+			switch name {
+			case "totalItems":
+				values = append(values, c.TotalItems)
+			case "current":
+				values.add(c.Current)
+			case "first":
+				values.add(c.First)
+			case "last":
+				values.add(c.Last)
+			case "items":
+				values.add(c.Items)
+			case "partOf":
+				values.add(c.PartOf)
+			case "next":
+				values.add(c.Next)
+			case "prev":
+				values.add(c.Prev)
+			}
+			return nil
+		})
+	case vocab.OrderedCollectionType.Match(typ):
+		_ = vocab.OnOrderedCollection(it, func(c *vocab.OrderedCollection) error {
+			switch name {
+			case "totalItems":
+				values = append(values, c.TotalItems)
+			case "current":
+				values.add(c.Current)
+			case "first":
+				values.add(c.First)
+			case "last":
+				values.add(c.Last)
+			case "orderedItems":
+				values.add(c.OrderedItems)
+			}
+			return nil
+		})
+	case vocab.CollectionType.Match(typ):
+		_ = vocab.OnCollection(it, func(c *vocab.Collection) error {
+			switch name {
+			case "totalItems":
+				values = append(values, c.TotalItems)
+			case "current":
+				values.add(c.Current)
+			case "first":
+				values.add(c.First)
+			case "last":
+				values.add(c.Last)
+			case "items":
+				values.add(c.Items)
+			}
+			return nil
+		})
+	}
+	return values
+}
+
+// propValues accumulates the values of a property, skipping the empty ones,
+// and flattening the item collections.
+type propValues []any
+
+func (pv *propValues) add(v any) {
+	switch vv := v.(type) {
+	case nil:
+	case vocab.ItemCollection:
+		for _, it := range vv {
+			pv.add(it)
+		}
+	case vocab.Item:
+		if !vocab.IsNil(vv) {
+			*pv = append(*pv, vv)
+		}
+	case vocab.NaturalLanguageValues:
+		if len(vv) > 0 {
+			*pv = append(*pv, vv)
+		}
+	case vocab.MimeType:
+		pv.add(string(vv))
+	case string:
+		if len(vv) > 0 {
+			*pv = append(*pv, vv)
+		}
+	case time.Time:
+		if !vv.IsZero() {
+			*pv = append(*pv, vv)
+		}
+	case time.Duration:
+		if vv > 0 {
+			*pv = append(*pv, vv)
+		}
+	case uint:
+		if vv > 0 {
+			*pv = append(*pv, vv)
+		}
+	}
+}
+
+// valueAsItems converts a property value to the Items that the checks can be applied on.
+func valueAsItems(v any) vocab.ItemCollection {
+	switch vv := v.(type) {
+	case vocab.Item:
+		return vocab.ItemCollection{vv}
+	case vocab.NaturalLanguageValues:
+		items := make(vocab.ItemCollection, 0, len(vv))
+		for _, c := range vv {
+			items = append(items, vocab.IRI(c.String()))
+		}
+		return items
+	case string:
+		return vocab.ItemCollection{vocab.IRI(vv)}
+	case time.Time:
+		return vocab.ItemCollection{vocab.IRI(vv.UTC().Format(time.RFC3339))}
+	case time.Duration:
+		return vocab.ItemCollection{vocab.IRI(vv.String())}
+	case uint:
+		return vocab.ItemCollection{vocab.IRI(strconv.FormatUint(uint64(vv), 10))}
+	case bool:
+		return vocab.ItemCollection{vocab.IRI(strconv.FormatBool(vv))}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package filters
+
+import (
+	"testing"
+	"time"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProperty(t *testing.T) {
+	published := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		check Check
+		item  vocab.Item
+		want  bool
+	}{
+		{
+			name:  "nil item",
+			check: Property("mediaType", SameIRI("image/png")),
+			want:  false,
+		},
+		{
+			name:  "mediaType",
+			check: Property("mediaType", SameIRI("image/png")),
+			item:  &vocab.Object{MediaType: "image/png"},
+			want:  true,
+		},
+		{
+			name:  "mediaType does not match",
+			check: Property("mediaType", SameIRI("image/png")),
+			item:  &vocab.Object{MediaType: "text/html"},
+			want:  false,
+		},
+		{
+			name:  "mediaType like",
+			check: Property("mediaType", IRILike("image/")),
+			item:  &vocab.Object{MediaType: "image/png"},
+			want:  true,
+		},
+		{
+			name:  "missing mediaType is nil",
+			check: Property("mediaType", NilIRI),
+			item:  &vocab.Object{},
+			want:  true,
+		},
+		{
+			name:  "mediaType is not nil",
+			check: Property("mediaType", NilIRI),
+			item:  &vocab.Object{MediaType: "image/png"},
+			want:  false,
+		},
+		{
+			name:  "generator id",
+			check: Property("generator", SameID("https://example.com/app")),
+			item:  &vocab.Object{Generator: &vocab.Object{ID: "https://example.com/app"}},
+			want:  true,
+		},
+		{
+			name:  "generator iri",
+			check: Property("generator", SameIRI("https://example.com/app")),
+			item:  &vocab.Object{Generator: vocab.IRI("https://example.com/app")},
+			want:  true,
+		},
+		{
+			name:  "missing generator",
+			check: Property("generator", NilItem),
+			item:  &vocab.Object{},
+			want:  true,
+		},
+		{
+			name:  "audience",
+			check: Property("audience", SameIRI("https://example.com/followers")),
+			item: &vocab.Object{Audience: vocab.ItemCollection{
+				vocab.IRI("https://example.com/~jdoe"),
+				vocab.IRI("https://example.com/followers"),
+			}},
+			want: true,
+		},
+		{
+			name:  "icon.url",
+			check: Property("icon.url", SameIRI("https://example.com/icon.png")),
+			item: &vocab.Actor{
+				Type: vocab.PersonType,
+				Icon: &vocab.Object{Type: vocab.ImageType, URL: vocab.IRI("https://example.com/icon.png")},
+			},
+			want: true,
+		},
+		{
+			name:  "icon.href of a link",
+			check: Property("icon.href", SameIRI("https://example.com/icon.png")),
+			item: &vocab.Object{
+				Icon: &vocab.Link{Type: vocab.LinkType, Href: "https://example.com/icon.png"},
+			},
+			want: true,
+		},
+		{
+			name:  "icon name",
+			check: Property("icon", NameIs("avatar")),
+			item: &vocab.Object{
+				Icon: &vocab.Object{Type: vocab.ImageType, Name: vocab.NaturalLanguageValues{vocab.NilLangRef: vocab.Content("avatar")}},
+			},
+			want: true,
+		},
+		{
+			name:  "icon of an IRI can not be resolved",
+			check: Property("icon.url", SameIRI("https://example.com/icon.png")),
+			item:  &vocab.Object{Icon: vocab.IRI("https://example.com/icon")},
+			want:  false,
+		},
+		{
+			name:  "endpoints.sharedInbox",
+			check: Property("endpoints.sharedInbox", SameIRI("https://example.com/inbox")),
+			item: &vocab.Actor{
+				Type:      vocab.PersonType,
+				Endpoints: &vocab.Endpoints{SharedInbox: vocab.IRI("https://example.com/inbox")},
+			},
+			want: true,
+		},
+		{
+			name:  "missing endpoints.sharedInbox",
+			check: Property("endpoints.sharedInbox", NilIRI),
+			item:  &vocab.Actor{Type: vocab.PersonType},
+			want:  true,
+		},
+		{
+			name:  "source.mediaType",
+			check: Property("source.mediaType", SameIRI("text/markdown")),
+			item:  &vocab.Object{Source: vocab.Source{MediaType: "text/markdown"}},
+			want:  true,
+		},
+		{
+			name:  "name",
+			check: Property("name", SameIRI("jdoe")),
+			item:  &vocab.Object{Name: vocab.NaturalLanguageValues{vocab.NilLangRef: vocab.Content("jdoe")}},
+			want:  true,
+		},
+		{
+			name:  "published",
+			check: Property("published", SameIRI("2024-05-01T10:00:00Z")),
+			item:  &vocab.Object{Published: published},
+			want:  true,
+		},
+		{
+			name:  "totalItems",
+			check: Property("totalItems", SameIRI("2")),
+			item:  &vocab.OrderedCollection{Type: vocab.OrderedCollectionType, TotalItems: 2},
+			want:  true,
+		},
+		{
+			name:  "totalItems zero",
+			check: Property("totalItems", SameIRI("0")),
+			item:  &vocab.Collection{Type: vocab.CollectionType},
+			want:  true,
+		},
+		{
+			name:  "object.attributedTo of activity",
+			check: Property("object.attributedTo", SameIRI("https://example.com/~jdoe")),
+			item: &vocab.Activity{
+				Type:   vocab.CreateType,
+				Object: &vocab.Object{AttributedTo: vocab.IRI("https://example.com/~jdoe")},
+			},
+			want: true,
+		},
+		{
+			name:  "unknown property",
+			check: Property("unknown", SameIRI("test")),
+			item:  &vocab.Object{ID: "https://example.com"},
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check.Match(tt.item); got != tt.want {
+				t.Errorf("Match() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_resolvePath(t *testing.T) {
+	tests := []struct {
+		name string
+		item vocab.Item
+		path string
+		want []any
+	}{
+		{
+			name: "empty path",
+			item: &vocab.Object{ID: "https://example.com"},
+		},
+		{
+			name: "id",
+			item: &vocab.Object{ID: "https://example.com"},
+			path: "id",
+			want: []any{vocab.IRI("https://example.com")},
+		},
+		{
+			name: "type",
+			item: &vocab.Object{Type: vocab.NoteType},
+			path: "type",
+			want: []any{string(vocab.NoteType)},
+		},
+		{
+			name: "to is flattened",
+			item: &vocab.Object{To: vocab.ItemCollection{vocab.IRI("https://example.com/1"), vocab.IRI("https://example.com/2")}},
+			path: "to",
+			want: []any{vocab.IRI("https://example.com/1"), vocab.IRI("https://example.com/2")},
+		},
+		{
+			name: "tag.name",
+			item: &vocab.Object{Tag: vocab.ItemCollection{
+				&vocab.Link{Type: vocab.MentionType, Name: vocab.NaturalLanguageValues{vocab.NilLangRef: vocab.Content("@jdoe")}},
+				&vocab.Object{Type: vocab.ObjectType},
+			}},
+			path: "tag.name",
+			want: []any{vocab.NaturalLanguageValues{vocab.NilLangRef: vocab.Content("@jdoe")}},
+		},
+		{
+			name: "empty values are skipped",
+			item: &vocab.Object{},
+			path: "mediaType",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolvePath(tt.item, tt.path); !cmp.Equal(got, tt.want) {
+				t.Errorf("resolvePath() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func Test_validPropertyPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "", want: false},
+		{path: "mediaType", want: true},
+		{path: "endpoints.sharedInbox", want: true},
+		{path: "icon..url", want: false},
+		{path: ".icon", want: false},
+		{path: "icon.", want: false},
+		{path: "icon'", want: false},
+		{path: `icon"`, want: false},
+		{path: "icon url", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := validPropertyPath(tt.path); got != tt.want {
+				t.Errorf("validPropertyPath(%q) = %t, want %t", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -12,6 +12,10 @@ func (r recipients) Match(it vocab.Item) bool {
 	return aud.Contains(vocab.IRI(r))
 }
 
+func (r recipients) Leaf() (Field, Operator, Value) {
+	return FieldRecipients, OperatorEquals, vocab.IRI(r)
+}
+
 // Recipients creates a filter that checks the [vocab.IRI] against the recipients list of the item it gets applied on.
 // Please take care that vocabulary objects that do not satisfy the [vocab.HasRecipients] interface, will return the
 // [vocab.PublicNS] IRI as a recipient.
@@ -0,0 +1,91 @@
+package filters
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/go-ap/filters/index"
+)
+
+// CheckResolver returns the bitmap of the documents of the in index that match the check, and if the bitmap
+// contains exactly the matching documents, or if they need to be verified using the in-memory check.
+// It returns a nil bitmap for the checks it can't resolve.
+type CheckResolver func(check Check, in index.Indexable) (*roaring64.Bitmap, bool)
+
+// TokensCheck is implemented by the checks of the applications that can be resolved using the index of
+// a registered Type, by the documents containing any of the tokens.
+type TokensCheck[T index.Tokenizable] interface {
+	Check
+	// IndexTokens returns the index Type, and the tokens whose documents match the check.
+	IndexTokens() (index.Type, []T)
+}
+
+var resolvers = struct {
+	sync.RWMutex
+	m map[index.Type]CheckResolver
+}{m: make(map[index.Type]CheckResolver)}
+
+// RegisterIndex registers the typ index type, which contains the tokens returned by extractFn, so it is part
+// of the indexes returned by [index.Full], and it's used by SearchIndex for resolving the checks that resolveFn
+// can resolve. See [index.Register] for the values typ can have, which allow at most 64 custom index types.
+//
+// When resolveFn is nil, the index resolves the checks that implement TokensCheck for typ, eg:
+//
+//	type mediaTypeIs string
+//
+//	func (m mediaTypeIs) Match(it vocab.Item) bool { ... }
+//
+//	func (m mediaTypeIs) IndexTokens() (index.Type, []string) {
+//		return ByMediaType, []string{string(m)}
+//	}
+func RegisterIndex[T index.Tokenizable](typ index.Type, extractFn index.ExtractFnType[T], resolveFn CheckResolver) error {
+	err := index.Register(typ, func() index.Indexable {
+		return index.NewTokenIndex(extractFn)
+	})
+	if err != nil {
+		return err
+	}
+	if resolveFn == nil {
+		resolveFn = resolveTokens[T](typ)
+	}
+
+	resolvers.Lock()
+	defer resolvers.Unlock()
+
+	resolvers.m[typ] = resolveFn
+	return nil
+}
+
+// resolveTokens returns the CheckResolver for the TokensCheck checks of the typ index.
+func resolveTokens[T index.Tokenizable](typ index.Type) CheckResolver {
+	return func(check Check, in index.Indexable) (*roaring64.Bitmap, bool) {
+		tc, ok := check.(TokensCheck[T])
+		if !ok {
+			return nil, false
+		}
+		checkTyp, tokens := tc.IndexTokens()
+		if checkTyp != typ {
+			return nil, false
+		}
+		return lookup[T](map[index.Type]index.Indexable{typ: in}, typ, tokens...)
+	}
+}
+
+// resolveRegistered resolves the check using the resolvers of the registered index types.
+func resolveRegistered(check Check, indexes map[index.Type]index.Indexable) (*roaring64.Bitmap, bool) {
+	resolvers.RLock()
+	defer resolvers.RUnlock()
+
+	for _, typ := range slices.Sorted(maps.Keys(resolvers.m)) {
+		in, ok := indexes[typ]
+		if !ok {
+			continue
+		}
+		if bmp, exact := resolvers.m[typ](check, in); bmp != nil {
+			return bmp, exact
+		}
+	}
+	return nil, false
+}
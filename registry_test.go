@@ -0,0 +1,105 @@
+package filters
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/filters/index"
+)
+
+const byMediaType = index.MinCustomType
+
+type mediaTypeIs vocab.MimeType
+
+func (m mediaTypeIs) Match(it vocab.Item) bool {
+	ob, ok := it.(*vocab.Object)
+	return ok && ob.MediaType == vocab.MimeType(m)
+}
+
+func (m mediaTypeIs) IndexTokens() (index.Type, []string) {
+	return byMediaType, []string{string(m)}
+}
+
+func extractMediaType(li vocab.LinkOrIRI) []string {
+	ob, ok := li.(*vocab.Object)
+	if !ok || ob.MediaType == "" {
+		return nil
+	}
+	return []string{string(ob.MediaType)}
+}
+
+// NOTE(marius): the index types can't be unregistered, so we register the custom one once for all the tests.
+var registerMediaType = sync.OnceValue(func() error {
+	return RegisterIndex(byMediaType, extractMediaType, nil)
+})
+
+var mediaTypeObjects = []vocab.LinkOrIRI{
+	&vocab.Object{ID: "https://federated.local/objects/1", Type: vocab.NoteType, MediaType: "text/markdown"},
+	&vocab.Object{ID: "https://federated.local/objects/2", Type: vocab.NoteType, MediaType: "text/html"},
+	&vocab.Object{ID: "https://federated.local/objects/3", Type: vocab.ArticleType, MediaType: "text/markdown"},
+	&vocab.Object{ID: "https://federated.local/objects/4", Type: vocab.ArticleType},
+}
+
+func TestRegisterIndex(t *testing.T) {
+	if err := registerMediaType(); err != nil {
+		t.Fatalf("RegisterIndex() error = %v", err)
+	}
+	if err := RegisterIndex(byMediaType, extractMediaType, nil); err == nil {
+		t.Errorf("RegisterIndex() for a registered type error = nil, want an error")
+	}
+	if err := RegisterIndex(ByType, extractMediaType, nil); !errors.Is(err, index.ErrReservedType) {
+		t.Errorf("RegisterIndex() for a builtin type error = %v, want %v", err, index.ErrReservedType)
+	}
+
+	in := index.Full()
+	in.Add(mediaTypeObjects...)
+
+	tests := []struct {
+		name   string
+		checks Checks
+		want   []vocab.IRI
+	}{
+		{
+			name:   "media type",
+			checks: Checks{mediaTypeIs("text/markdown")},
+			want:   []vocab.IRI{"https://federated.local/objects/1", "https://federated.local/objects/3"},
+		},
+		{
+			name:   "with type",
+			checks: Checks{HasType(vocab.ArticleType), mediaTypeIs("text/markdown")},
+			want:   []vocab.IRI{"https://federated.local/objects/3"},
+		},
+		{
+			name:   "not",
+			checks: Checks{Not(mediaTypeIs("text/markdown"))},
+			want:   []vocab.IRI{"https://federated.local/objects/2", "https://federated.local/objects/4"},
+		},
+		{
+			name:   "missing",
+			checks: Checks{mediaTypeIs("text/plain")},
+			want:   []vocab.IRI{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bmp *roaring64.Bitmap
+			var remaining Checks
+			in.View(func(indexes map[index.Type]index.Indexable) {
+				bmp, remaining = tt.checks.IndexResolve(indexes)
+			})
+			if len(remaining) > 0 {
+				t.Errorf("IndexResolve() = %d remaining checks, want the registered index to resolve them", len(remaining))
+			}
+			want := roaring64.New()
+			for _, iri := range tt.want {
+				want.Add(index.HashFn(iri))
+			}
+			if bmp == nil || !bmp.Equals(want) {
+				t.Errorf("IndexResolve() = %v, want %v", bmp, want.ToArray())
+			}
+		})
+	}
+}
@@ -2,12 +2,12 @@ package filters
 
 import (
 	"fmt"
-	"reflect"
-	"runtime"
 	"strings"
 
 	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/filters/index"
 	"github.com/leporo/sqlf"
+	"golang.org/x/text/language"
 )
 
 func SQLLimit(st *Stmt, f ...Check) {
@@ -41,6 +41,10 @@ func getWhereClauses(s *Stmt, f ...Check) {
 	addAttributedToWheres(s, f...)
 	addURLWheres(s, f...)
 	addContextWheres(s, f...)
+	addPropertyWheres(s, f...)
+	addNumericWheres(s, f...)
+	addCollatedWheres(s, f...)
+	addPatternWheres(s, f...)
 }
 
 func addNotClauses(s *Stmt, f ...Check) {
@@ -208,24 +212,18 @@ func addURLWheres(s *Stmt, f ...Check) {
 	}
 }
 
-func sameFns(f1, f2 any) bool {
-	p1 := reflect.ValueOf(f1).Pointer()
-	p2 := reflect.ValueOf(f2).Pointer()
-	if p1 == p2 {
-		return true
-	}
-	if p1 == 0 || p2 == 0 {
-		return false
-	}
-	s1, l1 := runtime.FuncForPC(p1).FileLine(p1)
-	s2, l2 := runtime.FuncForPC(p2).FileLine(p2)
-	return s1 == s2 && l1 == l2
-}
-
 func addNLVWheres(s *Stmt, f ...Check) {
+	if s == nil || len(f) == 0 {
+		return
+	}
+	isPg := stmtIsPostgres(s)
 	for _, check := range f {
 		switch c := check.(type) {
 		case naturalLanguageValCheck:
+			if c.lang != language.Und {
+				addLocalizedNLVWhere(s, c)
+				continue
+			}
 			var field string
 			switch c.typ {
 			case byName:
@@ -237,18 +235,135 @@ func addNLVWheres(s *Stmt, f ...Check) {
 			case byContent:
 				field = keyContent
 			}
-			switch {
-			case sameFns(c.checkFn, naturalLanguageEmpty):
+			expr, val := field, c.checkValue
+			if c.collated {
+				expr, val = collatedExpr(isPg, field, c.checkValue, c.collation)
+			}
+			switch c.op {
+			case nlvEmpty:
 				s.Where(field + " IS NULL")
-			case sameFns(c.checkFn, naturalLanguageValuesLike):
-				s.Where(field+" LIKE ?", "%"+c.checkValue+"%")
-			case sameFns(c.checkFn, naturalLanguageValuesEquals):
-				s.Where(field+" = ?", c.checkValue)
+			case nlvLike:
+				s.Where(expr+" LIKE ?", "%"+val+"%")
+			case nlvEquals:
+				s.Where(expr+" = ?", val)
 			}
 		}
 	}
 }
 
+// addLocalizedNLVWhere translates the natural language value checks restricted to a language
+// to conditions on the corresponding map property of the raw document, eg: contentMap.fr.
+// Unlike the in memory check, it matches only the exact language ref, without the regional fallbacks.
+func addLocalizedNLVWhere(s *Stmt, c naturalLanguageValCheck) {
+	field, _, _ := c.Leaf()
+	prop := string(field) + "Map." + c.lang.String()
+	isPg := stmtIsPostgres(s)
+	expr, val := jsonPath(isPg, prop), c.checkValue
+	if c.collated {
+		expr, val = collatedExpr(isPg, expr, c.checkValue, c.collation)
+	}
+	switch c.op {
+	case nlvEmpty:
+		jsonIsNull(s, prop)
+	case nlvLike:
+		s.Where(expr+" LIKE ?", "%"+val+"%")
+	case nlvEquals:
+		s.Where(expr+" = ?", val)
+	}
+}
+
+// addCollatedWheres translates the Like checks on IRI properties that have been configured with WithCollation.
+func addCollatedWheres(s *Stmt, f ...Check) {
+	if s == nil || len(f) == 0 {
+		return
+	}
+	isPg := stmtIsPostgres(s)
+	for _, check := range f {
+		c, ok := check.(collatedLike)
+		if !ok {
+			continue
+		}
+		field, _, frag := c.Leaf()
+		expr, val := collatedExpr(isPg, sqlColumn(isPg, field), frag.(string), c.collation)
+		s.Where(expr+" LIKE ?", "%"+val+"%")
+	}
+}
+
+// addPatternWheres translates the regular expression checks to the REGEXP operator for sqlite,
+// which requires the regexp() user function to be registered by the driver, and to the ~ operator for postgres.
+// The glob patterns are translated to GLOB for sqlite and LIKE for postgres.
+// The invalid patterns don't match anything, like the in memory checks.
+func addPatternWheres(s *Stmt, f ...Check) {
+	if s == nil || len(f) == 0 {
+		return
+	}
+	isPg := stmtIsPostgres(s)
+	for _, check := range f {
+		c, ok := check.(patternCheck)
+		if !ok {
+			continue
+		}
+		col := sqlColumn(isPg, c.field)
+		switch {
+		case c.re == nil:
+			s.Where("1 = 0")
+		case c.glob && isPg:
+			s.Where(col+" LIKE ?", globToLike(c.expr))
+		case c.glob:
+			s.Where(col+" GLOB ?", globToSQLiteGlob(c.expr))
+		case isPg:
+			s.Where(col+" ~ ?", c.expr)
+		default:
+			s.Where(col+" REGEXP ?", c.expr)
+		}
+	}
+}
+
+// sqlColumn returns the SQL expression for the field property.
+// The ID, IRI, URL and the natural language values have their own columns, the rest are extracted
+// from the raw JSON document.
+func sqlColumn(isPg bool, field Field) string {
+	switch field {
+	case FieldID, FieldIRI:
+		return "iri"
+	case FieldURL:
+		return "url"
+	case FieldName, FieldSummary, FieldContent:
+		return string(field)
+	case FieldPreferredUsername:
+		return "preferred_username"
+	}
+	return jsonPath(isPg, string(field))
+}
+
+// globToLike converts a glob pattern to a LIKE pattern, escaping the LIKE wildcards.
+func globToLike(glob string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `*`, `%`)
+	return r.Replace(glob)
+}
+
+// globToSQLiteGlob converts a glob pattern to a sqlite GLOB pattern, escaping the "?" and "[" wildcards.
+func globToSQLiteGlob(glob string) string {
+	r := strings.NewReplacer(`[`, `[[]`, `?`, `[?]`)
+	return r.Replace(glob)
+}
+
+// collatedExpr wraps the expr SQL expression, and folds the val argument, according to the coll Collation.
+// It's used only for the checks configured with WithCollation.
+// CaseFold is translated to lower(), and, for postgres, AccentFold to unaccent(), which requires the extension
+// with the same name. The width folding, and the accent folding for sqlite, are not supported and get ignored.
+func collatedExpr(isPg bool, expr, val string, coll Collation) (string, string) {
+	if isPg && coll&AccentFold == AccentFold {
+		expr = "unaccent(" + expr + ")"
+		val = index.Fold(val, AccentFold)
+	}
+	if coll&CaseFold == CaseFold {
+		expr = "lower(" + expr + ")"
+		val = strings.ToLower(val)
+	}
+	return expr, val
+}
+
 func addInReplyToWheres(s *Stmt, f ...Check) {
 	for _, check := range f {
 		switch c := check.(type) {
@@ -275,40 +390,100 @@ func addAttributedToWheres(s *Stmt, f ...Check) {
 	}
 }
 
-func jsonLike(s *Stmt, prop string, val fmt.Stringer) {
-	isPg := stmtIsPostgres(s)
-	if isPg {
-		s.Where(fmt.Sprintf(`raw->>'%s' LIKE ?`, prop), "%"+val.String()+"%")
-	} else {
-		s.Where(fmt.Sprintf(`json_extract(raw, '$.%s') LIKE ?`, prop), "%"+val.String()+"%")
+// addPropertyWheres translates the Property checks which contain leaf checks to conditions on the JSON path
+// of the raw document. The checks on the ID or IRI of the property value get applied on the path itself,
+// the rest on the corresponding sub-property.
+func addPropertyWheres(s *Stmt, f ...Check) {
+	for _, check := range f {
+		c, ok := check.(propertyChecks)
+		if !ok || !validPropertyPath(c.path) {
+			continue
+		}
+		for _, fn := range c.fns {
+			leaf, ok := fn.(Leaf)
+			if !ok {
+				continue
+			}
+			field, op, val := leaf.Leaf()
+			path := c.path
+			switch field {
+			case FieldNone, FieldID, FieldIRI:
+			default:
+				path += "." + string(field)
+			}
+			switch op {
+			case OperatorNil:
+				jsonIsNull(s, path)
+			case OperatorEquals:
+				jsonEquals(s, path, val)
+			case OperatorLike:
+				if v, ok := val.(string); ok {
+					jsonLike(s, path, vocab.IRI(v))
+				}
+			}
+		}
 	}
 }
 
-func jsonIsNull(s *Stmt, prop string) {
-	isPg := stmtIsPostgres(s)
-	if isPg {
-		s.Where(fmt.Sprintf(`raw->>'%s' IS NULL`, prop))
-	} else {
-		s.Where(fmt.Sprintf(`json_extract(raw, '$.%s') IS NULL`, prop))
+// addNumericWheres translates the numeric comparison checks to conditions on the JSON path of the raw document.
+// The duration property is skipped, as it is stored in the xsd:duration format, which can't be compared numerically.
+func addNumericWheres(s *Stmt, f ...Check) {
+	for _, check := range f {
+		c, ok := check.(numericCheck)
+		if !ok || !validPropertyPath(c.path) || c.path == keyDuration || strings.HasSuffix(c.path, "."+keyDuration) {
+			continue
+		}
+		prop := jsonPath(stmtIsPostgres(s), c.path)
+		if stmtIsPostgres(s) {
+			prop = "(" + prop + ")::numeric"
+		}
+		switch c.op {
+		case OperatorGt:
+			s.Where(prop+" > ?", c.value)
+		case OperatorGte:
+			s.Where(prop+" >= ?", c.value)
+		case OperatorLt:
+			s.Where(prop+" < ?", c.value)
+		case OperatorLte:
+			s.Where(prop+" <= ?", c.value)
+		case OperatorBetween:
+			s.Where(prop+" BETWEEN ? AND ?", c.value, c.max)
+		}
 	}
 }
 
-func jsonIsNotNull(s *Stmt, prop string) {
-	isPg := stmtIsPostgres(s)
-	if isPg {
-		s.Where(fmt.Sprintf(`raw->>'%s' IS NOT NULL`, prop))
-	} else {
-		s.Where(fmt.Sprintf(`json_extract(raw, '$.%s') IS NOT NULL`, prop))
+// jsonPath returns the expression that extracts the value found at the dotted prop path of the raw JSON document.
+// For sqlite the path segments that are not plain identifiers, like the "fr-CA" language refs, are quoted.
+func jsonPath(isPg bool, prop string) string {
+	if !isPg {
+		segments := strings.Split(prop, ".")
+		for i, seg := range segments {
+			if strings.ContainsRune(seg, '-') {
+				segments[i] = `"` + seg + `"`
+			}
+		}
+		return fmt.Sprintf(`json_extract(raw, '$.%s')`, strings.Join(segments, "."))
 	}
+	if !strings.Contains(prop, ".") {
+		return fmt.Sprintf(`raw->>'%s'`, prop)
+	}
+	return fmt.Sprintf(`raw#>>'{%s}'`, strings.ReplaceAll(prop, ".", ","))
+}
+
+func jsonLike(s *Stmt, prop string, val fmt.Stringer) {
+	s.Where(jsonPath(stmtIsPostgres(s), prop)+` LIKE ?`, "%"+val.String()+"%")
+}
+
+func jsonIsNull(s *Stmt, prop string) {
+	s.Where(jsonPath(stmtIsPostgres(s), prop) + ` IS NULL`)
+}
+
+func jsonIsNotNull(s *Stmt, prop string) {
+	s.Where(jsonPath(stmtIsPostgres(s), prop) + ` IS NOT NULL`)
 }
 
 func jsonEquals(s *Stmt, prop string, val any) {
-	isPg := stmtIsPostgres(s)
-	if isPg {
-		s.Where(fmt.Sprintf(`raw->>'%s' = ?`, prop), val)
-	} else {
-		s.Where(fmt.Sprintf(`json_extract(raw, '$.%s') = ?`, prop), val)
-	}
+	s.Where(jsonPath(stmtIsPostgres(s), prop)+` = ?`, val)
 }
 
 func stmtIsPostgres(s *Stmt) bool {
@@ -6,6 +6,7 @@ import (
 	vocab "github.com/go-ap/activitypub"
 	"github.com/google/go-cmp/cmp"
 	"github.com/leporo/sqlf"
+	"golang.org/x/text/language"
 )
 
 func Test_SQLWhere(t *testing.T) {
@@ -495,6 +496,226 @@ func Test_SQLWhere(t *testing.T) {
 			gotQuery: " WHERE url LIKE $1",
 			gotArgs:  []any{vocab.IRI("%http://example.com%")},
 		},
+		{
+			name: "property equals",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{Property("endpoints.sharedInbox", SameIRI("https://example.com/inbox"))},
+			},
+			gotQuery: " WHERE json_extract(raw, '$.endpoints.sharedInbox') = ?",
+			gotArgs:  []any{vocab.IRI("https://example.com/inbox")},
+		},
+		{
+			name: "property like",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{Property("mediaType", IRILike("image"))},
+			},
+			gotQuery: " WHERE json_extract(raw, '$.mediaType') LIKE ?",
+			gotArgs:  []any{"%image%"},
+		},
+		{
+			name: "property nil",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{Property("generator", NilItem)},
+			},
+			gotQuery: " WHERE json_extract(raw, '$.generator') IS NULL",
+		},
+		{
+			name: "property name",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{Property("icon", NameIs("avatar"))},
+			},
+			gotQuery: " WHERE json_extract(raw, '$.icon.name') = ?",
+			gotArgs:  []any{"avatar"},
+		},
+		{
+			name: "property with invalid path",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{Property("icon') OR 1=1 --", SameIRI("https://example.com"))},
+			},
+		},
+		{
+			name: "postgres property",
+			args: args{
+				s: sqlf.PostgreSQL.New(""),
+				f: []Check{Property("mediaType", SameIRI("image/png"))},
+			},
+			gotQuery: " WHERE raw->>'mediaType' = $1",
+			gotArgs:  []any{vocab.IRI("image/png")},
+		},
+		{
+			name: "postgres nested property",
+			args: args{
+				s: sqlf.PostgreSQL.New(""),
+				f: []Check{Property("source.mediaType", SameIRI("text/markdown"))},
+			},
+			gotQuery: " WHERE raw#>>'{source,mediaType}' = $1",
+			gotArgs:  []any{vocab.IRI("text/markdown")},
+		},
+		{
+			name: "totalItems greater than",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{Gt("totalItems", 10)},
+			},
+			gotQuery: " WHERE json_extract(raw, '$.totalItems') > ?",
+			gotArgs:  []any{10.0},
+		},
+		{
+			name: "width between",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{Between("width", 100, 200)},
+			},
+			gotQuery: " WHERE json_extract(raw, '$.width') BETWEEN ? AND ?",
+			gotArgs:  []any{100.0, 200.0},
+		},
+		{
+			name: "duration is skipped",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{Lt("duration", 60)},
+			},
+		},
+		{
+			name: "postgres replies.totalItems less than or equal",
+			args: args{
+				s: sqlf.PostgreSQL.New(""),
+				f: []Check{Lte("replies.totalItems", 5)},
+			},
+			gotQuery: " WHERE (raw#>>'{replies,totalItems}')::numeric <= $1",
+			gotArgs:  []any{5.0},
+		},
+		{
+			name: "content like in language",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{ContentLike("chat", language.French)},
+			},
+			gotQuery: " WHERE json_extract(raw, '$.contentMap.fr') LIKE ?",
+			gotArgs:  []any{"%chat%"},
+		},
+		{
+			name: "name is in regional language",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{NameIs("jdoe", language.AmericanEnglish)},
+			},
+			gotQuery: ` WHERE json_extract(raw, '$.nameMap."en-US"') = ?`,
+			gotArgs:  []any{"jdoe"},
+		},
+		{
+			name: "postgres summary in language",
+			args: args{
+				s: sqlf.PostgreSQL.New(""),
+				f: []Check{SummaryIs("chat", language.French)},
+			},
+			gotQuery: " WHERE raw#>>'{summaryMap,fr}' = $1",
+			gotArgs:  []any{"chat"},
+		},
+		{
+			name: "name like with exact collation",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{WithCollation(ExactCollation, NameLike("Café"))},
+			},
+			gotQuery: " WHERE name LIKE ?",
+			gotArgs:  []any{"%Café%"},
+		},
+		{
+			name: "postgres name like with default collation",
+			args: args{
+				s: sqlf.PostgreSQL.New(""),
+				f: []Check{NameLike("Café")},
+			},
+			gotQuery: " WHERE name LIKE $1",
+			gotArgs:  []any{"%Café%"},
+		},
+		{
+			name: "postgres name like with explicit default collation",
+			args: args{
+				s: sqlf.PostgreSQL.New(""),
+				f: []Check{WithCollation(DefaultCollation, NameLike("Café"))},
+			},
+			gotQuery: " WHERE lower(unaccent(name)) LIKE $1",
+			gotArgs:  []any{"%cafe%"},
+		},
+		{
+			name: "postgres summary in language with case folding",
+			args: args{
+				s: sqlf.PostgreSQL.New(""),
+				f: []Check{WithCollation(CaseFold, SummaryIs("Chat", language.French))},
+			},
+			gotQuery: " WHERE lower(raw#>>'{summaryMap,fr}') = $1",
+			gotArgs:  []any{"chat"},
+		},
+		{
+			name: "iri like with case folding",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{WithCollation(CaseFold, IRILike("Notes"))},
+			},
+			gotQuery: " WHERE lower(iri) LIKE ?",
+			gotArgs:  []any{"%notes%"},
+		},
+		{
+			name: "attributedTo like with case folding",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{WithCollation(CaseFold, AttributedToLike("JDoe"))},
+			},
+			gotQuery: " WHERE lower(json_extract(raw, '$.attributedTo')) LIKE ?",
+			gotArgs:  []any{"%jdoe%"},
+		},
+		{
+			name: "id regular expression",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{MustIDMatches(`^https://example\.com/`)},
+			},
+			gotQuery: " WHERE iri REGEXP ?",
+			gotArgs:  []any{`^https://example\.com/`},
+		},
+		{
+			name: "postgres name regular expression",
+			args: args{
+				s: sqlf.PostgreSQL.New(""),
+				f: []Check{MustNameMatches(`^jdoe`)},
+			},
+			gotQuery: " WHERE name ~ $1",
+			gotArgs:  []any{`^jdoe`},
+		},
+		{
+			name: "url glob",
+			args: args{
+				s: sqlf.New(""),
+				f: []Check{MustURLGlob("https://*.example.com/?q=[1]")},
+			},
+			gotQuery: " WHERE url GLOB ?",
+			gotArgs:  []any{"https://*.example.com/[?]q=[[]1]"},
+		},
+		{
+			name: "postgres id glob",
+			args: args{
+				s: sqlf.PostgreSQL.New(""),
+				f: []Check{MustIDGlob("https://*.example.com/100%_done")},
+			},
+			gotQuery: " WHERE iri LIKE $1",
+			gotArgs:  []any{`https://%.example.com/100\%\_done`},
+		},
+		{
+			name: "invalid regular expression",
+			args: args{
+				s: sqlf.New(""),
+				// NOTE(marius): FromValues builds such checks for the invalid patterns.
+				f: []Check{patternCheck{field: FieldID, expr: "("}},
+			},
+			gotQuery: " WHERE 1 = 0",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1,6 +1,11 @@
 package filters
 
-import vocab "github.com/go-ap/activitypub"
+import (
+	"strings"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/filters/index"
+)
 
 func Tag(fns ...Check) Check {
 	return tagChecks(fns)
@@ -31,3 +36,91 @@ func (a tagChecks) Match(it vocab.Item) bool {
 	})
 	return match
 }
+
+func (a tagChecks) Combinator() (Field, Operator) {
+	return FieldTag, OperatorAll
+}
+
+func (a tagChecks) Children() []Check {
+	return a
+}
+
+// emojiType is the type that Mastodon uses for the custom emoji tag items.
+const emojiType vocab.ActivityVocabularyType = "Emoji"
+
+// normalizeEmoji returns the emoji shortcode without the surrounding ":", and case folded.
+func normalizeEmoji(name string) string {
+	return index.Fold(strings.Trim(strings.TrimSpace(name), ":"), index.CaseFold)
+}
+
+type hashtagCheck string
+
+// HasHashtag creates a filter that checks if the item has a Hashtag tag with the name.
+// The names are compared case-insensitively, and the leading "#" is optional, so
+// HasHashtag("#GoLang") matches a "#golang" tag.
+func HasHashtag(name string) Check {
+	return hashtagCheck(index.NormalizeHashtag(name))
+}
+
+func (h hashtagCheck) Match(it vocab.Item) bool {
+	return hasTagNamed(it, index.HashtagType, string(h), index.NormalizeHashtag)
+}
+
+func (h hashtagCheck) Leaf() (Field, Operator, Value) {
+	return FieldHashtag, OperatorEquals, string(h)
+}
+
+type emojiCheck string
+
+// HasEmoji creates a filter that checks if the item has an Emoji tag with the shortcode name.
+// The names are compared case-insensitively, and the surrounding ":" are optional, so
+// HasEmoji(":Blob:") matches a ":blob:" tag.
+func HasEmoji(name string) Check {
+	return emojiCheck(normalizeEmoji(name))
+}
+
+func (e emojiCheck) Match(it vocab.Item) bool {
+	return hasTagNamed(it, emojiType, string(e), normalizeEmoji)
+}
+
+func (e emojiCheck) Leaf() (Field, Operator, Value) {
+	return FieldEmoji, OperatorEquals, string(e)
+}
+
+func hasTagNamed(it vocab.Item, typ vocab.ActivityVocabularyType, name string, normalize func(string) string) bool {
+	if name == "" {
+		return false
+	}
+	for _, tag := range index.TagsOfType(it, typ) {
+		for _, n := range index.TagName(tag) {
+			if normalize(n.String()) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type mentionCheck vocab.IRI
+
+// Mentions creates a filter that checks if the item has a Mention tag for the actor IRI.
+// The IRI is compared to the href of the Mention.
+func Mentions(actor vocab.IRI) Check {
+	return mentionCheck(actor)
+}
+
+func (m mentionCheck) Match(it vocab.Item) bool {
+	if m == "" {
+		return false
+	}
+	for _, tag := range index.TagsOfType(it, index.MentionType) {
+		if index.TagHref(tag).Equal(vocab.IRI(m)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m mentionCheck) Leaf() (Field, Operator, Value) {
+	return FieldMention, OperatorEquals, vocab.IRI(m)
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	vocab "github.com/go-ap/activitypub"
+	"github.com/go-ap/filters/index"
 )
 
 func Test_tagChecks_Match(t *testing.T) {
@@ -60,3 +61,158 @@ func Test_tagChecks_Match(t *testing.T) {
 		})
 	}
 }
+
+var taggedNote = &vocab.Object{
+	ID:   "https://example.com/notes/1",
+	Type: vocab.NoteType,
+	Tag: vocab.ItemCollection{
+		&vocab.Link{Type: index.HashtagType, Href: "https://example.com/tags/golang", Name: vocab.DefaultNaturalLanguage("#GoLang")},
+		&vocab.Mention{Type: index.MentionType, Href: "https://example.com/~jdoe", Name: vocab.DefaultNaturalLanguage("@jdoe@example.com")},
+		&vocab.Object{ID: "https://example.com/emojis/1", Type: emojiType, Name: vocab.DefaultNaturalLanguage(":Blob:")},
+		&vocab.Object{ID: "https://example.com/~alice", Type: index.MentionType},
+	},
+}
+
+func TestHasHashtag(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		it   vocab.Item
+		want bool
+	}{
+		{
+			name: "nil item",
+			tag:  "golang",
+			want: false,
+		},
+		{
+			name: "empty name",
+			tag:  "#",
+			it:   taggedNote,
+			want: false,
+		},
+		{
+			name: "without hash",
+			tag:  "golang",
+			it:   taggedNote,
+			want: true,
+		},
+		{
+			name: "with hash, different case",
+			tag:  "#GOLANG",
+			it:   taggedNote,
+			want: true,
+		},
+		{
+			name: "substring",
+			tag:  "#go",
+			it:   taggedNote,
+			want: false,
+		},
+		{
+			name: "emoji is not a hashtag",
+			tag:  "blob",
+			it:   taggedNote,
+			want: false,
+		},
+		{
+			name: "untyped tag",
+			tag:  "golang",
+			it:   &vocab.Object{Tag: vocab.ItemCollection{&vocab.Object{Name: vocab.DefaultNaturalLanguage("#golang")}}},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasHashtag(tt.tag).Match(tt.it); got != tt.want {
+				t.Errorf("HasHashtag(%q).Match() = %t, want %t", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor vocab.IRI
+		it    vocab.Item
+		want  bool
+	}{
+		{
+			name:  "nil item",
+			actor: "https://example.com/~jdoe",
+			want:  false,
+		},
+		{
+			name:  "empty IRI",
+			actor: "",
+			it:    taggedNote,
+			want:  false,
+		},
+		{
+			name:  "link href",
+			actor: "https://example.com/~jdoe",
+			it:    taggedNote,
+			want:  true,
+		},
+		{
+			name:  "object ID",
+			actor: "https://example.com/~alice",
+			it:    taggedNote,
+			want:  true,
+		},
+		{
+			name:  "hashtag href is not a mention",
+			actor: "https://example.com/tags/golang",
+			it:    taggedNote,
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Mentions(tt.actor).Match(tt.it); got != tt.want {
+				t.Errorf("Mentions(%q).Match() = %t, want %t", tt.actor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasEmoji(t *testing.T) {
+	tests := []struct {
+		name  string
+		emoji string
+		it    vocab.Item
+		want  bool
+	}{
+		{
+			name:  "nil item",
+			emoji: ":blob:",
+			want:  false,
+		},
+		{
+			name:  "with colons, different case",
+			emoji: ":blob:",
+			it:    taggedNote,
+			want:  true,
+		},
+		{
+			name:  "without colons",
+			emoji: "BLOB",
+			it:    taggedNote,
+			want:  true,
+		},
+		{
+			name:  "hashtag is not an emoji",
+			emoji: "golang",
+			it:    taggedNote,
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasEmoji(tt.emoji).Match(tt.it); got != tt.want {
+				t.Errorf("HasEmoji(%q).Match() = %t, want %t", tt.emoji, got, tt.want)
+			}
+		})
+	}
+}
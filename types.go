@@ -53,6 +53,10 @@ func (tt withTypes) Match(it vocab.Item) bool {
 	return itemsHaveType
 }
 
+func (tt withTypes) Leaf() (Field, Operator, Value) {
+	return FieldType, OperatorIn, vocab.ActivityVocabularyTypes(tt)
+}
+
 func (tt withTypes) GoString() string {
 	ss := strings.Builder{}
 	ss.WriteString("type=[")
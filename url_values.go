@@ -1,13 +1,15 @@
 package filters
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
-	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 
 	vocab "github.com/go-ap/activitypub"
+	"golang.org/x/text/language"
 )
 
 const (
@@ -31,17 +33,34 @@ const (
 
 	keyTag = "tag"
 
+	keyHashtag = "hashtag"
+	keyMention = "mention"
+	keyEmoji   = "emoji"
+
+	keyProperty = "prop"
+
+	keyLanguageSeparator = "@"
+	keyLanguage          = "lang"
+
+	keyTotalItems = "totalItems"
+	keyStartIndex = "startIndex"
+	keyWidth      = "width"
+	keyHeight     = "height"
+	keyDuration   = "duration"
+
 	keyAfter  = "after"
 	keyBefore = "before"
 
 	keyMaxItems = "maxItems"
 )
 
+// FromURL returns the checks corresponding to the query values of the u URL, see FromValues.
 func FromURL(u url.URL) Checks {
-	q := u.Query()
-	return append(fromValues(q), paginationFromValues(q)...)
+	return FromValues(u.Query())
 }
 
+// FromIRI returns the checks corresponding to the query values of the i IRI, see ParseValues.
+// It returns an error if i is not a valid URL, or if it contains invalid patterns.
 func FromIRI(i vocab.IRI) (Checks, error) {
 	if vocab.IsNil(i) {
 		return nil, nil
@@ -50,11 +69,25 @@ func FromIRI(i vocab.IRI) (Checks, error) {
 	if err != nil {
 		return nil, err
 	}
-	return FromURL(*u), nil
+	return ParseValues(u.Query())
 }
 
+// FromValues returns the checks corresponding to the q query values.
+// The invalid regular expressions and glob patterns result in checks that don't match anything,
+// use ParseValues for getting them reported as errors.
 func FromValues(q url.Values) Checks {
-	return append(fromValues(q), paginationFromValues(q)...)
+	f, _ := fromValues(q)
+	return append(f, paginationFromValues(q)...)
+}
+
+// ParseValues returns the checks corresponding to the q query values, like FromValues, and the errors
+// of the invalid regular expressions and glob patterns, in which case the checks are nil.
+func ParseValues(q url.Values) (Checks, error) {
+	f, err := fromValues(q)
+	if err != nil {
+		return nil, err
+	}
+	return append(f, paginationFromValues(q)...), nil
 }
 
 func paginationFromValues(q url.Values) Checks {
@@ -94,6 +127,9 @@ func paginationFromValues(q url.Values) Checks {
 type buildFilterFn func(string) Check
 
 type checkGroup struct {
+	// field is the property the checks apply on, it's used for building the regular expression
+	// and glob pattern checks. If it's FieldNone, the pattern values result in checks that don't match anything.
+	field  Field
 	nilFn  Check
 	likeFn buildFilterFn
 	sameFn buildFilterFn
@@ -103,6 +139,11 @@ func parseURLValue(v string) (string, string) {
 	if len(v) < 1 {
 		return opNone, v
 	}
+	for _, op := range []string{opNotRegexp, opNotGlob, opRegexp, opGlob} {
+		if len(v) > len(op) && v[:len(op)] == op {
+			return op, v[len(op):]
+		}
+	}
 	op := opNone
 	if len(v) > 2 && v[0:2] == opNotLike {
 		op = v[0:2]
@@ -120,11 +161,19 @@ const (
 	opLike    = "~"
 	opNotLike = "!~"
 	opNone    = ""
+	// opRegexp prefixes the RE2 regular expressions, eg: =~^https://example\.com/
+	opRegexp    = "=~"
+	opNotRegexp = "!=~"
+	// opGlob prefixes the glob patterns, where "*" matches any sequence of characters, eg: *~https://*.example.com/*
+	opGlob    = "*~"
+	opNotGlob = "!*~"
 	sNilIRI   = string(vocab.NilIRI)
 	sEmptyIRI = string(vocab.EmptyIRI)
 )
 
-func (cg checkGroup) build(vv ...string) Check {
+// build returns the check for the vv URL values, and the errors of the invalid patterns among them.
+func (cg checkGroup) build(vv ...string) (Check, error) {
+	var errs []error
 	f := make(Checks, 0)
 	for _, n := range vv {
 		switch op, v := parseURLValue(n); op {
@@ -144,18 +193,48 @@ func (cg checkGroup) build(vv ...string) Check {
 			f = append(f, cg.likeFn(v))
 		case opNotLike:
 			f = append(f, Not(cg.likeFn(v)))
+		case opRegexp, opGlob:
+			c, err := cg.pattern(v, op == opGlob)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			f = append(f, c)
+		case opNotRegexp, opNotGlob:
+			c, err := cg.pattern(v, op == opNotGlob)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if c.re == nil {
+				// NOTE(marius): the negation of an invalid pattern would match everything, so we keep
+				// the check that doesn't match anything.
+				f = append(f, c)
+			} else {
+				f = append(f, Not(c))
+			}
 		}
 	}
+	err := errors.Join(errs...)
 	if len(f) == 0 {
-		return nil
+		return nil, err
 	}
 	if len(f) == 1 {
-		return f[0]
+		return f[0], err
 	}
-	return Any(f...)
+	return Any(f...), err
+}
+
+// pattern returns the check for the "=~" regular expression, and the "*~" glob pattern URL values.
+// The invalid, or too complex, patterns, and the ones for the properties that don't support them,
+// result in an error, and a check that doesn't match anything.
+func (cg checkGroup) pattern(v string, glob bool) (patternCheck, error) {
+	if cg.field == FieldNone {
+		return patternCheck{expr: v, glob: glob}, fmt.Errorf("%w: %q", ErrPatternNotSupported, v)
+	}
+	return newPatternCheck(cg.field, v, glob)
 }
 
 var idFilters = checkGroup{
+	field:  FieldID,
 	nilFn:  NilID,
 	likeFn: IDLike,
 	sameFn: func(s string) Check {
@@ -163,31 +242,41 @@ var idFilters = checkGroup{
 	},
 }
 
-var preferredUsernameFilters = checkGroup{
-	nilFn:  PreferredUsernameEmpty,
-	likeFn: PreferredUsernameLike,
-	sameFn: PreferredUsernameIs,
+// nlvFilters returns the checkGroup for the typ natural language values property,
+// restricted to the lang language, if it's not [language.Und].
+func nlvFilters(typ nlvType, lang language.Tag) checkGroup {
+	var matcher language.Matcher
+	if lang != language.Und {
+		matcher = newLanguageMatcher(lang)
+	}
+	build := func(op nlvOp) func(string) Check {
+		return func(s string) Check {
+			return naturalLanguageValCheck{checkValue: s, op: op, typ: typ, lang: lang, matcher: matcher, text: nlvTextOptions(typ), collation: nlvCollation(op)}
+		}
+	}
+	field, _, _ := naturalLanguageValCheck{typ: typ}.Leaf()
+	if lang != language.Und {
+		// NOTE(marius): the pattern checks can't be restricted to a language.
+		field = FieldNone
+	}
+	return checkGroup{
+		field:  field,
+		nilFn:  naturalLanguageValCheck{op: nlvEmpty, typ: typ, lang: lang, matcher: matcher, text: nlvTextOptions(typ), collation: nlvCollation(nlvEmpty)},
+		likeFn: build(nlvLike),
+		sameFn: build(nlvEquals),
+	}
 }
 
-var nameFilters = checkGroup{
-	nilFn:  NameEmpty,
-	likeFn: NameLike,
-	sameFn: NameIs,
-}
+var preferredUsernameFilters = nlvFilters(byPreferredUsername, language.Und)
 
-var summaryFilters = checkGroup{
-	nilFn:  SummaryEmpty,
-	likeFn: SummaryLike,
-	sameFn: SummaryIs,
-}
+var nameFilters = nlvFilters(byName, language.Und)
 
-var contentFilters = checkGroup{
-	nilFn:  ContentEmpty,
-	likeFn: ContentLike,
-	sameFn: ContentIs,
-}
+var summaryFilters = nlvFilters(bySummary, language.Und)
+
+var contentFilters = nlvFilters(byContent, language.Und)
 
 var urlFilters = checkGroup{
+	field:  FieldURL,
 	nilFn:  NilIRI,
 	likeFn: URLLike,
 	sameFn: func(s string) Check {
@@ -196,6 +285,7 @@ var urlFilters = checkGroup{
 }
 
 var attributedToFilters = checkGroup{
+	field:  FieldAttributedTo,
 	nilFn:  NilAttributedTo,
 	likeFn: AttributedToLike,
 	sameFn: func(s string) Check {
@@ -204,6 +294,7 @@ var attributedToFilters = checkGroup{
 }
 
 var contextFilters = checkGroup{
+	field:  FieldContext,
 	nilFn:  NilContext,
 	likeFn: ContextLike,
 	sameFn: func(s string) Check {
@@ -212,6 +303,7 @@ var contextFilters = checkGroup{
 }
 
 var inReplyToFilters = checkGroup{
+	field:  FieldInReplyTo,
 	nilFn:  NilInReplyTo,
 	likeFn: InReplyToLike,
 	sameFn: func(s string) Check {
@@ -219,13 +311,61 @@ var inReplyToFilters = checkGroup{
 	},
 }
 
-func fromValues(q url.Values) Checks {
+// propertyFilters returns the checkGroup for the values of the property found at the dotted path.
+// The values are compared as IRIs, see [Property] for how they get converted.
+func propertyFilters(path string) checkGroup {
+	return checkGroup{
+		nilFn: Property(path, NilIRI),
+		likeFn: func(s string) Check {
+			return Property(path, IRILike(s))
+		},
+		sameFn: func(s string) Check {
+			return Property(path, SameIRI(vocab.IRI(s)))
+		},
+	}
+}
+
+// tagFilters returns the checks built with fn for the hashtag, mention and emoji values.
+// The values can be negated using the "!" prefix, and multiple values result in an Any aggregate.
+func tagFilters(fn func(string) Check, vv ...string) Check {
+	f := make(Checks, 0, len(vv))
+	for _, n := range vv {
+		switch op, v := parseURLValue(n); op {
+		case opNone:
+			if v != "" {
+				f = append(f, fn(v))
+			}
+		case opNot:
+			if v != "" {
+				f = append(f, Not(fn(v)))
+			}
+		}
+	}
+	if len(f) == 0 {
+		return nil
+	}
+	if len(f) == 1 {
+		return f[0]
+	}
+	return Any(f...)
+}
+
+// fromValues returns the checks for the q values, without the pagination ones, and the errors
+// of the invalid patterns.
+func fromValues(q url.Values) (Checks, error) {
+	var errs []error
 	actorQ := make(url.Values)
 	objectQ := make(url.Values)
 	targetQ := make(url.Values)
 	tagQ := make(url.Values)
 
 	f := make(Checks, 0)
+	add := func(c Check, err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+		f = append(f, c)
+	}
 	for k, vv := range q {
 		pieces := strings.SplitN(k, ".", 2)
 		piece := k
@@ -234,19 +374,36 @@ func fromValues(q url.Values) Checks {
 			piece = pieces[0]
 			remainder = pieces[1]
 		}
+		if prop, lang, ok := strings.Cut(piece, keyLanguageSeparator); ok {
+			// NOTE(marius): the natural language values properties can be restricted to a language,
+			// eg: ?content@fr=~chat
+			if tag, err := language.Parse(lang); err == nil {
+				switch prop {
+				case keyName:
+					add(nlvFilters(byName, tag).build(vv...))
+				case keySummary:
+					add(nlvFilters(bySummary, tag).build(vv...))
+				case keyContent:
+					add(nlvFilters(byContent, tag).build(vv...))
+				case keyPreferredUsername:
+					add(nlvFilters(byPreferredUsername, tag).build(vv...))
+				}
+			}
+			continue
+		}
 		switch piece {
 		case keyID, keyIRI:
-			f = append(f, idFilters.build(vv...))
+			add(idFilters.build(vv...))
 		case keyType:
 			f = append(f, HasType(VocabularyTypesFilter(vv...)...))
 		case keyName:
-			f = append(f, nameFilters.build(vv...))
+			add(nameFilters.build(vv...))
 		case keySummary:
-			f = append(f, summaryFilters.build(vv...))
+			add(summaryFilters.build(vv...))
 		case keyContent:
-			f = append(f, contentFilters.build(vv...))
+			add(contentFilters.build(vv...))
 		case keyPreferredUsername:
-			f = append(f, preferredUsernameFilters.build(vv...))
+			add(preferredUsernameFilters.build(vv...))
 		case keyActor:
 			if len(remainder) == 0 {
 				remainder = keyID
@@ -267,43 +424,92 @@ func fromValues(q url.Values) Checks {
 				remainder = keyID
 			}
 			tagQ[remainder] = vv
+		case keyHashtag:
+			f = append(f, tagFilters(HasHashtag, vv...))
+		case keyMention:
+			f = append(f, tagFilters(func(s string) Check { return Mentions(vocab.IRI(s)) }, vv...))
+		case keyEmoji:
+			f = append(f, tagFilters(HasEmoji, vv...))
 		case keyURL:
-			f = append(f, urlFilters.build(vv...))
+			add(urlFilters.build(vv...))
 		case keyAttributedTo:
-			f = append(f, attributedToFilters.build(vv...))
+			add(attributedToFilters.build(vv...))
 		case keyInReplyTo:
-			f = append(f, inReplyToFilters.build(vv...))
+			add(inReplyToFilters.build(vv...))
 		case keyContext:
-			f = append(f, contextFilters.build(vv...))
+			add(contextFilters.build(vv...))
+		case keyLanguage:
+			lf := make(Checks, 0, len(vv))
+			for _, v := range vv {
+				if tag, err := language.Parse(v); err == nil {
+					lf = append(lf, HasLanguage(tag))
+				}
+			}
+			if len(lf) > 0 {
+				f = append(f, Any(lf...))
+			}
+		case keyProperty:
+			if !validPropertyPath(remainder) {
+				continue
+			}
+			if nf := numericFilters(remainder, vv...); nf != nil {
+				f = append(f, nf)
+			} else {
+				add(propertyFilters(remainder).build(vv...))
+			}
+		case keyTotalItems, keyStartIndex, keyWidth, keyHeight, keyDuration:
+			if nf := numericFilters(piece, vv...); nf != nil {
+				f = append(f, nf)
+			}
 		}
 	}
+	// NOTE(marius): the checkGroups, and tagFilters, return nil when none of the values resulted in a check,
+	// eg: for the empty hashtag values.
+	f = slices.DeleteFunc(f, func(c Check) bool { return c == nil })
 	if len(actorQ) > 0 {
-		if af := fromValues(actorQ); len(af) > 0 {
+		af, err := fromValues(actorQ)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if len(af) > 0 {
 			f = append(f, Actor(af...))
 		}
 	}
 	if len(objectQ) > 0 {
-		if of := fromValues(objectQ); len(of) > 0 {
+		of, err := fromValues(objectQ)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if len(of) > 0 {
 			f = append(f, Object(of...))
 		}
 	}
 	if len(targetQ) > 0 {
-		if tf := fromValues(targetQ); len(tf) > 0 {
+		tf, err := fromValues(targetQ)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if len(tf) > 0 {
 			f = append(f, Target(tf...))
 		}
 	}
 	if len(tagQ) > 0 {
-		if tf := fromValues(tagQ); len(tf) > 0 {
+		tf, err := fromValues(tagQ)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if len(tf) > 0 {
 			f = append(f, Tag(tf...))
 		}
 	}
+	err := errors.Join(errs...)
 	if len(f) == 0 {
-		return nil
+		return nil, err
 	}
 	if len(f) == 1 {
-		return f
+		return f, err
 	}
-	return Checks{All(f...)}
+	return Checks{All(f...)}, err
 }
 
 func urlValue(f Check, q url.Values) {
@@ -363,6 +569,20 @@ func urlValue(f Check, q url.Values) {
 			}
 			q[p] = vv
 		}
+	case propertyChecks:
+		if len(check.fns) == 1 {
+			switch check.fns[0].(type) {
+			case iriEquals, iriLike, iriNil:
+				q.Add(keyProperty+"."+check.path, extractURLVal(check.fns[0]))
+			}
+		}
+	case numericCheck:
+		switch check.path {
+		case keyTotalItems, keyStartIndex, keyWidth, keyHeight, keyDuration:
+			q.Add(check.path, check.urlValue())
+		default:
+			q.Add(keyProperty+"."+check.path, check.urlValue())
+		}
 	case *beforeCrit:
 		if len(check.fns) >= 1 {
 			for _, cc := range check.fns {
@@ -375,27 +595,30 @@ func urlValue(f Check, q url.Values) {
 				q.Add(keyAfter, extractURLVal(cc))
 			}
 		}
+	case patternCheck:
+		q.Add(string(check.field), check.urlValue())
+	case languageCheck:
+		q.Add(keyLanguage, check.lang.String())
+	case hashtagCheck:
+		q.Add(keyHashtag, string(check))
+	case mentionCheck:
+		q.Add(keyMention, string(check))
+	case emojiCheck:
+		q.Add(keyEmoji, string(check))
 	case *counter:
 		q.Set(keyMaxItems, strconv.FormatInt(int64(check.max), 10))
 	case naturalLanguageValCheck:
-		var name string
-		switch check.typ {
-		case byName:
-			name = keyName
-		case byPreferredUsername:
-			name = keyPreferredUsername
-		case bySummary:
-			name = keySummary
-		case byContent:
-			name = keyContent
+		field, _, _ := check.Leaf()
+		name := string(field)
+		if check.lang != language.Und {
+			name += keyLanguageSeparator + check.lang.String()
 		}
-		rCheckFn := reflect.ValueOf(check.checkFn)
-		switch rCheckFn.Pointer() {
-		case nlvEqCheck.Pointer():
+		switch check.op {
+		case nlvEquals:
 			q.Add(name, check.checkValue)
-		case nlvEmptyCheck.Pointer():
+		case nlvEmpty:
 			q.Add(name, "")
-		case nlvLikeCheck.Pointer():
+		case nlvLike:
 			q.Add(name, "~"+check.checkValue)
 		}
 	}
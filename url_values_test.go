@@ -8,6 +8,7 @@ import (
 
 	vocab "github.com/go-ap/activitypub"
 	"github.com/google/go-cmp/cmp"
+	"golang.org/x/text/language"
 )
 
 var mockURL = url.URL{
@@ -67,11 +68,22 @@ func TestFromIRI(t *testing.T) {
 			},
 			wantErr: &url.Error{"parse", ":/example-com", errors.New("missing protocol scheme")},
 		},
+		{
+			name: "pattern not supported",
+			iri:  "https://example.com?name@fr==~chat",
+			item: vocab.ItemCollection{
+				vocab.Activity{Type: ty("Create")},
+			},
+			want: vocab.ItemCollection{
+				vocab.Activity{Type: ty("Create")},
+			},
+			wantErr: ErrPatternNotSupported,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			gotFn, gotErr := FromIRI(tt.iri)
-			if (gotErr != nil || tt.wantErr != nil) && !reflect.DeepEqual(gotErr, tt.wantErr) {
+			if (gotErr != nil || tt.wantErr != nil) && !reflect.DeepEqual(gotErr, tt.wantErr) && !errors.Is(gotErr, tt.wantErr) {
 				t.Errorf("Error returned FromIRI().Run() = %s, want %s", gotErr, tt.wantErr)
 			}
 			if got := gotFn.Run(tt.item); !reflect.DeepEqual(got, tt.want) {
@@ -425,9 +437,10 @@ func Test_paginationFromValues(t *testing.T) {
 
 func Test_fromValues(t *testing.T) {
 	tests := []struct {
-		name string
-		arg  url.Values
-		want Checks
+		name    string
+		arg     url.Values
+		want    Checks
+		wantErr bool
 	}{
 		{
 			name: "empty",
@@ -666,30 +679,210 @@ func Test_fromValues(t *testing.T) {
 			arg:  vals(kv("inReplyTo", "https://example.com")),
 			want: Checks{SameInReplyTo("https://example.com")},
 		},
+		{
+			name: "property equals",
+			arg:  vals(kv("prop.mediaType", "image/png")),
+			want: Checks{Property("mediaType", SameIRI("image/png"))},
+		},
+		{
+			name: "property like",
+			arg:  vals(kv("prop.endpoints.sharedInbox", "~example.com")),
+			want: Checks{Property("endpoints.sharedInbox", IRILike("example.com"))},
+		},
+		{
+			name: "property nil",
+			arg:  vals(kv("prop.generator", "")),
+			want: Checks{Property("generator", NilIRI)},
+		},
+		{
+			name: "property not equals",
+			arg:  vals(kv("prop.source.mediaType", "!text/html")),
+			want: Checks{Not(Property("source.mediaType", SameIRI("text/html")))},
+		},
+		{
+			name: "property without path",
+			arg:  vals(kv("prop", "image/png")),
+		},
+		{
+			name: "property with invalid path",
+			arg:  vals(kv("prop.media'Type", "image/png")),
+		},
+		{
+			name: "totalItems greater than",
+			arg:  vals(kv("totalItems", ">10")),
+			want: Checks{Gt("totalItems", 10)},
+		},
+		{
+			name: "width less than or equal",
+			arg:  vals(kv("width", "<=1024")),
+			want: Checks{Lte("width", 1024)},
+		},
+		{
+			name: "duration between",
+			arg:  vals(kv("duration", "10..60.5")),
+			want: Checks{Between("duration", 10, 60.5)},
+		},
+		{
+			name: "totalItems interval",
+			arg:  vals(kv("totalItems", ">=10", "<100")),
+			want: Checks{All(Gte("totalItems", 10), Lt("totalItems", 100))},
+		},
+		{
+			name: "totalItems invalid",
+			arg:  vals(kv("totalItems", "10")),
+		},
+		{
+			name: "property numeric",
+			arg:  vals(kv("prop.replies.totalItems", ">5")),
+			want: Checks{Gt("replies.totalItems", 5)},
+		},
+		{
+			name: "content like in language",
+			arg:  vals(kv("content@fr", "~chat")),
+			want: Checks{ContentLike("chat", language.French)},
+		},
+		{
+			name: "name is in language",
+			arg:  vals(kv("name@en-US", "jdoe")),
+			want: Checks{NameIs("jdoe", language.AmericanEnglish)},
+		},
+		{
+			name: "summary empty in language",
+			arg:  vals(kv("summary@de", "")),
+			want: Checks{nlvFilters(bySummary, language.German).nilFn},
+		},
+		{
+			name: "invalid language",
+			arg:  vals(kv("content@12345", "~chat")),
+		},
+		{
+			name: "language on non natural language property",
+			arg:  vals(kv("id@fr", "https://example.com")),
+		},
+		{
+			name: "has language",
+			arg:  vals(kv("lang", "fr")),
+			want: Checks{HasLanguage(language.French)},
+		},
+		{
+			name: "has any of the languages",
+			arg:  vals(kv("lang", "fr", "en")),
+			want: Checks{Any(HasLanguage(language.French), HasLanguage(language.English))},
+		},
+		{
+			name: "id regular expression",
+			arg:  vals(kv("id", `=~^https://example\.com/[0-9]+$`)),
+			want: Checks{MustIDMatches(`^https://example\.com/[0-9]+$`)},
+		},
+		{
+			name: "id glob",
+			arg:  vals(kv("id", "*~https://*.example.com/actors/*")),
+			want: Checks{MustIDGlob("https://*.example.com/actors/*")},
+		},
+		{
+			name: "not name regular expression",
+			arg:  vals(kv("name", "!=~^jdoe")),
+			want: Checks{Not(MustNameMatches(`^jdoe`))},
+		},
+		{
+			name: "not name glob",
+			arg:  vals(kv("name", "!*~jdoe*")),
+			want: Checks{Not(MustNameGlob("jdoe*"))},
+		},
+		{
+			name: "content regular expression",
+			arg:  vals(kv("content", `=~\bchat\b`)),
+			want: Checks{MustContentMatches(`\bchat\b`)},
+		},
+		{
+			name:    "invalid regular expression",
+			arg:     vals(kv("url", "=~(")),
+			want:    Checks{patternCheck{field: FieldURL, expr: "("}},
+			wantErr: true,
+		},
+		{
+			name:    "not invalid regular expression",
+			arg:     vals(kv("url", "!=~(")),
+			want:    Checks{patternCheck{field: FieldURL, expr: "("}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid regular expression of the actor",
+			arg:     vals(kv("actor.id", "=~(")),
+			want:    Checks{Actor(patternCheck{field: FieldID, expr: "("})},
+			wantErr: true,
+		},
+		{
+			name: "like with slashes is a substring",
+			arg:  vals(kv("id", "~/objects/")),
+			want: Checks{IDLike("/objects/")},
+		},
+		{
+			name: "like with wildcard is a substring",
+			arg:  vals(kv("id", "~https://*.example.com")),
+			want: Checks{IDLike("https://*.example.com")},
+		},
+		{
+			name:    "regular expression in language doesn't match",
+			arg:     vals(kv("name@fr", "=~chat")),
+			want:    Checks{patternCheck{expr: "chat"}},
+			wantErr: true,
+		},
+		{
+			name: "hashtag",
+			arg:  vals(kv(keyHashtag, "#GoLang")),
+			want: Checks{HasHashtag("golang")},
+		},
+		{
+			name: "not hashtag",
+			arg:  vals(kv(keyHashtag, "!golang")),
+			want: Checks{Not(HasHashtag("golang"))},
+		},
+		{
+			name: "multiple hashtags",
+			arg:  vals(kv(keyHashtag, "golang", "rust")),
+			want: Checks{Any(HasHashtag("golang"), HasHashtag("rust"))},
+		},
+		{
+			name: "empty hashtag",
+			arg:  vals(kv(keyHashtag, "")),
+			want: nil,
+		},
+		{
+			name: "mention",
+			arg:  vals(kv(keyMention, "https://example.com/~jdoe")),
+			want: Checks{Mentions("https://example.com/~jdoe")},
+		},
+		{
+			name: "emoji",
+			arg:  vals(kv(keyEmoji, ":blob:")),
+			want: Checks{HasEmoji("blob")},
+		},
+		{
+			name: "hashtag and mention",
+			arg:  vals(kv(keyHashtag, "golang"), kv(keyMention, "https://example.com/~jdoe")),
+			want: Checks{All(HasHashtag("golang"), Mentions("https://example.com/~jdoe"))},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := fromValues(tt.arg); !cmp.Equal(got, tt.want, cmp.Comparer(NaturalLanguageValuesComparer)) {
-				t.Errorf("fromValues() = %s", cmp.Diff(tt.want, got, cmp.Comparer(NaturalLanguageValuesComparer)))
+			got, err := fromValues(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("fromValues() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !cmp.Equal(got, tt.want, cmp.Comparer(NaturalLanguageValuesComparer), cmp.Comparer(ChecksComparer)) {
+				t.Errorf("fromValues() = %s", cmp.Diff(tt.want, got, cmp.Comparer(NaturalLanguageValuesComparer), cmp.Comparer(ChecksComparer)))
 			}
 		})
 	}
 }
 
 func NaturalLanguageValuesComparer(n1, n2 naturalLanguageValCheck) bool {
-	if !sameFns(n1.checkFn, n2.checkFn) {
-		return false
-	}
-	if !sameFns(n1.accumFn, n2.accumFn) {
-		return false
-	}
-	if n1.checkValue != n2.checkValue {
-		return false
-	}
-	if n1.typ != n2.typ {
-		return false
-	}
-	return true
+	return Equal(n1, n2)
+}
+
+func ChecksComparer(c1, c2 Check) bool {
+	return Equal(c1, c2)
 }
 
 func kv(key string, values ...string) url.Values {
@@ -811,6 +1004,61 @@ func Test_urlValue(t *testing.T) {
 			arg:  ContentEmpty,
 			want: vals(kv(keyContent, "")),
 		},
+		{
+			name: "property",
+			arg:  Property("mediaType", SameIRI("image/png")),
+			want: vals(kv(keyProperty+".mediaType", "image/png")),
+		},
+		{
+			name: "property like",
+			arg:  Property("endpoints.sharedInbox", IRILike("example.com")),
+			want: vals(kv(keyProperty+".endpoints.sharedInbox", "~example.com")),
+		},
+		{
+			name: "totalItems",
+			arg:  Gt("totalItems", 10),
+			want: vals(kv(keyTotalItems, ">10")),
+		},
+		{
+			name: "property between",
+			arg:  Between("replies.totalItems", 1, 2.5),
+			want: vals(kv(keyProperty+".replies.totalItems", "1..2.5")),
+		},
+		{
+			name: "content like in language",
+			arg:  ContentLike("chat", language.French),
+			want: vals(kv(keyContent+"@fr", "~chat")),
+		},
+		{
+			name: "has language",
+			arg:  HasLanguage(language.French),
+			want: vals(kv(keyLanguage, "fr")),
+		},
+		{
+			name: "id regular expression",
+			arg:  MustIDMatches(`^https://example\.com/`),
+			want: vals(kv(keyID, `=~^https://example\.com/`)),
+		},
+		{
+			name: "url glob",
+			arg:  MustURLGlob("https://*.example.com/*"),
+			want: vals(kv(keyURL, "*~https://*.example.com/*")),
+		},
+		{
+			name: "hashtag",
+			arg:  HasHashtag("#GoLang"),
+			want: vals(kv(keyHashtag, "golang")),
+		},
+		{
+			name: "mention",
+			arg:  Mentions("https://example.com/~jdoe"),
+			want: vals(kv(keyMention, "https://example.com/~jdoe")),
+		},
+		{
+			name: "emoji",
+			arg:  HasEmoji(":blob:"),
+			want: vals(kv(keyEmoji, "blob")),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
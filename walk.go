@@ -0,0 +1,101 @@
+package filters
+
+// Visitor is the function called by Walk for every Check in a tree.
+// If it returns false, the operands of the received Check are not visited.
+type Visitor func(Check) bool
+
+// Rewriter is the function called by Rewrite for every Check in a tree, starting from the root.
+// It returns the replacement for the received Check, or nil if it should be removed,
+// and whether the operands of the replacement should be rewritten in their turn.
+type Rewriter func(Check) (Check, bool)
+
+// Walk traverses the c Check tree in depth-first order, calling visit for each node.
+// The operands of the composite checks, like Any, All, Not, Actor, Object, Target, Tag, After and Before,
+// are visited after their parent, and only if visit returned true for it.
+func Walk(c Check, visit Visitor) {
+	if c == nil || visit == nil {
+		return
+	}
+	if !visit(c) {
+		return
+	}
+	for _, op := range operands(c) {
+		Walk(op, visit)
+	}
+}
+
+// Rewrite returns a copy of the c Check tree, where every node has been replaced with the result of calling fn on it.
+// The nodes are rewritten starting from the root, and the operands of a composite check get rewritten
+// only if fn asked for it. In that case the composite is rebuilt with the rewritten operands, and
+// if all of them have been removed, the composite is removed too.
+//
+// The received tree is not modified.
+func Rewrite(c Check, fn Rewriter) Check {
+	if c == nil || fn == nil {
+		return c
+	}
+	r, descend := fn(c)
+	if r == nil || !descend {
+		return r
+	}
+	ops := operands(r)
+	if len(ops) == 0 {
+		return r
+	}
+	rewritten := make([]Check, 0, len(ops))
+	for _, op := range ops {
+		if op = Rewrite(op, fn); op != nil {
+			rewritten = append(rewritten, op)
+		}
+	}
+	if len(rewritten) == 0 {
+		return nil
+	}
+	return withOperands(r, rewritten)
+}
+
+// rewriteChecks applies Rewrite on all the fns, returning the ones that have not been removed.
+func rewriteChecks(fns []Check, fn Rewriter) Checks {
+	c := make([]Check, 0)
+	for _, f := range fns {
+		if r := Rewrite(f, fn); r != nil {
+			c = append(c, r)
+		}
+	}
+	return c
+}
+
+// operands returns the Check list that a Composite check applies, or nil for Leaf checks.
+func operands(c Check) []Check {
+	if cc, ok := c.(Composite); ok {
+		return cc.Children()
+	}
+	return nil
+}
+
+// withOperands returns a new check of the same kind as c, which applies the fns operands.
+func withOperands(c Check, fns []Check) Check {
+	switch cc := c.(type) {
+	case checkAll:
+		return All(fns...)
+	case checkAny:
+		return Any(fns...)
+	case notCrit:
+		return notCrit(fns)
+	case actorChecks:
+		return actorChecks(fns)
+	case objectChecks:
+		return objectChecks(fns)
+	case targetChecks:
+		return targetChecks(fns)
+	case tagChecks:
+		return tagChecks(fns)
+	case propertyChecks:
+		return propertyChecks{path: cc.path, fns: fns}
+	case *afterCrit:
+		return &afterCrit{fns: fns}
+	case *beforeCrit:
+		return &beforeCrit{check: true, fns: fns}
+	}
+	return c
+}
@@ -0,0 +1,169 @@
+package filters
+
+import (
+	"testing"
+
+	vocab "github.com/go-ap/activitypub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWalk(t *testing.T) {
+	tests := []struct {
+		name  string
+		check Check
+		skip  func(Check) bool
+		want  []string
+	}{
+		{
+			name: "nil",
+			want: []string{},
+		},
+		{
+			name:  "leaf",
+			check: SameID("https://example.com"),
+			want:  []string{canonicalString(SameID("https://example.com"))},
+		},
+		{
+			name:  "all",
+			check: All(SameID("https://example.com"), NameIs("jdoe")),
+			want: []string{
+				canonicalString(All(SameID("https://example.com"), NameIs("jdoe"))),
+				canonicalString(SameID("https://example.com")),
+				canonicalString(NameIs("jdoe")),
+			},
+		},
+		{
+			name:  "nested",
+			check: Any(Not(HasType(vocab.NoteType)), Object(SameID("https://example.com"))),
+			want: []string{
+				canonicalString(Any(Not(HasType(vocab.NoteType)), Object(SameID("https://example.com")))),
+				canonicalString(Not(HasType(vocab.NoteType))),
+				canonicalString(HasType(vocab.NoteType)),
+				canonicalString(Object(SameID("https://example.com"))),
+				canonicalString(SameID("https://example.com")),
+			},
+		},
+		{
+			name:  "skip object operands",
+			check: Any(Not(HasType(vocab.NoteType)), Object(SameID("https://example.com"))),
+			skip: func(c Check) bool {
+				_, ok := c.(objectChecks)
+				return ok
+			},
+			want: []string{
+				canonicalString(Any(Not(HasType(vocab.NoteType)), Object(SameID("https://example.com")))),
+				canonicalString(Not(HasType(vocab.NoteType))),
+				canonicalString(HasType(vocab.NoteType)),
+				canonicalString(Object(SameID("https://example.com"))),
+			},
+		},
+		{
+			name:  "pagination",
+			check: After(SameID("https://example.com")),
+			want: []string{
+				canonicalString(After(SameID("https://example.com"))),
+				canonicalString(SameID("https://example.com")),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			Walk(tt.check, func(c Check) bool {
+				got = append(got, canonicalString(c))
+				return tt.skip == nil || !tt.skip(c)
+			})
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("Walk() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	sameIDToIRI := func(c Check) (Check, bool) {
+		if id, ok := c.(idEquals); ok {
+			return SameIRI(vocab.IRI(id)), false
+		}
+		return c, true
+	}
+	removeNames := func(c Check) (Check, bool) {
+		if _, ok := c.(naturalLanguageValCheck); ok {
+			return nil, false
+		}
+		return c, true
+	}
+	tests := []struct {
+		name  string
+		check Check
+		fn    Rewriter
+		want  Check
+	}{
+		{
+			name: "nil",
+			fn:   sameIDToIRI,
+		},
+		{
+			name:  "replace leaf",
+			check: SameID("https://example.com"),
+			fn:    sameIDToIRI,
+			want:  SameIRI("https://example.com"),
+		},
+		{
+			name:  "replace nested leaves",
+			check: Any(SameID("https://example.com"), Object(Not(SameID("https://example.com/1")))),
+			fn:    sameIDToIRI,
+			want:  Any(SameIRI("https://example.com"), Object(Not(SameIRI("https://example.com/1")))),
+		},
+		{
+			name:  "remove leaf",
+			check: All(SameID("https://example.com"), NameIs("jdoe")),
+			fn:    removeNames,
+			want:  SameID("https://example.com"),
+		},
+		{
+			name:  "remove composite with no operands left",
+			check: All(SameID("https://example.com"), Not(NameIs("jdoe")), Actor(NameLike("jdoe"))),
+			fn:    removeNames,
+			want:  SameID("https://example.com"),
+		},
+		{
+			name:  "remove everything",
+			check: Any(NameIs("jdoe"), NameLike("jdoe")),
+			fn:    removeNames,
+			want:  nil,
+		},
+		{
+			name:  "don't descend",
+			check: Object(SameID("https://example.com")),
+			fn: func(c Check) (Check, bool) {
+				if _, ok := c.(idEquals); ok {
+					return nil, false
+				}
+				return c, false
+			},
+			want: Object(SameID("https://example.com")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Rewrite(tt.check, tt.fn); !Equal(got, tt.want) {
+				t.Errorf("Rewrite() = %s", cmp.Diff(canonicalString(tt.want), canonicalString(got)))
+			}
+		})
+	}
+}
+
+func TestRewrite_doesNotModifyTheOriginal(t *testing.T) {
+	check := All(SameID("https://example.com"), After(SameID("https://example.com/1")))
+	want := canonicalString(check)
+	_ = Rewrite(check, func(c Check) (Check, bool) {
+		if id, ok := c.(idEquals); ok {
+			return SameIRI(vocab.IRI(id)), false
+		}
+		return c, true
+	})
+	if got := canonicalString(check); got != want {
+		t.Errorf("Rewrite() modified the original check %s, want %s", got, want)
+	}
+}